@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/gorilla/mux"
 	v1 "github.com/jlundan/journeys-api/internal/app/journeys/handlers/v1"
+	"github.com/jlundan/journeys-api/internal/app/journeys/realtime"
 	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
 	"github.com/jlundan/journeys-api/internal/app/journeys/server"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
 	"github.com/spf13/cobra"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+	// Embedded so that the agency timezone can be loaded also in containers without zoneinfo.
+	_ "time/tzdata"
 )
 
 const defaultPort = 8080
@@ -20,6 +28,7 @@ const defaultShortCacheDuration = 30 * time.Minute
 const defaultLongCacheDuration = 2 * time.Hour
 const defaultShortCacheLowerBound = 0
 const defaultShortCacheUpperBound = 5
+const defaultRealtimeInterval = 30 * time.Second
 
 // This variable is set at build time
 //
@@ -30,6 +39,19 @@ var dryRun bool
 var disableCache bool
 var skipValidation bool
 
+var mergeOutputDir string
+var mergeNamespaces []string
+var mergeStopMatchRadius float64
+
+var diffFormat string
+
+var filterOutputDir string
+var filterStartDate string
+var filterEndDate string
+var filterRouteIds []string
+var filterLineNames []string
+var filterBoundingBox string
+
 var MainCommand = &cobra.Command{
 	Use: "journeys",
 }
@@ -57,7 +79,12 @@ var StartCommand = &cobra.Command{
 			log.Fatal(err)
 		}
 
-		dataStore, errs := repository.NewJourneysRepository(gtfsPath, skipValidation)
+		dialect, err := getCSVDialect()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dataStore, errs := repository.NewJourneysRepository(filepath.SplitList(gtfsPath), dialect, skipValidation)
 
 		for _, e := range errs {
 			log.Println(e)
@@ -82,7 +109,7 @@ var StartCommand = &cobra.Command{
 				log.Println(fmt.Sprintf("Error parsing short-cache upper bound: %s. Using default value: %v", err.Error(), defaultShortCacheUpperBound))
 			}
 
-			memcached, err := server.NewMemcachedCacheMiddleware(memcache.New(os.Getenv("MEMCACHED_URL")), getShortCacheDuration(), getLongCacheDuration(), scLowerBound, scUpperBound)
+			memcached, err := server.NewMemcachedCacheMiddleware(memcache.New(os.Getenv("MEMCACHED_URL")), getShortCacheDuration(), getLongCacheDuration(), scLowerBound, scUpperBound, dataStore.Timezone)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -95,23 +122,43 @@ var StartCommand = &cobra.Command{
 
 			router.Use(memcached.Middleware)
 
-			log.Println(fmt.Sprintf("Using cache. Short cache duration: %v, Long cache duration: %v. Short cache duration hours %v -> %v", getShortCacheDuration(), getLongCacheDuration(), scLowerBound, scUpperBound))
+			log.Println(fmt.Sprintf("Using cache. Short cache duration: %v, Long cache duration: %v. Short cache duration hours %v -> %v (%v)", getShortCacheDuration(), getLongCacheDuration(), scLowerBound, scUpperBound, dataStore.Timezone))
 		}
 
 		dataService := service.NewJourneysDataService(dataStore)
 
+		tripUpdates := realtime.Source{
+			Url:  os.Getenv("JOURNEYS_GTFS_RT_TRIP_UPDATES_URL"),
+			Path: os.Getenv("JOURNEYS_GTFS_RT_TRIP_UPDATES_PATH"),
+		}
+		if tripUpdates.Url != "" || tripUpdates.Path != "" {
+			state := realtime.NewState(dataStore)
+			dataService.Realtime.State = state
+			go state.Run(context.Background(), tripUpdates, getRealtimeInterval())
+
+			log.Println(fmt.Sprintf("Using realtime trip updates from %v, interval %v", tripUpdates, getRealtimeInterval()))
+		}
+
 		router.HandleFunc("/v1/lines", v1.HandleGetAllLines(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/lines/{name}`, v1.HandleGetOneLine(dataService, baseUrl)).Methods("GET")
+		router.HandleFunc(`/v1/lines/{name}/timetable`, v1.HandleGetLineTimetables(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc("/v1/journeys", v1.HandleGetAllJourneys(dataService, baseUrl, vehicleActivityBaseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/journeys/{name}`, v1.HandleGetOneJourney(dataService, baseUrl, vehicleActivityBaseUrl)).Methods("GET")
 		router.HandleFunc("/v1/journey-patterns", v1.HandleGetAllJourneyPatterns(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/journey-patterns/{name}`, v1.HandleGetOneJourneyPattern(dataService, baseUrl)).Methods("GET")
+		router.HandleFunc(`/v1/journey-patterns/{name}/segments`, v1.HandleGetJourneyPatternSegments(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc("/v1/routes", v1.HandleGetAllRoutes(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/routes/{name}`, v1.HandleGetOneRoute(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc("/v1/stop-points", v1.HandleGetAllStopPoints(dataService, baseUrl)).Methods("GET")
+		// Registered before /v1/stop-points/{name}, which would match it too.
+		router.HandleFunc("/v1/stop-points/search", v1.HandleSearchStopPoints(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/stop-points/{name}`, v1.HandleGetOneStopPoint(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/stop-points/{name}/journeys`, v1.HandleGetJourneysForStopPoint(dataService, baseUrl, vehicleActivityBaseUrl, false)).Methods("GET")
 		router.HandleFunc(`/v1/stop-points/{name}/journeys/active`, v1.HandleGetJourneysForStopPoint(dataService, baseUrl, vehicleActivityBaseUrl, true)).Methods("GET")
+		router.HandleFunc(`/v1/stop-points/{name}/departures`, v1.HandleGetDeparturesForStopPoint(dataService, baseUrl, vehicleActivityBaseUrl)).Methods("GET")
+		router.HandleFunc("/v1/plans", v1.HandleGetPlans(dataService, baseUrl)).Methods("GET")
+		router.HandleFunc("/v1/reachability", v1.HandleGetReachability(dataService, baseUrl)).Methods("GET")
+		router.HandleFunc(`/v1/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt`, v1.HandleGetTile(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc("/v1/municipalities", v1.HandleGetAllMunicipalities(dataService, baseUrl)).Methods("GET")
 		router.HandleFunc(`/v1/municipalities/{name}`, v1.HandleGetOneMunicipality(dataService, baseUrl)).Methods("GET")
 
@@ -119,6 +166,139 @@ var StartCommand = &cobra.Command{
 	},
 }
 
+var MergeCommand = &cobra.Command{
+	Use:   "merge <gtfs path> <gtfs path>...",
+	Short: "Merge GTFS feeds into one",
+	Long:  "Merge GTFS feeds into one. Colliding ids are deduplicated or namespaced, agencies with the same name and URL are merged, and stops with the same stop_code close to each other are unified.",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := getCSVDialect()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var feeds []*ggtfs.Feed
+		for _, gtfsPath := range args {
+			feed, errs := ggtfs.LoadFeed(gtfsPath, dialect)
+			for _, e := range errs {
+				log.Println(fmt.Sprintf("%v: %v", gtfsPath, e))
+			}
+			for _, n := range feed.LoadNotices {
+				log.Println(fmt.Sprintf("%v: %v", gtfsPath, n.AsText()))
+			}
+			feeds = append(feeds, feed)
+		}
+
+		merged, notices := ggtfs.MergeFeeds(feeds, ggtfs.MergeOptions{
+			Namespaces:      mergeNamespaces,
+			StopMatchRadius: mergeStopMatchRadius,
+		})
+
+		for _, n := range notices {
+			log.Println(n.AsText())
+		}
+
+		if err = ggtfs.WriteFeed(mergeOutputDir, merged); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = repository.WriteMunicipalities(mergeOutputDir, args, dialect); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var DiffCommand = &cobra.Command{
+	Use:   "diff <old gtfs path> <new gtfs path>",
+	Short: "Compare two versions of a GTFS feed",
+	Long:  "Compare two versions of a GTFS feed. Reports added, removed and changed stops, lines, routes, journey patterns and trips, schedule changes per line, and calendar changes.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := getCSVDialect()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var feeds []*ggtfs.Feed
+		for _, gtfsPath := range args {
+			feed, errs := ggtfs.LoadFeed(gtfsPath, dialect)
+			for _, e := range errs {
+				log.Println(fmt.Sprintf("%v: %v", gtfsPath, e))
+			}
+			feeds = append(feeds, feed)
+		}
+
+		diff := ggtfs.DiffFeeds(feeds[0], feeds[1])
+
+		switch diffFormat {
+		case "text":
+			err = diff.WriteText(os.Stdout)
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(diff)
+		default:
+			log.Fatal(fmt.Sprintf("unknown format: %v, expected text or json", diffFormat))
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var FilterCommand = &cobra.Command{
+	Use:   "filter <gtfs path>",
+	Short: "Write a subset of a GTFS feed",
+	Long:  "Write a subset of a GTFS feed. Keeps the trips running in a date window, belonging to the given lines or calling at stops inside a bounding box, together with everything they reference.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := getCSVDialect()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts := ggtfs.FilterOptions{
+			RouteIds:  filterRouteIds,
+			LineNames: filterLineNames,
+		}
+
+		if opts.StartDate, err = parseOptionalDate(filterStartDate); err != nil {
+			log.Fatal(fmt.Sprintf("invalid start date: %v", err))
+		}
+		if opts.EndDate, err = parseOptionalDate(filterEndDate); err != nil {
+			log.Fatal(fmt.Sprintf("invalid end date: %v", err))
+		}
+
+		if filterBoundingBox != "" {
+			bbox, err := parseBoundingBox(filterBoundingBox)
+			if err != nil {
+				log.Fatal(err)
+			}
+			opts.BoundingBox = bbox
+		}
+
+		feed, errs := ggtfs.LoadFeed(args[0], dialect)
+		for _, e := range errs {
+			log.Println(e)
+		}
+		for _, n := range feed.LoadNotices {
+			log.Println(n.AsText())
+		}
+
+		filtered := ggtfs.FilterFeed(feed, opts)
+		log.Println(fmt.Sprintf("kept %v of %v trips, %v of %v stops", len(filtered.Trips), len(feed.Trips), len(filtered.Stops), len(feed.Stops)))
+
+		if err = ggtfs.WriteFeed(filterOutputDir, filtered); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = repository.WriteMunicipalities(filterOutputDir, args, dialect); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
 func onServerStartupSuccess(port int) {
 	log.Println(fmt.Sprintf("listening on port %v", port))
 }
@@ -177,12 +357,100 @@ func getLongCacheDuration() time.Duration {
 	return duration
 }
 
+func getRealtimeInterval() time.Duration {
+	intervalStr := os.Getenv("JOURNEYS_GTFS_RT_INTERVAL")
+	if intervalStr == "" {
+		return defaultRealtimeInterval
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		fmt.Printf("Invalid JOURNEYS_GTFS_RT_INTERVAL format: %s, using default %v\n", intervalStr, defaultRealtimeInterval)
+		return defaultRealtimeInterval
+	}
+
+	return interval
+}
+
+func getCSVDialect() (ggtfs.CsvDialect, error) {
+	var dialect ggtfs.CsvDialect
+
+	delimiter := os.Getenv("JOURNEYS_GTFS_DELIMITER")
+	if delimiter != "" {
+		runes := []rune(delimiter)
+		if len(runes) != 1 {
+			return dialect, fmt.Errorf("invalid JOURNEYS_GTFS_DELIMITER: %v, expected a single character", delimiter)
+		}
+		dialect.Delimiter = runes[0]
+	}
+
+	lazyQuotes := os.Getenv("JOURNEYS_GTFS_LAZY_QUOTES")
+	if lazyQuotes != "" {
+		v, err := strconv.ParseBool(lazyQuotes)
+		if err != nil {
+			return dialect, fmt.Errorf("invalid JOURNEYS_GTFS_LAZY_QUOTES: %v", lazyQuotes)
+		}
+		dialect.LazyQuotes = v
+	}
+
+	enc, err := ggtfs.ParseCharacterEncoding(strings.ToLower(os.Getenv("JOURNEYS_GTFS_ENCODING")))
+	if err != nil {
+		return dialect, fmt.Errorf("invalid JOURNEYS_GTFS_ENCODING: %v", err.Error())
+	}
+	dialect.Encoding = enc
+
+	return dialect, nil
+}
+
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func parseBoundingBox(value string) (*ggtfs.BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bounding box: %v, expected minLat,minLon,maxLat,maxLon", value)
+	}
+
+	var coordinates [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bounding box: %v, expected minLat,minLon,maxLat,maxLon", value)
+		}
+		coordinates[i] = v
+	}
+
+	return &ggtfs.BoundingBox{MinLat: coordinates[0], MinLon: coordinates[1], MaxLat: coordinates[2], MaxLon: coordinates[3]}, nil
+}
+
 func main() {
 	StartCommand.Flags().BoolVar(&disableCache, "disable-cache", false, "Do not use cache")
 	StartCommand.Flags().BoolVar(&skipValidation, "skip-validation", false, "Skip all validations")
 	StartCommand.Flags().BoolVar(&dryRun, "dry-run", false, "Perform a dry run without starting the server")
 
+	MergeCommand.Flags().StringVarP(&mergeOutputDir, "output", "o", "", "Directory where the merged feed is written")
+	MergeCommand.Flags().StringSliceVar(&mergeNamespaces, "namespaces", nil, "Comma separated namespaces for the feeds, used to prefix colliding ids")
+	MergeCommand.Flags().Float64Var(&mergeStopMatchRadius, "stop-match-radius", ggtfs.DefaultStopMatchRadius, "Distance in meters within which stops with the same code are unified, negative disables")
+	_ = MergeCommand.MarkFlagRequired("output")
+
+	DiffCommand.Flags().StringVar(&diffFormat, "format", "text", "Output format, text or json")
+
+	FilterCommand.Flags().StringVarP(&filterOutputDir, "output", "o", "", "Directory where the filtered feed is written")
+	FilterCommand.Flags().StringVar(&filterStartDate, "start-date", "", "Keep services running on or after the date (YYYY-MM-DD)")
+	FilterCommand.Flags().StringVar(&filterEndDate, "end-date", "", "Keep services running on or before the date (YYYY-MM-DD)")
+	FilterCommand.Flags().StringSliceVar(&filterRouteIds, "routes", nil, "Comma separated route_ids to keep")
+	FilterCommand.Flags().StringSliceVar(&filterLineNames, "lines", nil, "Comma separated line names (route_short_name) to keep")
+	FilterCommand.Flags().StringVar(&filterBoundingBox, "bbox", "", "Keep trips calling at stops inside minLat,minLon,maxLat,maxLon")
+	_ = FilterCommand.MarkFlagRequired("output")
+
 	MainCommand.AddCommand(StartCommand)
+	MainCommand.AddCommand(MergeCommand)
+	MainCommand.AddCommand(DiffCommand)
+	MainCommand.AddCommand(FilterCommand)
 	MainCommand.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Print the version number",
@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/app/journeys/realtime"
 	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
 	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type successResponse[T APIEntity] struct {
@@ -31,12 +34,55 @@ type routerTestCase[T APIEntity] struct {
 	handlerConfig    handlerConfig
 }
 
+// testNow is the current time of the test data service, a Wednesday on which the weekday services of the
+// test data run.
+var testNow = time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+
 func newJourneysTestDataService(t *testing.T) *service.JourneysDataService {
-	repo, errs := repository.NewJourneysRepository("testdata/tre/gtfs", true)
+	repo, errs := repository.NewJourneysRepository([]string{"testdata/tre/gtfs"}, ggtfs.CsvDialect{}, true)
 	if len(errs) > 0 {
 		t.Error(errs)
 	}
-	return service.NewJourneysDataService(repo)
+	dataService := service.NewJourneysDataService(repo)
+	dataService.Clock.Now = func() time.Time { return testNow }
+	return dataService
+}
+
+// newRealtimeTestDataService returns the test data service with realtime estimates for the service day of testNow.
+// 7020295685 is two minutes late from its second call on and skips its last call, 7020205685 is cancelled, and
+// 7024545685 is 15 minutes late at its last call.
+func newRealtimeTestDataService(t *testing.T) *service.JourneysDataService {
+	dataService := newJourneysTestDataService(t)
+
+	ptr := func(v int32) *int32 { return &v }
+	sequence := func(v uint32) *uint32 { return &v }
+	late := time.Date(2024, 5, 15, 7, 36, 0, 0, dataService.Clock.Location).Unix()
+
+	state := realtime.NewState(dataService.Journeys.Repository)
+	state.Apply(&realtime.FeedMessage{
+		Header: realtime.FeedHeader{Version: "2.0", Timestamp: uint64(testNow.Unix())},
+		Entities: []*realtime.FeedEntity{
+			{Id: "1", TripUpdate: &realtime.TripUpdate{
+				Trip: realtime.TripDescriptor{TripId: "7020295685"},
+				StopTimeUpdates: []*realtime.StopTimeUpdate{
+					{StopSequence: sequence(2), Arrival: &realtime.StopTimeEvent{Delay: ptr(120)}},
+					{StopSequence: sequence(3), ScheduleRelationship: realtime.StopSkipped},
+				},
+			}},
+			{Id: "2", TripUpdate: &realtime.TripUpdate{
+				Trip: realtime.TripDescriptor{TripId: "7020205685", StartDate: "20240515", ScheduleRelationship: realtime.TripCanceled},
+			}},
+			{Id: "3", TripUpdate: &realtime.TripUpdate{
+				Trip: realtime.TripDescriptor{TripId: "7024545685"},
+				StopTimeUpdates: []*realtime.StopTimeUpdate{
+					{StopId: "3607", Arrival: &realtime.StopTimeEvent{Time: &late}, Departure: &realtime.StopTimeEvent{Time: &late}},
+				},
+			}},
+		},
+	})
+	dataService.Realtime.State = state
+
+	return dataService
 }
 
 func runRouterTestCases[E APIEntity](t *testing.T, testCases []routerTestCase[E]) {
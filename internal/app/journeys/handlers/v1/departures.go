@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDeparturesDuration = time.Hour
+const maxDeparturesDuration = 24 * time.Hour
+const defaultDeparturesLimit = 10
+const maxDeparturesLimit = 100
+
+var errInvalidDeparturesFrom = errors.New("invalid from, expected hh:mm, hh:mm:ss or an ISO-8601 timestamp")
+var errInvalidDeparturesDuration = errors.New(fmt.Sprintf("invalid duration, expected a duration such as 90m, at most %v", maxDeparturesDuration))
+var errInvalidDeparturesLimit = errors.New(fmt.Sprintf("invalid limit, expected a number between 1 and %v", maxDeparturesLimit))
+
+func HandleGetDeparturesForStopPoint(service *service.JourneysDataService, baseUrl string, vehicleActivityBaseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		stopPointId := mux.Vars(req)["name"]
+
+		from, until, limit, err := getDeparturesWindow(req, service.Clock)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		setRealtimeCacheControl(service, rw)
+		if departuresWindowIsRelative(req) {
+			setNoStoreCacheControl(rw)
+		}
+
+		var departures []Departure
+		for _, md := range service.Realtime.Departures(stopPointId, from, until, limit) {
+			departures = append(departures, convertDeparture(md, baseUrl, vehicleActivityBaseUrl))
+		}
+
+		sendSuccessResponse(departures, getExcludeFieldsQueryParameter(req), rw)
+	}
+}
+
+// departuresWindowIsRelative tells whether the window of a departures query depends on the current time, because
+// neither an absolute from nor date is given.
+func departuresWindowIsRelative(req *http.Request) bool {
+	query := req.URL.Query()
+	return query.Get("date") == "" && !strings.Contains(query.Get("from"), "T")
+}
+
+// getDeparturesWindow reads the time window and the limit of a departures query. The window starts at from, which
+// is either a time on the service day of date or an absolute timestamp. Without from, the window starts at the
+// start of the service day of date, or now if date is not given either.
+func getDeparturesWindow(req *http.Request, clock *service.Clock) (time.Time, time.Time, int, error) {
+	query := req.URL.Query()
+
+	var date time.Time
+	if v := query.Get("date"); v != "" {
+		d, err := clock.ParseDate(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, service.ErrInvalidDate
+		}
+		date = d
+	}
+
+	from := clock.Current()
+	if !date.IsZero() {
+		from = clock.ServiceDay(date)
+	}
+
+	if v := query.Get("from"); v != "" {
+		if strings.Contains(v, "T") {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return time.Time{}, time.Time{}, 0, errInvalidDeparturesFrom
+			}
+			from = t
+		} else {
+			offset, err := utils.ParseGtfsTime(v)
+			if err != nil {
+				return time.Time{}, time.Time{}, 0, errInvalidDeparturesFrom
+			}
+			if date.IsZero() {
+				date = clock.Date(clock.Current())
+			}
+			from = clock.At(date, offset)
+		}
+	}
+
+	duration := defaultDeparturesDuration
+	if v := query.Get("duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxDeparturesDuration {
+			return time.Time{}, time.Time{}, 0, errInvalidDeparturesDuration
+		}
+		duration = d
+	}
+
+	limit := defaultDeparturesLimit
+	if v := query.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxDeparturesLimit {
+			return time.Time{}, time.Time{}, 0, errInvalidDeparturesLimit
+		}
+		limit = l
+	}
+
+	return from, from.Add(duration), limit, nil
+}
+
+// convertDeparture converts a departure of RealtimeService.Departures, whose call always has a stop point.
+func convertDeparture(d *model.Departure, baseUrl string, vehicleActivityBaseUrl string) Departure {
+	var lineId string
+	if d.Journey.Line != nil {
+		lineId = d.Journey.Line.Name
+	}
+
+	departure := Departure{
+		JourneyUrl:        fmt.Sprintf("%v%v/%v", baseUrl, journeysPrefix, d.Journey.Id),
+		StopPointUrl:      fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, d.Call.StopPoint.ShortName),
+		ActivityUrl:       fmt.Sprintf("%v%v?journeyRef=%v", vehicleActivityBaseUrl, "/vehicle-activity", d.Journey.ActivityId),
+		LineUrl:           fmt.Sprintf("%v%v/%v", baseUrl, linePrefix, lineId),
+		LineId:            lineId,
+		HeadSign:          d.Journey.HeadSign,
+		Direction:         d.Journey.Direction,
+		Platform:          d.Call.StopPoint.PlatformCode,
+		ServiceDate:       d.ServiceDate.Format("2006-01-02"),
+		DepartureTime:     d.Call.DepartureTime,
+		DepartureDateTime: d.Time.Format(time.RFC3339),
+		Cancelled:         d.Estimate != nil && d.Estimate.Cancelled,
+		Realtime:          d.Estimate != nil,
+	}
+
+	if d.Estimate != nil {
+		e := callEstimate(d.Estimate, d.CallIndex)
+		delay := int(e.DepartureDelay / time.Second)
+		departure.ExpectedDepartureTime = expectedTime(d.Call.DepartureTime, e.DepartureDelay)
+		departure.ExpectedDepartureDateTime = d.ExpectedTime.Format(time.RFC3339)
+		departure.Delay = &delay
+		departure.Skipped = e.Skipped
+	}
+
+	return departure
+}
+
+type Departure struct {
+	JourneyUrl        string `json:"journeyUrl"`
+	StopPointUrl      string `json:"stopPointUrl"`
+	ActivityUrl       string `json:"activityUrl"`
+	LineUrl           string `json:"lineUrl"`
+	LineId            string `json:"lineId"`
+	HeadSign          string `json:"headSign"`
+	Direction         string `json:"directionId"`
+	Platform          string `json:"platform"`
+	ServiceDate       string `json:"serviceDate"`
+	DepartureTime     string `json:"departureTime"`
+	DepartureDateTime string `json:"departureDateTime"`
+	// The expected time is the scheduled time shifted by the realtime delay. It and the delay are only set when the
+	// journey has a realtime estimate.
+	ExpectedDepartureTime     string `json:"expectedDepartureTime,omitempty"`
+	ExpectedDepartureDateTime string `json:"expectedDepartureDateTime,omitempty"`
+	// Delay is the realtime delay of the departure in seconds.
+	Delay     *int `json:"delay,omitempty"`
+	Skipped   bool `json:"skipped"`
+	Cancelled bool `json:"cancelled"`
+	Realtime  bool `json:"realtime"`
+}
@@ -0,0 +1,152 @@
+//go:build journeys_stops_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"github.com/gorilla/mux"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeparturesRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	departures := handlerConfig{handler: HandleGetDeparturesForStopPoint(dataService, "", ""), url: "/v1/stop-points/{name}/departures"}
+
+	line3A := Departure{
+		JourneyUrl:        "/journeys/7024545685",
+		StopPointUrl:      "/stop-points/3615",
+		ActivityUrl:       "/vehicle-activity?journeyRef=3A_0720_3607_3615",
+		LineUrl:           "/lines/3A",
+		LineId:            "3A",
+		HeadSign:          "Lentävänniemi",
+		Direction:         "0",
+		ServiceDate:       "2024-05-15",
+		DepartureTime:     "07:20:00",
+		DepartureDateTime: "2024-05-15T07:20:00+03:00",
+	}
+
+	line1 := Departure{
+		JourneyUrl:        "/journeys/7020205685",
+		StopPointUrl:      "/stop-points/7017",
+		ActivityUrl:       "/vehicle-activity?journeyRef=1_1443_7015_7017",
+		LineUrl:           "/lines/1",
+		LineId:            "1",
+		HeadSign:          "Vatiala",
+		Direction:         "1",
+		ServiceDate:       "2024-05-15",
+		DepartureTime:     "14:43:00",
+		DepartureDateTime: "2024-05-15T14:43:00+03:00",
+	}
+
+	saturday3A := line3A
+	saturday3A.JourneyUrl = "/journeys/123456789"
+	saturday3A.ServiceDate = "2000-01-01"
+	saturday3A.DepartureDateTime = "2000-01-01T07:20:00+02:00"
+
+	testCases := []routerTestCase[Departure]{
+		{"/v1/stop-points/3615/departures?date=2024-05-15&from=07:00", []Departure{line3A}, false, departures},
+		{"/v1/stop-points/3615/departures?date=2024-05-15&from=07:30", []Departure{}, false, departures},
+		{"/v1/stop-points/3615/departures?date=2024-05-15&duration=24h", []Departure{line3A}, false, departures},
+		{"/v1/stop-points/3615/departures?date=2000-01-01&duration=24h", []Departure{saturday3A}, false, departures},
+		{"/v1/stop-points/7017/departures?from=2024-05-15T14:00:00%2B03:00&duration=2h", []Departure{line1}, false, departures},
+		{"/v1/stop-points/7017/departures?from=2024-05-15T14:00:00%2B03:00&duration=2h&limit=1", []Departure{line1}, false, departures},
+		// The current time of the test data service is 15:00 in Helsinki.
+		{"/v1/stop-points/7017/departures", []Departure{}, false, departures},
+		{"/v1/stop-points/7017/departures?from=14:00", []Departure{line1}, false, departures},
+		// The journey ends at 7015.
+		{"/v1/stop-points/7015/departures?date=2024-05-15&duration=24h", []Departure{}, false, departures},
+		{"/v1/stop-points/nonexistent/departures", []Departure{}, false, departures},
+		{"/v1/stop-points/3615/departures?limit=0", []Departure{}, true, departures},
+		{"/v1/stop-points/3615/departures?duration=25h", []Departure{}, true, departures},
+		{"/v1/stop-points/3615/departures?from=noon", []Departure{}, true, departures},
+		{"/v1/stop-points/3615/departures?date=2024-13-01", []Departure{}, true, departures},
+	}
+
+	runRouterTestCases(t, testCases)
+
+	// The responses whose window depends on the current time must not be cached.
+	cacheControls := []struct {
+		url      string
+		expected string
+	}{
+		{"/v1/stop-points/7017/departures", "no-store"},
+		{"/v1/stop-points/7017/departures?from=14:00", "no-store"},
+		{"/v1/stop-points/7017/departures?date=2024-05-15", ""},
+		{"/v1/stop-points/7017/departures?from=2024-05-15T14:00:00%2B03:00", ""},
+	}
+
+	for _, cc := range cacheControls {
+		router := mux.NewRouter()
+		router.HandleFunc(departures.url, departures.handler)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", cc.url, nil))
+		if got := rec.Header().Get("Cache-Control"); got != cc.expected {
+			t.Errorf("%v: expected Cache-Control %q, got %q", cc.url, cc.expected, got)
+		}
+	}
+}
+
+func TestDeparturesRealtimeRoutes(t *testing.T) {
+	dataService := newRealtimeTestDataService(t)
+
+	departures := handlerConfig{handler: HandleGetDeparturesForStopPoint(dataService, "", ""), url: "/v1/stop-points/{name}/departures"}
+
+	delay, noDelay := 120, 0
+
+	delayed := Departure{
+		JourneyUrl:                "/journeys/7020295685",
+		StopPointUrl:              "/stop-points/8171",
+		ActivityUrl:               "/vehicle-activity?journeyRef=1A_0630_8149_4600",
+		LineUrl:                   "/lines/1A",
+		LineId:                    "1A",
+		HeadSign:                  "Lentoasema",
+		Direction:                 "0",
+		ServiceDate:               "2024-05-15",
+		DepartureTime:             "06:31:30",
+		DepartureDateTime:         "2024-05-15T06:31:30+03:00",
+		ExpectedDepartureTime:     "06:33:30",
+		ExpectedDepartureDateTime: "2024-05-15T06:33:30+03:00",
+		Delay:                     &delay,
+		Realtime:                  true,
+	}
+
+	cancelled := Departure{
+		JourneyUrl:                "/journeys/7020205685",
+		StopPointUrl:              "/stop-points/7017",
+		ActivityUrl:               "/vehicle-activity?journeyRef=1_1443_7015_7017",
+		LineUrl:                   "/lines/1",
+		LineId:                    "1",
+		HeadSign:                  "Vatiala",
+		Direction:                 "1",
+		ServiceDate:               "2024-05-15",
+		DepartureTime:             "14:43:00",
+		DepartureDateTime:         "2024-05-15T14:43:00+03:00",
+		ExpectedDepartureTime:     "14:43:00",
+		ExpectedDepartureDateTime: "2024-05-15T14:43:00+03:00",
+		Delay:                     &noDelay,
+		Cancelled:                 true,
+		Realtime:                  true,
+	}
+
+	// The estimates are for the service day of testNow, so on the next day the journey runs on schedule.
+	nextDay := cancelled
+	nextDay.ServiceDate = "2024-05-16"
+	nextDay.DepartureDateTime = "2024-05-16T14:43:00+03:00"
+	nextDay.ExpectedDepartureTime = ""
+	nextDay.ExpectedDepartureDateTime = ""
+	nextDay.Delay = nil
+	nextDay.Cancelled = false
+	nextDay.Realtime = false
+
+	testCases := []routerTestCase[Departure]{
+		// The departure is scheduled before the window, but expected in it.
+		{"/v1/stop-points/8171/departures?date=2024-05-15&from=06:32", []Departure{delayed}, false, departures},
+		{"/v1/stop-points/8171/departures?date=2024-05-15&from=06:34", []Departure{}, false, departures},
+		{"/v1/stop-points/7017/departures?date=2024-05-15&from=14:00", []Departure{cancelled}, false, departures},
+		{"/v1/stop-points/7017/departures?date=2024-05-16&from=14:00", []Departure{nextDay}, false, departures},
+	}
+
+	runRouterTestCases(t, testCases)
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const geoJsonContentType = "application/geo+json"
+
+// GeoJsonFeatureCollection is a GeoJSON (RFC 7946) feature collection. GeoJSON responses are sent as is, without
+// the envelope of the other responses.
+type GeoJsonFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJsonFeature `json:"features"`
+}
+
+type GeoJsonFeature struct {
+	Type string `json:"type"`
+	// Geometry is nil for the entities without a location, and encoded as null.
+	Geometry   *GeoJsonGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+type GeoJsonGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// wantsGeoJson reports whether the request asks for GeoJSON, either with the format query parameter or with the
+// Accept header. Accept is added to the Vary header of the response, since the response depends on it.
+func wantsGeoJson(req *http.Request, w http.ResponseWriter) bool {
+	w.Header().Add("Vary", "Accept")
+	if req.URL.Query().Get("format") == "geojson" {
+		return true
+	}
+	return strings.Contains(req.Header.Get("Accept"), geoJsonContentType)
+}
+
+// sendEntities sends the entities as a GeoJSON FeatureCollection if the request asks for GeoJSON, and as a success
+// response otherwise. In GeoJSON the fields of an entity are the properties of its feature, except geometryFields,
+// which are replaced by the geometry returned by geometry for the index of the entity.
+func sendEntities[T APIEntity](entities []T, geometry func(int) *GeoJsonGeometry, geometryFields []string, req *http.Request, w http.ResponseWriter) {
+	if !wantsGeoJson(req, w) {
+		sendSuccessResponse(entities, getExcludeFieldsQueryParameter(req), w)
+		return
+	}
+
+	var features []GeoJsonFeature
+	for i, properties := range filterBodyElements(apiEntitiesToArrayOfAnyMaps(entities), getExcludeFieldsQueryParameter(req)) {
+		for _, field := range geometryFields {
+			delete(properties, field)
+		}
+		features = append(features, newGeoJsonFeature(geometry(i), properties))
+	}
+
+	sendGeoJson(newGeoJsonFeatureCollection(features), w)
+}
+
+func newGeoJsonFeatureCollection(features []GeoJsonFeature) GeoJsonFeatureCollection {
+	if features == nil {
+		features = []GeoJsonFeature{}
+	}
+	return GeoJsonFeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func newGeoJsonFeature(geometry *GeoJsonGeometry, properties map[string]any) GeoJsonFeature {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	return GeoJsonFeature{Type: "Feature", Geometry: geometry, Properties: properties}
+}
+
+func pointGeometry(stopPoint *model.StopPoint) *GeoJsonGeometry {
+	return &GeoJsonGeometry{Type: "Point", Coordinates: [2]float64{stopPoint.Longitude, stopPoint.Latitude}}
+}
+
+// shapeGeometry returns the shape as a LineString, or nil if the shape has less than two points.
+func shapeGeometry(shape []model.ShapePoint) *GeoJsonGeometry {
+	if len(shape) < 2 {
+		return nil
+	}
+
+	coordinates := make([][2]float64, 0, len(shape))
+	for _, p := range shape {
+		coordinates = append(coordinates, [2]float64{p.Longitude, p.Latitude})
+	}
+
+	return &GeoJsonGeometry{Type: "LineString", Coordinates: coordinates}
+}
+
+// stopPointsGeometry returns a LineString through the stop points, or nil if there are less than two.
+func stopPointsGeometry(stopPoints []*model.StopPoint) *GeoJsonGeometry {
+	shape := make([]model.ShapePoint, 0, len(stopPoints))
+	for _, sp := range stopPoints {
+		shape = append(shape, model.ShapePoint{Latitude: sp.Latitude, Longitude: sp.Longitude})
+	}
+	return shapeGeometry(shape)
+}
+
+func sendGeoJson(collection GeoJsonFeatureCollection, w http.ResponseWriter) {
+	response, err := json.Marshal(collection)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", geoJsonContentType)
+	sendResponse(response, w)
+}
@@ -0,0 +1,107 @@
+//go:build journeys_geojson_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testGeoJsonFeatureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type     string `json:"type"`
+		Geometry *struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	} `json:"features"`
+}
+
+func TestGeoJsonRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/stop-points", HandleGetAllStopPoints(dataService, ""))
+	router.HandleFunc("/v1/stop-points/{name}", HandleGetOneStopPoint(dataService, ""))
+	router.HandleFunc("/v1/routes", HandleGetAllRoutes(dataService, ""))
+	router.HandleFunc("/v1/routes/{name}", HandleGetOneRoute(dataService, ""))
+	router.HandleFunc("/v1/journey-patterns", HandleGetAllJourneyPatterns(dataService, ""))
+
+	testCases := []struct {
+		target string
+		accept string
+		// expected holds the geometry type and the coordinates of each feature, and expectedProperty the value of
+		// a property of the first feature.
+		expected         [][2]string
+		expectedProperty [2]any
+	}{
+		{"/v1/stop-points/4600?format=geojson", "", [][2]string{{"Point", "[23.97756,61.47561]"}}, [2]any{"shortName", "4600"}},
+		{"/v1/stop-points/4600", "application/geo+json", [][2]string{{"Point", "[23.97756,61.47561]"}}, [2]any{"name", "Vatiala"}},
+		{"/v1/stop-points/4600?format=geojson&exclude-fields=name", "", [][2]string{{"Point", "[23.97756,61.47561]"}}, [2]any{"name", nil}},
+		{"/v1/stop-points?format=geojson&near=61.4445,23.87235", "", [][2]string{
+			{"Point", "[23.87235,61.4445]"}, {"Point", "[23.86961,61.44173]"},
+		}, [2]any{"distance", float64(0)}},
+		{"/v1/stop-points/nonexistent?format=geojson", "", [][2]string{}, [2]any{}},
+		{"/v1/routes/1501146007035?format=geojson", "", [][2]string{{"LineString", "[[23.97764,61.4759],[23.97732,61.47694]]"}}, [2]any{"url", "/routes/1501146007035"}},
+		{"/v1/routes?format=geojson&lineId=1", "", [][2]string{
+			{"LineString", "[[23.64305,61.46557],[23.64453,61.46576],[23.64644,61.46593]]"},
+		}, [2]any{"lineUrl", "/lines/1"}},
+		{"/v1/journey-patterns?format=geojson&lineId=1A", "", [][2]string{{"LineString", "[[23.97764,61.4759],[23.97732,61.47694]]"}}, [2]any{"lineUrl", "/lines/1A"}},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.target, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/geo+json" {
+			t.Errorf("%v: expected a GeoJSON response, got %v %v", tc.target, rec.Code, rec.Header().Get("Content-Type"))
+			continue
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("%v: expected Vary: Accept, got %q", tc.target, rec.Header().Get("Vary"))
+		}
+
+		var collection testGeoJsonFeatureCollection
+		if err := json.Unmarshal(rec.Body.Bytes(), &collection); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if collection.Type != "FeatureCollection" {
+			t.Errorf("%v: expected a FeatureCollection, got %v", tc.target, collection.Type)
+		}
+
+		features := make([][2]string, 0)
+		for _, f := range collection.Features {
+			if f.Type != "Feature" || f.Geometry == nil {
+				t.Errorf("%v: expected a feature with a geometry", tc.target)
+				continue
+			}
+			if _, ok := f.Properties["location"]; ok {
+				t.Errorf("%v: the location should be the geometry, not a property", tc.target)
+			}
+			if _, ok := f.Properties["geographicCoordinateProjection"]; ok {
+				t.Errorf("%v: the projection should be the geometry, not a property", tc.target)
+			}
+			features = append(features, [2]string{f.Geometry.Type, string(f.Geometry.Coordinates)})
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, features, tc.target)
+
+		if len(collection.Features) > 0 {
+			key := tc.expectedProperty[0].(string)
+			if got := collection.Features[0].Properties[key]; got != tc.expectedProperty[1] {
+				t.Errorf("%v: expected property %v to be %v, got %v", tc.target, key, tc.expectedProperty[1], got)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"math"
 	"net/http"
 )
 
@@ -17,7 +18,7 @@ func HandleGetAllJourneyPatterns(service *service.JourneysDataService, baseUrl s
 			journeyPatterns = append(journeyPatterns, convertJourneyPattern(mjp, baseUrl))
 		}
 
-		sendSuccessResponse(journeyPatterns, getExcludeFieldsQueryParameter(req), rw)
+		sendEntities(journeyPatterns, func(i int) *GeoJsonGeometry { return journeyPatternGeometry(modelJourneyPatterns[i]) }, nil, req, rw)
 	}
 }
 
@@ -25,15 +26,58 @@ func HandleGetOneJourneyPattern(service *service.JourneysDataService, baseUrl st
 	return func(rw http.ResponseWriter, req *http.Request) {
 		mj, err := service.JourneyPatterns.GetOneById(mux.Vars(req)["name"])
 		if err != nil {
-			sendSuccessResponse([]JourneyPattern{}, getExcludeFieldsQueryParameter(req), rw)
+			sendEntities([]JourneyPattern{}, nil, nil, req, rw)
 			return
 		}
 
 		journeyPatterns := []JourneyPattern{convertJourneyPattern(mj, baseUrl)}
-		sendSuccessResponse(journeyPatterns, getExcludeFieldsQueryParameter(req), rw)
+		sendEntities(journeyPatterns, func(int) *GeoJsonGeometry { return journeyPatternGeometry(mj) }, nil, req, rw)
 	}
 }
 
+// HandleGetJourneyPatternSegments returns the parts of the route of a journey pattern between its consecutive stop
+// points. The geometry of the segments is selected like the geometry of the routes.
+func HandleGetJourneyPatternSegments(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		geometry, err := getRouteGeometryQueryParameters(req)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		mjp, err := service.JourneyPatterns.GetOneById(mux.Vars(req)["name"])
+		if err != nil {
+			sendEntities([]JourneyPatternSegment{}, nil, routeGeometryFields, req, rw)
+			return
+		}
+
+		var segments []JourneyPatternSegment
+		for _, s := range mjp.Segments {
+			segment := JourneyPatternSegment{
+				JourneyPatternUrl: fmt.Sprintf("%v%v/%v", baseUrl, journeyPatternPrefix, mjp.Id),
+				FromStopPoint:     convertJourneyPatternStopPoint(s.From, baseUrl),
+				ToStopPoint:       convertJourneyPatternStopPoint(s.To, baseUrl),
+				Distance:          math.Round(s.Distance),
+			}
+			segment.Projection, segment.EncodedPolyline = geometry.encode(s.Shape)
+			segments = append(segments, segment)
+		}
+
+		sendEntities(segments, func(i int) *GeoJsonGeometry { return shapeGeometry(geometry.simplify(mjp.Segments[i].Shape)) }, routeGeometryFields, req, rw)
+	}
+}
+
+// journeyPatternGeometry returns the shape of the route of the journey pattern, or a line through its stop points if
+// the route has no shape.
+func journeyPatternGeometry(jp *model.JourneyPattern) *GeoJsonGeometry {
+	if jp.Route != nil {
+		if geometry := shapeGeometry(jp.Route.Shape); geometry != nil {
+			return geometry
+		}
+	}
+	return stopPointsGeometry(jp.StopPoints)
+}
+
 func convertJourneyPattern(jp *model.JourneyPattern, baseUrl string) JourneyPattern {
 	var direction string
 
@@ -54,6 +98,7 @@ func convertJourneyPattern(jp *model.JourneyPattern, baseUrl string) JourneyPatt
 		OriginStop:      fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, jp.StopPoints[0].ShortName),
 		DestinationStop: fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, jp.StopPoints[len(jp.StopPoints)-1].ShortName),
 		Direction:       direction,
+		Length:          math.Round(jp.Length),
 	}
 
 	for _, v := range jp.StopPoints {
@@ -103,6 +148,18 @@ type JourneyPattern struct {
 	StopPoints      []JourneyPatternStopPoint `json:"stopPoints"`
 	Journeys        []JourneyPatternJourney   `json:"journeys"`
 	Direction       string                    `json:"direction"`
+	// Length is the distance from the first stop point to the last one along the route in meters.
+	Length float64 `json:"length"`
+}
+
+type JourneyPatternSegment struct {
+	JourneyPatternUrl string                  `json:"journeyPatternUrl"`
+	FromStopPoint     JourneyPatternStopPoint `json:"fromStopPoint"`
+	ToStopPoint       JourneyPatternStopPoint `json:"toStopPoint"`
+	// Distance is the length of the segment along the route in meters.
+	Distance        float64 `json:"distance"`
+	Projection      string  `json:"geographicCoordinateProjection,omitempty"`
+	EncodedPolyline string  `json:"encodedPolyline,omitempty"`
 }
 
 type JourneyPatternJourney struct {
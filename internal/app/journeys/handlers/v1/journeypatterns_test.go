@@ -78,6 +78,7 @@ func TestJourneyPatternsRoutes(t *testing.T) {
 							DayTypeExceptions: []DayTypeException{{"2021-04-05", "2021-04-05", "yes"}, {"2021-05-13", "2021-05-13", "no"}},
 						},
 					},
+					Length: 185,
 				},
 			}, false, one,
 		},
@@ -98,6 +99,48 @@ func TestJourneyPatternsRoutes(t *testing.T) {
 	runRouterTestCases(t, testCases)
 }
 
+func TestJourneyPatternSegmentsRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	segments := handlerConfig{handler: HandleGetJourneyPatternSegments(dataService, ""), url: "/v1/journey-patterns/{name}/segments"}
+
+	spm := getJourneyPatternStopPointMap()
+	segment := func(jp string, from string, to string, distance float64, projection string, polyline string) JourneyPatternSegment {
+		return JourneyPatternSegment{
+			JourneyPatternUrl: journeyPatternUrl(jp),
+			FromStopPoint:     spm[from],
+			ToStopPoint:       spm[to],
+			Distance:          distance,
+			Projection:        projection,
+			EncodedPolyline:   polyline,
+		}
+	}
+
+	testCases := []routerTestCase[JourneyPatternSegment]{
+		{"/v1/journey-patterns/047b0afc973ee2fd4fe92b128c3a932a/segments",
+			[]JourneyPatternSegment{
+				segment("047b0afc973ee2fd4fe92b128c3a932a", "7017", "7015", 185, "6146557,2364305:-19,-148:-17,-191", ""),
+			}, false, segments,
+		},
+		{"/v1/journey-patterns/047b0afc973ee2fd4fe92b128c3a932a/segments?geometry=encodedPolyline",
+			[]JourneyPatternSegment{
+				segment("047b0afc973ee2fd4fe92b128c3a932a", "7017", "7015", 185, "", "y~cvJaxhoCe@gHa@}J"),
+			}, false, segments,
+		},
+		{"/v1/journey-patterns/9bc7403ad27267edbfbd63c3e92e5afa/segments",
+			[]JourneyPatternSegment{
+				segment("9bc7403ad27267edbfbd63c3e92e5afa", "4600", "8171", 117, "6147590,2397764:-104,32", ""),
+				// The shape of the test data ends before the last stop point.
+				segment("9bc7403ad27267edbfbd63c3e92e5afa", "8171", "8149", 0, "6147694,2397732", ""),
+			}, false, segments,
+		},
+		{"/v1/journey-patterns/foobar/segments", []JourneyPatternSegment{}, false, segments},
+		{"/v1/journey-patterns/047b0afc973ee2fd4fe92b128c3a932a/segments?geometry=foo", []JourneyPatternSegment{}, true, segments},
+	}
+
+	runRouterTestCases(t, testCases)
+}
+
 func getJourneyPatternMap() map[string]JourneyPattern {
 	result := make(map[string]JourneyPattern)
 
@@ -109,11 +152,12 @@ func getJourneyPatternMap() map[string]JourneyPattern {
 		destinationStop string
 		name            string
 		direction       string
+		length          float64
 		stopPoints      []JourneyPatternStopPoint
 		journeys        []JourneyPatternJourney
 	}{
 		{"047b0afc973ee2fd4fe92b128c3a932a", "1", "1504270174600", "7017",
-			"7015", "Suupantori - Pirkkala", "1",
+			"7015", "Suupantori - Pirkkala", "1", 185,
 			[]JourneyPatternStopPoint{getJourneyPatternStopPointMap()["7017"], getJourneyPatternStopPointMap()["7015"]},
 			[]JourneyPatternJourney{
 				{
@@ -128,7 +172,7 @@ func getJourneyPatternMap() map[string]JourneyPattern {
 			}},
 
 		{"65f51d2f85284af2fad1305c0ce71033", "3A", "1517136151028", "3615",
-			"3607", "Näyttelijänkatu - Lavastajanpolku", "0",
+			"3607", "Näyttelijänkatu - Lavastajanpolku", "0", 20,
 			[]JourneyPatternStopPoint{getJourneyPatternStopPointMap()["3615"], getJourneyPatternStopPointMap()["3607"]},
 			[]JourneyPatternJourney{
 				{
@@ -152,7 +196,7 @@ func getJourneyPatternMap() map[string]JourneyPattern {
 			}},
 
 		{"9bc7403ad27267edbfbd63c3e92e5afa", "1A", "1501146007035", "4600",
-			"8149", "Vatiala - Sudenkorennontie", "0",
+			"8149", "Vatiala - Sudenkorennontie", "0", 117,
 			[]JourneyPatternStopPoint{getJourneyPatternStopPointMap()["4600"], getJourneyPatternStopPointMap()["8171"], getJourneyPatternStopPointMap()["8149"]},
 			[]JourneyPatternJourney{
 				{
@@ -167,7 +211,7 @@ func getJourneyPatternMap() map[string]JourneyPattern {
 			}},
 
 		{"c01c71b0c9f456ba21f498a1dca54b3b", "-1", "111111111", "3615",
-			"7017", "Näyttelijänkatu - Suupantori", "0",
+			"7017", "Näyttelijänkatu - Suupantori", "0", 0,
 			[]JourneyPatternStopPoint{getJourneyPatternStopPointMap()["3615"], getJourneyPatternStopPointMap()["7017"]},
 			[]JourneyPatternJourney{
 				{
@@ -193,6 +237,7 @@ func getJourneyPatternMap() map[string]JourneyPattern {
 			Name:            tc.name,
 			StopPoints:      tc.stopPoints,
 			Journeys:        tc.journeys,
+			Length:          tc.length,
 		}
 	}
 
@@ -5,43 +5,81 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"math"
 	"net/http"
+	"time"
 )
 
 func HandleGetAllJourneys(service *service.JourneysDataService, baseUrl string, vehicleActivityBaseUrl string) func(http.ResponseWriter, *http.Request) {
 	return func(rw http.ResponseWriter, req *http.Request) {
-		modelJourneys := service.Journeys.Search(getQueryParameters(req), true)
+		times, err := getServiceDayTimes(req, service.Clock)
+		if err != nil {
+			sendFailResponse("invalid serviceDate, expected YYYY-MM-DD", http.StatusBadRequest, rw)
+			return
+		}
+
+		searchParams := getQueryParameters(req)
+		if _, err := service.Clock.ParseDateRange(searchParams); err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		modelJourneys := service.Journeys.Search(searchParams, true)
 
 		var journeys []Journey
 		for _, mj := range modelJourneys {
-			journeys = append(journeys, convertJourney(mj, baseUrl, vehicleActivityBaseUrl))
+			journeys = append(journeys, convertJourney(mj, getJourneyEstimate(service, mj, times), baseUrl, vehicleActivityBaseUrl, times))
 		}
 
+		setRealtimeCacheControl(service, rw)
 		sendSuccessResponse(journeys, getExcludeFieldsQueryParameter(req), rw)
 	}
 }
 
 func HandleGetOneJourney(service *service.JourneysDataService, baseUrl string, vehicleActivityBaseUrl string) func(http.ResponseWriter, *http.Request) {
 	return func(rw http.ResponseWriter, req *http.Request) {
+		times, err := getServiceDayTimes(req, service.Clock)
+		if err != nil {
+			sendFailResponse("invalid serviceDate, expected YYYY-MM-DD", http.StatusBadRequest, rw)
+			return
+		}
+
 		mj, err := service.Journeys.GetOneById(mux.Vars(req)["name"])
 		if err != nil {
 			sendSuccessResponse([]Journey{}, getExcludeFieldsQueryParameter(req), rw)
 			return
 		}
 
-		journeys := []Journey{convertJourney(mj, baseUrl, vehicleActivityBaseUrl)}
+		journeys := []Journey{convertJourney(mj, getJourneyEstimate(service, mj, times), baseUrl, vehicleActivityBaseUrl, times)}
+		setRealtimeCacheControl(service, rw)
 		sendSuccessResponse(journeys, getExcludeFieldsQueryParameter(req), rw)
 	}
 }
 
-func convertJourney(j *model.Journey, baseUrl string, vehicleActivityBaseUrl string) Journey {
+func convertJourney(j *model.Journey, estimate *model.JourneyEstimate, baseUrl string, vehicleActivityBaseUrl string, times *serviceDayTimes) Journey {
 	calls := make([]JourneyCall, 0)
-	for _, c := range j.Calls {
-		calls = append(calls, JourneyCall{
-			DepartureTime: c.DepartureTime,
-			ArrivalTime:   c.ArrivalTime,
-			StopPoint:     convertJourneyStopPoint(c.StopPoint, baseUrl),
-		})
+	for i, c := range j.Calls {
+		call := JourneyCall{
+			DepartureTime:     c.DepartureTime,
+			ArrivalTime:       c.ArrivalTime,
+			DepartureDateTime: times.format(c.DepartureTime),
+			ArrivalDateTime:   times.format(c.ArrivalTime),
+			DistanceTraveled:  math.Round(c.DistanceTraveled),
+			StopPoint:         convertJourneyStopPoint(c.StopPoint, baseUrl),
+		}
+
+		if estimate != nil {
+			e := callEstimate(estimate, i)
+			delay := int(e.DepartureDelay / time.Second)
+			call.ExpectedDepartureTime = expectedTime(c.DepartureTime, e.DepartureDelay)
+			call.ExpectedArrivalTime = expectedTime(c.ArrivalTime, e.ArrivalDelay)
+			call.ExpectedDepartureDateTime = times.format(call.ExpectedDepartureTime)
+			call.ExpectedArrivalDateTime = times.format(call.ExpectedArrivalTime)
+			call.Delay = &delay
+			call.Skipped = e.Skipped
+		}
+
+		calls = append(calls, call)
 	}
 
 	dayTypeExceptions := makeDayTypeExceptions(j)
@@ -79,6 +117,11 @@ func convertJourney(j *model.Journey, baseUrl string, vehicleActivityBaseUrl str
 		DayTypeExceptions:    dayTypeExceptions,
 		DepartureTime:        j.DepartureTime,
 		ArrivalTime:          j.ArrivalTime,
+		DepartureDateTime:    times.format(j.DepartureTime),
+		ArrivalDateTime:      times.format(j.ArrivalTime),
+		Length:               math.Round(j.Length),
+		Realtime:             estimate != nil,
+		Cancelled:            estimate != nil && estimate.Cancelled,
 	}
 }
 
@@ -128,6 +171,8 @@ type Journey struct {
 	JourneyPatternUrl    string             `json:"journeyPatternUrl"`
 	DepartureTime        string             `json:"departureTime"`
 	ArrivalTime          string             `json:"arrivalTime"`
+	DepartureDateTime    string             `json:"departureDateTime,omitempty"`
+	ArrivalDateTime      string             `json:"arrivalDateTime,omitempty"`
 	HeadSign             string             `json:"headSign"`
 	Direction            string             `json:"directionId"`
 	WheelchairAccessible bool               `json:"wheelchairAccessible"`
@@ -135,6 +180,11 @@ type Journey struct {
 	DayTypes             []string           `json:"dayTypes"`
 	DayTypeExceptions    []DayTypeException `json:"dayTypeExceptions"`
 	Calls                []JourneyCall      `json:"calls"`
+	// Length is the distance from the first call to the last one along the route in meters.
+	Length float64 `json:"length"`
+	// Realtime tells whether the expected times of the calls are realtime estimates, or the scheduled times.
+	Realtime  bool `json:"realtime"`
+	Cancelled bool `json:"cancelled"`
 }
 
 type JourneyGtfsInfo struct {
@@ -148,9 +198,23 @@ type DayTypeException struct {
 }
 
 type JourneyCall struct {
-	DepartureTime string           `json:"departureTime"`
-	ArrivalTime   string           `json:"arrivalTime"`
-	StopPoint     JourneyStopPoint `json:"stopPoint"`
+	DepartureTime     string `json:"departureTime"`
+	ArrivalTime       string `json:"arrivalTime"`
+	DepartureDateTime string `json:"departureDateTime,omitempty"`
+	ArrivalDateTime   string `json:"arrivalDateTime,omitempty"`
+	// The expected times are the scheduled times shifted by the realtime delays. They and the delay are only set
+	// when the journey has a realtime estimate, so they are left out of the calls of the trip plans, which follow the
+	// schedule.
+	ExpectedDepartureTime     string `json:"expectedDepartureTime,omitempty"`
+	ExpectedArrivalTime       string `json:"expectedArrivalTime,omitempty"`
+	ExpectedDepartureDateTime string `json:"expectedDepartureDateTime,omitempty"`
+	ExpectedArrivalDateTime   string `json:"expectedArrivalDateTime,omitempty"`
+	// Delay is the realtime delay of the departure in seconds.
+	Delay   *int `json:"delay,omitempty"`
+	Skipped bool `json:"skipped,omitempty"`
+	// DistanceTraveled is the distance from the first call along the route in meters.
+	DistanceTraveled float64          `json:"distanceTraveled"`
+	StopPoint        JourneyStopPoint `json:"stopPoint"`
 }
 
 type JourneyStopPoint struct {
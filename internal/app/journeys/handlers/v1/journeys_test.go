@@ -4,9 +4,14 @@ package v1
 
 import (
 	"fmt"
+	"net/http/httptest"
+	"slices"
 	"testing"
 )
 
+// noDelay is the delay of the calls of a realtime estimate which are on schedule.
+var noDelay = 0
+
 func TestJourneysRoutes(t *testing.T) {
 	dataService := newJourneysTestDataService(t)
 
@@ -14,6 +19,15 @@ func TestJourneysRoutes(t *testing.T) {
 	all := handlerConfig{handler: HandleGetAllJourneys(dataService, "", ""), url: "/v1/journeys"}
 
 	jm := getJourneyMap()
+
+	withDateTimes := jm["7020205685"]
+	withDateTimes.DepartureDateTime = "2024-05-15T14:43:00+03:00"
+	withDateTimes.ArrivalDateTime = "2024-05-15T14:44:45+03:00"
+	withDateTimes.Calls = []JourneyCall{
+		{DepartureTime: "14:43:00", ArrivalTime: "14:43:00", DepartureDateTime: "2024-05-15T14:43:00+03:00", ArrivalDateTime: "2024-05-15T14:43:00+03:00", StopPoint: getJourneyStopPointMap()["7017"]},
+		{DepartureTime: "14:44:45", ArrivalTime: "14:44:45", DepartureDateTime: "2024-05-15T14:44:45+03:00", ArrivalDateTime: "2024-05-15T14:44:45+03:00", DistanceTraveled: 185, StopPoint: getJourneyStopPointMap()["7015"]},
+	}
+
 	testCases := []routerTestCase[Journey]{
 		{"/v1/journeys",
 			[]Journey{jm["7020205685"], jm["7020295685"], jm["7024545685"]}, false, all,
@@ -42,6 +56,33 @@ func TestJourneysRoutes(t *testing.T) {
 		{"/v1/journeys?arrivalTime=14:44:45",
 			[]Journey{jm["7020205685"]}, false, all,
 		},
+		{"/v1/journeys?departureTime=14:43",
+			[]Journey{jm["7020205685"]}, false, all,
+		},
+		{"/v1/journeys?departureTime=38:43",
+			[]Journey{}, false, all,
+		},
+		{"/v1/journeys?departureTime=14:43&serviceDate=2024-05-15",
+			[]Journey{withDateTimes}, false, all,
+		},
+		{"/v1/journeys/7020205685?serviceDate=2024-05-15",
+			[]Journey{withDateTimes}, false, one,
+		},
+		{"/v1/journeys?serviceDate=15.5.2024",
+			[]Journey{}, true, all,
+		},
+		{"/v1/journeys?date=2021-04-05",
+			[]Journey{jm["7020205685"], jm["7020295685"], jm["7024545685"]}, false, all,
+		},
+		{"/v1/journeys?date=2021-05-13",
+			[]Journey{}, false, all,
+		},
+		{"/v1/journeys?startDate=2021-05-13&endDate=2021-05-14&lineId=1",
+			[]Journey{jm["7020205685"]}, false, all,
+		},
+		{"/v1/journeys?date=2021-05-13&endDate=2021-05-14",
+			[]Journey{}, true, all,
+		},
 		{"/v1/journeys?gtfsTripId=7020295685",
 			[]Journey{jm["7020295685"]}, false, all,
 		},
@@ -74,6 +115,84 @@ func TestJourneysRoutes(t *testing.T) {
 	runRouterTestCases(t, testCases)
 }
 
+func TestJourneysRealtimeRoutes(t *testing.T) {
+	dataService := newRealtimeTestDataService(t)
+
+	one := handlerConfig{handler: HandleGetOneJourney(dataService, "", ""), url: "/v1/journeys/{name}"}
+	all := handlerConfig{handler: HandleGetAllJourneys(dataService, "", ""), url: "/v1/journeys"}
+
+	jm := getJourneyMap()
+	delay, lateDelay := 120, 900
+
+	// The calls of a journey with a realtime estimate have the expected times and the delay, which are changed
+	// below for the delayed calls.
+	estimated := func(j Journey) Journey {
+		j.Realtime = true
+		j.Calls = slices.Clone(j.Calls)
+		for i := range j.Calls {
+			c := &j.Calls[i]
+			c.ExpectedDepartureTime, c.ExpectedArrivalTime = c.DepartureTime, c.ArrivalTime
+			c.Delay = &noDelay
+		}
+		return j
+	}
+
+	delayed := estimated(jm["7020295685"])
+	delayed.Calls[1].ExpectedDepartureTime, delayed.Calls[1].ExpectedArrivalTime = "06:33:30", "06:33:30"
+	delayed.Calls[1].Delay = &delay
+	delayed.Calls[2].ExpectedDepartureTime, delayed.Calls[2].ExpectedArrivalTime = "06:34:30", "06:34:30"
+	delayed.Calls[2].Delay = &delay
+	delayed.Calls[2].Skipped = true
+
+	cancelled := estimated(jm["7020205685"])
+	cancelled.Cancelled = true
+
+	late := estimated(jm["7024545685"])
+	late.Calls[1].ExpectedDepartureTime, late.Calls[1].ExpectedArrivalTime = "07:36:00", "07:36:00"
+	late.Calls[1].Delay = &lateDelay
+
+	withDateTimes := delayed
+	withDateTimes.DepartureDateTime = "2024-05-15T06:30:00+03:00"
+	withDateTimes.ArrivalDateTime = "2024-05-15T06:32:30+03:00"
+	withDateTimes.Calls = []JourneyCall{
+		{DepartureTime: "06:30:00", ArrivalTime: "06:30:00", DepartureDateTime: "2024-05-15T06:30:00+03:00", ArrivalDateTime: "2024-05-15T06:30:00+03:00", ExpectedDepartureTime: "06:30:00", ExpectedArrivalTime: "06:30:00", ExpectedDepartureDateTime: "2024-05-15T06:30:00+03:00", ExpectedArrivalDateTime: "2024-05-15T06:30:00+03:00", Delay: &noDelay, StopPoint: getJourneyStopPointMap()["4600"]},
+		{DepartureTime: "06:31:30", ArrivalTime: "06:31:30", DepartureDateTime: "2024-05-15T06:31:30+03:00", ArrivalDateTime: "2024-05-15T06:31:30+03:00", ExpectedDepartureTime: "06:33:30", ExpectedArrivalTime: "06:33:30", ExpectedDepartureDateTime: "2024-05-15T06:33:30+03:00", ExpectedArrivalDateTime: "2024-05-15T06:33:30+03:00", Delay: &delay, DistanceTraveled: 117, StopPoint: getJourneyStopPointMap()["8171"]},
+		{DepartureTime: "06:32:30", ArrivalTime: "06:32:30", DepartureDateTime: "2024-05-15T06:32:30+03:00", ArrivalDateTime: "2024-05-15T06:32:30+03:00", ExpectedDepartureTime: "06:34:30", ExpectedArrivalTime: "06:34:30", ExpectedDepartureDateTime: "2024-05-15T06:34:30+03:00", ExpectedArrivalDateTime: "2024-05-15T06:34:30+03:00", Delay: &delay, Skipped: true, DistanceTraveled: 117, StopPoint: getJourneyStopPointMap()["8149"]},
+	}
+
+	// The estimates are for the service day of testNow, so on the next day the journey runs on schedule.
+	nextDay := jm["7020205685"]
+	nextDay.DepartureDateTime = "2024-05-16T14:43:00+03:00"
+	nextDay.ArrivalDateTime = "2024-05-16T14:44:45+03:00"
+	nextDay.Calls = []JourneyCall{
+		{DepartureTime: "14:43:00", ArrivalTime: "14:43:00", DepartureDateTime: "2024-05-16T14:43:00+03:00", ArrivalDateTime: "2024-05-16T14:43:00+03:00", StopPoint: getJourneyStopPointMap()["7017"]},
+		{DepartureTime: "14:44:45", ArrivalTime: "14:44:45", DepartureDateTime: "2024-05-16T14:44:45+03:00", ArrivalDateTime: "2024-05-16T14:44:45+03:00", DistanceTraveled: 185, StopPoint: getJourneyStopPointMap()["7015"]},
+	}
+
+	testCases := []routerTestCase[Journey]{
+		{"/v1/journeys",
+			[]Journey{cancelled, delayed, late}, false, all,
+		},
+		{"/v1/journeys/7020295685",
+			[]Journey{delayed}, false, one,
+		},
+		{"/v1/journeys/7020295685?serviceDate=2024-05-15",
+			[]Journey{withDateTimes}, false, one,
+		},
+		{"/v1/journeys/7020205685?serviceDate=2024-05-16",
+			[]Journey{nextDay}, false, one,
+		},
+	}
+
+	runRouterTestCases(t, testCases)
+
+	rec := httptest.NewRecorder()
+	HandleGetAllJourneys(dataService, "", "")(rec, httptest.NewRequest("GET", "/v1/journeys", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected the realtime response not to be stored, got Cache-Control %q", got)
+	}
+}
+
 func getJourneyMap() map[string]Journey {
 	result := make(map[string]Journey)
 
@@ -92,6 +211,7 @@ func getJourneyMap() map[string]Journey {
 		dayTypes             []string
 		dayTypeExceptions    []DayTypeException
 		calls                []JourneyCall
+		length               float64
 	}{
 		{
 			"111111111",
@@ -108,9 +228,10 @@ func getJourneyMap() map[string]Journey {
 			[]string{"monday", "tuesday", "wednesday", "thursday", "friday"},
 			[]DayTypeException{},
 			[]JourneyCall{
-				{"07:20:00", "07:20:00", getJourneyStopPointMap()["3615"]},
-				{"07:21:00", "07:21:00", getJourneyStopPointMap()["7017"]},
+				{DepartureTime: "07:20:00", ArrivalTime: "07:20:00", StopPoint: getJourneyStopPointMap()["3615"]},
+				{DepartureTime: "07:21:00", ArrivalTime: "07:21:00", StopPoint: getJourneyStopPointMap()["7017"]},
 			},
+			0,
 		},
 		{
 			"7020205685",
@@ -127,9 +248,10 @@ func getJourneyMap() map[string]Journey {
 			[]string{"monday", "tuesday", "wednesday", "thursday", "friday"},
 			[]DayTypeException{{"2021-04-05", "2021-04-05", "yes"}, {"2021-05-13", "2021-05-13", "no"}},
 			[]JourneyCall{
-				{"14:43:00", "14:43:00", getJourneyStopPointMap()["7017"]},
-				{"14:44:45", "14:44:45", getJourneyStopPointMap()["7015"]},
+				{DepartureTime: "14:43:00", ArrivalTime: "14:43:00", StopPoint: getJourneyStopPointMap()["7017"]},
+				{DepartureTime: "14:44:45", ArrivalTime: "14:44:45", DistanceTraveled: 185, StopPoint: getJourneyStopPointMap()["7015"]},
 			},
+			185,
 		},
 		{
 			"7020295685",
@@ -146,10 +268,12 @@ func getJourneyMap() map[string]Journey {
 			[]string{"monday", "tuesday", "wednesday", "thursday", "friday"},
 			[]DayTypeException{{"2021-04-05", "2021-04-05", "yes"}, {"2021-05-13", "2021-05-13", "no"}},
 			[]JourneyCall{
-				{"06:30:00", "06:30:00", getJourneyStopPointMap()["4600"]},
-				{"06:31:30", "06:31:30", getJourneyStopPointMap()["8171"]},
-				{"06:32:30", "06:32:30", getJourneyStopPointMap()["8149"]},
+				{DepartureTime: "06:30:00", ArrivalTime: "06:30:00", StopPoint: getJourneyStopPointMap()["4600"]},
+				{DepartureTime: "06:31:30", ArrivalTime: "06:31:30", DistanceTraveled: 117, StopPoint: getJourneyStopPointMap()["8171"]},
+				// The shape of the test data ends before the last stop point.
+				{DepartureTime: "06:32:30", ArrivalTime: "06:32:30", DistanceTraveled: 117, StopPoint: getJourneyStopPointMap()["8149"]},
 			},
+			117,
 		},
 		{
 			"7024545685",
@@ -166,9 +290,10 @@ func getJourneyMap() map[string]Journey {
 			[]string{"monday", "tuesday", "wednesday", "thursday", "friday"},
 			[]DayTypeException{{"2021-04-05", "2021-04-05", "yes"}, {"2021-05-13", "2021-05-13", "no"}},
 			[]JourneyCall{
-				{"07:20:00", "07:20:00", getJourneyStopPointMap()["3615"]},
-				{"07:21:00", "07:21:00", getJourneyStopPointMap()["3607"]},
+				{DepartureTime: "07:20:00", ArrivalTime: "07:20:00", StopPoint: getJourneyStopPointMap()["3615"]},
+				{DepartureTime: "07:21:00", ArrivalTime: "07:21:00", DistanceTraveled: 20, StopPoint: getJourneyStopPointMap()["3607"]},
 			},
+			20,
 		},
 	}
 
@@ -188,6 +313,7 @@ func getJourneyMap() map[string]Journey {
 			DayTypes:             tc.dayTypes,
 			DayTypeExceptions:    tc.dayTypeExceptions,
 			Calls:                tc.calls,
+			Length:               tc.length,
 		}
 	}
 
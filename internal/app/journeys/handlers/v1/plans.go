@@ -0,0 +1,254 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxPlanTransfers = 5
+const maxPlanWalkDistance = 2000.0
+
+var errMissingPlanPlaces = errors.New("missing from or to")
+var errInvalidPlanPlace = errors.New("invalid from or to, expected a stop point id or lat,lon")
+var errInvalidDepartAt = errors.New("invalid departAt, expected hh:mm, hh:mm:ss or an ISO-8601 timestamp")
+var errInvalidMaxTransfers = errors.New(fmt.Sprintf("invalid maxTransfers, expected a number between 0 and %v", maxPlanTransfers))
+var errInvalidMaxWalkDistance = errors.New(fmt.Sprintf("invalid maxWalkDistance, expected meters between 0 and %v", maxPlanWalkDistance))
+var errInvalidWheelchair = errors.New("invalid wheelchair, expected true or false")
+
+func HandleGetPlans(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		query, err := getPlanQuery(req, service)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		if departAtIsRelative(req) {
+			setNoStoreCacheControl(rw)
+		}
+
+		var plans []Plan
+		for _, itinerary := range service.Planner.Plan(query) {
+			plans = append(plans, convertItinerary(itinerary, baseUrl, service.Clock))
+		}
+
+		sendSuccessResponse(plans, getExcludeFieldsQueryParameter(req), rw)
+	}
+}
+
+func getPlanQuery(req *http.Request, dataService *service.JourneysDataService) (service.PlanQuery, error) {
+	query := req.URL.Query()
+
+	if query.Get("from") == "" || query.Get("to") == "" {
+		return service.PlanQuery{}, errMissingPlanPlaces
+	}
+
+	from, err := getPlace(query.Get("from"), dataService)
+	if err != nil {
+		return service.PlanQuery{}, err
+	}
+
+	to, err := getPlace(query.Get("to"), dataService)
+	if err != nil {
+		return service.PlanQuery{}, err
+	}
+
+	options, err := getTripOptions(query, dataService.Clock)
+	if err != nil {
+		return service.PlanQuery{}, err
+	}
+
+	return service.PlanQuery{From: from, To: to, TripOptions: options}, nil
+}
+
+// departAtIsRelative tells whether the departure time of a trip query depends on the current time, because it is
+// not given as an absolute timestamp.
+func departAtIsRelative(req *http.Request) bool {
+	return !strings.Contains(req.URL.Query().Get("departAt"), "T")
+}
+
+// getTripOptions reads the departure time and the options shared by the trip plans and the reachability queries.
+func getTripOptions(query url.Values, clock *service.Clock) (service.TripOptions, error) {
+	result := service.TripOptions{
+		DepartAt:        clock.Current(),
+		MaxTransfers:    service.DefaultMaxTransfers,
+		MaxWalkDistance: service.DefaultMaxWalkDistance,
+		MinTransferTime: service.DefaultMinTransferTime,
+	}
+
+	if v := query.Get("departAt"); v != "" {
+		if strings.Contains(v, "T") {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return service.TripOptions{}, errInvalidDepartAt
+			}
+			result.DepartAt = t
+		} else {
+			offset, err := utils.ParseGtfsTime(v)
+			if err != nil {
+				return service.TripOptions{}, errInvalidDepartAt
+			}
+			result.DepartAt = clock.At(clock.Date(clock.Current()), offset)
+		}
+	}
+
+	if v := query.Get("maxTransfers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxPlanTransfers {
+			return service.TripOptions{}, errInvalidMaxTransfers
+		}
+		result.MaxTransfers = n
+	}
+
+	if v := query.Get("maxWalkDistance"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d < 0 || d > maxPlanWalkDistance {
+			return service.TripOptions{}, errInvalidMaxWalkDistance
+		}
+		result.MaxWalkDistance = d
+	}
+
+	if v := query.Get("wheelchair"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return service.TripOptions{}, errInvalidWheelchair
+		}
+		result.WheelchairAccessible = b
+	}
+
+	return result, nil
+}
+
+// getPlace reads an origin or a destination, which is either the id of a stop point or a lat,lon coordinate pair.
+func getPlace(value string, dataService *service.JourneysDataService) (model.Place, error) {
+	if parts := strings.Split(value, ","); len(parts) == 2 {
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return model.Place{}, errInvalidPlanPlace
+		}
+
+		lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return model.Place{}, errInvalidPlanPlace
+		}
+
+		return model.Place{Latitude: lat, Longitude: lon}, nil
+	}
+
+	sp, err := dataService.StopPoints.GetOneById(value)
+	if err != nil {
+		return model.Place{}, errInvalidPlanPlace
+	}
+
+	return model.Place{StopPoint: sp, Latitude: sp.Latitude, Longitude: sp.Longitude}, nil
+}
+
+func convertItinerary(itinerary *model.Itinerary, baseUrl string, clock *service.Clock) Plan {
+	first, last := itinerary.Legs[0], itinerary.Legs[len(itinerary.Legs)-1]
+
+	plan := Plan{
+		DepartureDateTime: first.Departure.Format(time.RFC3339),
+		ArrivalDateTime:   last.Arrival.Format(time.RFC3339),
+		Duration:          int(last.Arrival.Sub(first.Departure) / time.Second),
+		Transfers:         itinerary.Transfers,
+	}
+
+	var walkDistance float64
+	for _, leg := range itinerary.Legs {
+		walkDistance += leg.Distance
+		plan.Legs = append(plan.Legs, convertItineraryLeg(leg, baseUrl, clock))
+	}
+	plan.WalkDistance = math.Round(walkDistance)
+
+	return plan
+}
+
+func convertItineraryLeg(leg *model.ItineraryLeg, baseUrl string, clock *service.Clock) PlanLeg {
+	result := PlanLeg{
+		Mode:              string(leg.Mode),
+		From:              convertPlace(leg.From, baseUrl),
+		To:                convertPlace(leg.To, baseUrl),
+		DepartureDateTime: leg.Departure.Format(time.RFC3339),
+		ArrivalDateTime:   leg.Arrival.Format(time.RFC3339),
+		Distance:          math.Round(leg.Distance),
+	}
+
+	if leg.Journey == nil {
+		return result
+	}
+
+	result.JourneyUrl = fmt.Sprintf("%v%v/%v", baseUrl, journeysPrefix, leg.Journey.Id)
+	if leg.Journey.Line != nil {
+		result.LineUrl = fmt.Sprintf("%v%v/%v", baseUrl, linePrefix, leg.Journey.Line.Name)
+		result.LineId = leg.Journey.Line.Name
+	}
+	result.HeadSign = leg.Journey.HeadSign
+	result.ServiceDate = leg.ServiceDate.Format("2006-01-02")
+
+	times := &serviceDayTimes{clock: clock, serviceDay: leg.ServiceDate}
+	for _, c := range leg.Calls {
+		result.Calls = append(result.Calls, JourneyCall{
+			DepartureTime:     c.DepartureTime,
+			ArrivalTime:       c.ArrivalTime,
+			DepartureDateTime: times.format(c.DepartureTime),
+			ArrivalDateTime:   times.format(c.ArrivalTime),
+			DistanceTraveled:  math.Round(c.DistanceTraveled),
+			StopPoint:         convertJourneyStopPoint(c.StopPoint, baseUrl),
+		})
+	}
+
+	return result
+}
+
+func convertPlace(place model.Place, baseUrl string) PlanPlace {
+	result := PlanPlace{Location: fmt.Sprintf("%v,%v", place.Latitude, place.Longitude)}
+
+	if place.StopPoint != nil {
+		result.StopPointUrl = fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, place.StopPoint.ShortName)
+		result.ShortName = place.StopPoint.ShortName
+		result.Name = place.StopPoint.Name
+	}
+
+	return result
+}
+
+type Plan struct {
+	DepartureDateTime string `json:"departureDateTime"`
+	ArrivalDateTime   string `json:"arrivalDateTime"`
+	// Duration is the duration of the trip in seconds.
+	Duration     int       `json:"duration"`
+	Transfers    int       `json:"transfers"`
+	WalkDistance float64   `json:"walkDistance"`
+	Legs         []PlanLeg `json:"legs"`
+}
+
+type PlanLeg struct {
+	Mode              string        `json:"mode"`
+	From              PlanPlace     `json:"from"`
+	To                PlanPlace     `json:"to"`
+	DepartureDateTime string        `json:"departureDateTime"`
+	ArrivalDateTime   string        `json:"arrivalDateTime"`
+	Distance          float64       `json:"distance,omitempty"`
+	JourneyUrl        string        `json:"journeyUrl,omitempty"`
+	LineUrl           string        `json:"lineUrl,omitempty"`
+	LineId            string        `json:"lineId,omitempty"`
+	HeadSign          string        `json:"headSign,omitempty"`
+	ServiceDate       string        `json:"serviceDate,omitempty"`
+	Calls             []JourneyCall `json:"calls,omitempty"`
+}
+
+type PlanPlace struct {
+	StopPointUrl string `json:"stopPointUrl,omitempty"`
+	ShortName    string `json:"shortName,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Location     string `json:"location"`
+}
@@ -0,0 +1,113 @@
+//go:build journeys_plans_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"github.com/gorilla/mux"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlansRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	plans := handlerConfig{handler: HandleGetPlans(dataService, ""), url: "/v1/plans"}
+
+	municipality := JourneyMunicipality{Url: "/municipalities/211", ShortName: "211", Name: "Kangasala"}
+	vatiala := JourneyStopPoint{Url: "/stop-points/4600", ShortName: "4600", Name: "Vatiala", Location: "61.47561,23.97756", TariffZone: "B", Municipality: municipality}
+	vallintie := JourneyStopPoint{Url: "/stop-points/8171", ShortName: "8171", Name: "Vällintie", Location: "61.48067,23.97002", TariffZone: "B", Municipality: municipality}
+	sudenkorennontie := JourneyStopPoint{Url: "/stop-points/8149", ShortName: "8149", Name: "Sudenkorennontie", Location: "61.47979,23.96166", TariffZone: "C", Municipality: municipality}
+
+	vatialaPlace := PlanPlace{StopPointUrl: "/stop-points/4600", ShortName: "4600", Name: "Vatiala", Location: "61.47561,23.97756"}
+	sudenkorennontiePlace := PlanPlace{StopPointUrl: "/stop-points/8149", ShortName: "8149", Name: "Sudenkorennontie", Location: "61.47979,23.96166"}
+
+	transit := Plan{
+		DepartureDateTime: "2024-05-15T06:30:00+03:00",
+		ArrivalDateTime:   "2024-05-15T06:32:30+03:00",
+		Duration:          150,
+		Legs: []PlanLeg{{
+			Mode:              "transit",
+			From:              vatialaPlace,
+			To:                sudenkorennontiePlace,
+			DepartureDateTime: "2024-05-15T06:30:00+03:00",
+			ArrivalDateTime:   "2024-05-15T06:32:30+03:00",
+			JourneyUrl:        "/journeys/7020295685",
+			LineUrl:           "/lines/1A",
+			LineId:            "1A",
+			HeadSign:          "Lentoasema",
+			ServiceDate:       "2024-05-15",
+			Calls: []JourneyCall{
+				{DepartureTime: "06:30:00", ArrivalTime: "06:30:00", DepartureDateTime: "2024-05-15T06:30:00+03:00", ArrivalDateTime: "2024-05-15T06:30:00+03:00", StopPoint: vatiala},
+				{DepartureTime: "06:31:30", ArrivalTime: "06:31:30", DepartureDateTime: "2024-05-15T06:31:30+03:00", ArrivalDateTime: "2024-05-15T06:31:30+03:00", DistanceTraveled: 117, StopPoint: vallintie},
+				{DepartureTime: "06:32:30", ArrivalTime: "06:32:30", DepartureDateTime: "2024-05-15T06:32:30+03:00", ArrivalDateTime: "2024-05-15T06:32:30+03:00", DistanceTraveled: 117, StopPoint: sudenkorennontie},
+			},
+		}},
+	}
+
+	// The stop points are 964 meters apart.
+	walk := Plan{
+		DepartureDateTime: "2024-05-15T06:25:00+03:00",
+		ArrivalDateTime:   "2024-05-15T06:38:24+03:00",
+		Duration:          804,
+		WalkDistance:      964,
+		Legs: []PlanLeg{{
+			Mode:              "walk",
+			From:              vatialaPlace,
+			To:                sudenkorennontiePlace,
+			DepartureDateTime: "2024-05-15T06:25:00+03:00",
+			ArrivalDateTime:   "2024-05-15T06:38:24+03:00",
+			Distance:          964,
+		}},
+	}
+
+	testCases := []routerTestCase[Plan]{
+		{"/v1/plans?from=4600&to=8149&departAt=2024-05-15T06:25:00%2B03:00&maxWalkDistance=500", []Plan{transit}, false, plans},
+		{"/v1/plans?from=4600&to=8149&departAt=2024-05-15T06:25:00%2B03:00", []Plan{walk, transit}, false, plans},
+		// Walking is faster than waiting for the journey.
+		{"/v1/plans?from=4600&to=8149&departAt=2024-05-15T06:00:00%2B03:00", []Plan{withWalkDeparture(walk, "2024-05-15T06:00:00+03:00", "2024-05-15T06:13:24+03:00")}, false, plans},
+		// The current time of the test data service is 15:00 in Helsinki.
+		{"/v1/plans?from=4600&to=8149&departAt=06:25&maxWalkDistance=500", []Plan{transit}, false, plans},
+		// The journey has left on Friday and does not run on Saturday.
+		{"/v1/plans?from=4600&to=8149&departAt=2024-05-17T07:00:00%2B03:00&maxWalkDistance=500", []Plan{}, false, plans},
+		{"/v1/plans?from=4600&to=8149&departAt=06:25&maxWalkDistance=500&wheelchair=true", []Plan{}, false, plans},
+		{"/v1/plans?from=4600&to=4600", []Plan{}, false, plans},
+		{"/v1/plans?from=4600", []Plan{}, true, plans},
+		{"/v1/plans?from=nonexistent&to=8149", []Plan{}, true, plans},
+		{"/v1/plans?from=61.0,foo&to=8149", []Plan{}, true, plans},
+		{"/v1/plans?from=4600&to=8149&departAt=morning", []Plan{}, true, plans},
+		{"/v1/plans?from=4600&to=8149&maxTransfers=6", []Plan{}, true, plans},
+		{"/v1/plans?from=4600&to=8149&maxWalkDistance=-1", []Plan{}, true, plans},
+		{"/v1/plans?from=4600&to=8149&wheelchair=maybe", []Plan{}, true, plans},
+	}
+
+	runRouterTestCases(t, testCases)
+
+	cacheControls := []struct {
+		url      string
+		expected string
+	}{
+		{"/v1/plans?from=4600&to=8149", "no-store"},
+		{"/v1/plans?from=4600&to=8149&departAt=06:25", "no-store"},
+		{"/v1/plans?from=4600&to=8149&departAt=2024-05-15T06:25:00%2B03:00", ""},
+	}
+
+	for _, cc := range cacheControls {
+		router := mux.NewRouter()
+		router.HandleFunc(plans.url, plans.handler)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", cc.url, nil))
+		if got := rec.Header().Get("Cache-Control"); got != cc.expected {
+			t.Errorf("%v: expected Cache-Control %q, got %q", cc.url, cc.expected, got)
+		}
+	}
+}
+
+func withWalkDeparture(plan Plan, departure string, arrival string) Plan {
+	plan.DepartureDateTime = departure
+	plan.ArrivalDateTime = arrival
+	plan.Legs = []PlanLeg{plan.Legs[0]}
+	plan.Legs[0].DepartureDateTime = departure
+	plan.Legs[0].ArrivalDateTime = arrival
+	return plan
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultReachabilityDuration = 30 * time.Minute
+const maxReachabilityDuration = 3 * time.Hour
+
+var errMissingReachabilityFrom = errors.New("missing from")
+var errInvalidMaxDuration = errors.New(fmt.Sprintf("invalid maxDuration, expected a duration such as 45m, at most %v", maxReachabilityDuration))
+var errInvalidIsochrones = errors.New("invalid isochrones, expected a comma separated list of durations, at most maxDuration")
+
+func HandleGetReachability(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		query, isochrones, err := getReachabilityQuery(req, service)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		if departAtIsRelative(req) {
+			setNoStoreCacheControl(rw)
+		}
+
+		reachable := service.Planner.Reachable(query)
+
+		if wantsGeoJson(req, rw) {
+			var features []GeoJsonFeature
+			for _, d := range isochrones {
+				features = append(features, newGeoJsonFeature(
+					&GeoJsonGeometry{Type: "MultiPolygon", Coordinates: service.Planner.Isochrone(query, reachable, d)},
+					map[string]any{"duration": int(d / time.Second), "departAt": query.DepartAt.Format(time.RFC3339)},
+				))
+			}
+
+			sendGeoJson(newGeoJsonFeatureCollection(features), rw)
+			return
+		}
+
+		var stopPoints []ReachableStopPoint
+		for _, r := range reachable {
+			stopPoints = append(stopPoints, ReachableStopPoint{
+				StopPoint:       convertStopPoint(r.StopPoint, baseUrl),
+				ArrivalDateTime: r.Arrival.Format(time.RFC3339),
+				Duration:        int(r.Arrival.Sub(query.DepartAt) / time.Second),
+				Transfers:       r.Transfers,
+			})
+		}
+
+		sendSuccessResponse(stopPoints, getExcludeFieldsQueryParameter(req), rw)
+	}
+}
+
+// getReachabilityQuery reads the reachability query and the durations of the isochrones, longest first. The
+// isochrones default to one of maxDuration.
+func getReachabilityQuery(req *http.Request, dataService *service.JourneysDataService) (service.ReachabilityQuery, []time.Duration, error) {
+	query := req.URL.Query()
+
+	if query.Get("from") == "" {
+		return service.ReachabilityQuery{}, nil, errMissingReachabilityFrom
+	}
+
+	from, err := getPlace(query.Get("from"), dataService)
+	if err != nil {
+		return service.ReachabilityQuery{}, nil, err
+	}
+
+	options, err := getTripOptions(query, dataService.Clock)
+	if err != nil {
+		return service.ReachabilityQuery{}, nil, err
+	}
+
+	maxDuration := defaultReachabilityDuration
+	if v := query.Get("maxDuration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxReachabilityDuration {
+			return service.ReachabilityQuery{}, nil, errInvalidMaxDuration
+		}
+		maxDuration = d
+	}
+
+	isochrones := []time.Duration{maxDuration}
+	if v := query.Get("isochrones"); v != "" {
+		isochrones = nil
+		for _, part := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(part))
+			if err != nil || d <= 0 || d > maxDuration {
+				return service.ReachabilityQuery{}, nil, errInvalidIsochrones
+			}
+			isochrones = append(isochrones, d)
+		}
+		sort.Slice(isochrones, func(x, y int) bool {
+			return isochrones[x] > isochrones[y]
+		})
+	}
+
+	return service.ReachabilityQuery{From: from, MaxDuration: maxDuration, TripOptions: options}, isochrones, nil
+}
+
+type ReachableStopPoint struct {
+	StopPoint       StopPoint `json:"stopPoint"`
+	ArrivalDateTime string    `json:"arrivalDateTime"`
+	// Duration is the time from the departure to the arrival in seconds.
+	Duration  int `json:"duration"`
+	Transfers int `json:"transfers"`
+}
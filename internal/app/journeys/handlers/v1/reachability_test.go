@@ -0,0 +1,137 @@
+//go:build journeys_plans_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReachabilityRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	reachability := handlerConfig{handler: HandleGetReachability(dataService, ""), url: "/v1/reachability"}
+
+	municipality := StopPointMunicipality{Url: "/municipalities/211", ShortName: "211", Name: "Kangasala"}
+	vatiala := StopPoint{Url: "/stop-points/4600", ShortName: "4600", Name: "Vatiala", Location: "61.47561,23.97756", TariffZone: "B", Municipality: municipality}
+	vallintie := StopPoint{Url: "/stop-points/8171", ShortName: "8171", Name: "Vällintie", Location: "61.48067,23.97002", TariffZone: "B", Municipality: municipality}
+	sudenkorennontie := StopPoint{Url: "/stop-points/8149", ShortName: "8149", Name: "Sudenkorennontie", Location: "61.47979,23.96166", TariffZone: "C", Municipality: municipality}
+
+	origin := ReachableStopPoint{StopPoint: vatiala, ArrivalDateTime: "2024-05-15T06:25:00+03:00"}
+	byJourney := []ReachableStopPoint{
+		origin,
+		{StopPoint: vallintie, ArrivalDateTime: "2024-05-15T06:31:30+03:00", Duration: 390},
+		{StopPoint: sudenkorennontie, ArrivalDateTime: "2024-05-15T06:32:30+03:00", Duration: 450},
+	}
+
+	testCases := []routerTestCase[ReachableStopPoint]{
+		{"/v1/reachability?from=4600&departAt=2024-05-15T06:25:00%2B03:00&maxDuration=10m&maxWalkDistance=500", byJourney, false, reachability},
+		{"/v1/reachability?from=4600&departAt=2024-05-15T06:25:00%2B03:00&maxDuration=7m&maxWalkDistance=500", byJourney[:2], false, reachability},
+		{"/v1/reachability?from=4600&departAt=2024-05-15T06:25:00%2B03:00&maxDuration=10m&maxWalkDistance=500&wheelchair=true", byJourney[:1], false, reachability},
+		// The current time of the test data service is 15:00 in Helsinki.
+		{"/v1/reachability?from=4600&departAt=06:25&maxDuration=10m&maxWalkDistance=500", byJourney, false, reachability},
+		{"/v1/reachability?from=60.1699,24.9384", []ReachableStopPoint{}, false, reachability},
+		{"/v1/reachability", []ReachableStopPoint{}, true, reachability},
+		{"/v1/reachability?from=nonexistent", []ReachableStopPoint{}, true, reachability},
+		{"/v1/reachability?from=4600&maxDuration=4h", []ReachableStopPoint{}, true, reachability},
+		{"/v1/reachability?from=4600&maxDuration=10m&isochrones=5m,20m", []ReachableStopPoint{}, true, reachability},
+		{"/v1/reachability?from=4600&maxTransfers=-1", []ReachableStopPoint{}, true, reachability},
+	}
+
+	runRouterTestCases(t, testCases)
+
+	cacheControls := []struct {
+		url      string
+		expected string
+	}{
+		{"/v1/reachability?from=4600", "no-store"},
+		{"/v1/reachability?from=4600&departAt=06:25", "no-store"},
+		{"/v1/reachability?from=4600&departAt=2024-05-15T06:25:00%2B03:00", ""},
+	}
+
+	for _, cc := range cacheControls {
+		router := mux.NewRouter()
+		router.HandleFunc(reachability.url, reachability.handler)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", cc.url, nil))
+		if got := rec.Header().Get("Cache-Control"); got != cc.expected {
+			t.Errorf("%v: expected Cache-Control %q, got %q", cc.url, cc.expected, got)
+		}
+	}
+}
+
+func TestReachabilityIsochrones(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/reachability", HandleGetReachability(dataService, ""))
+
+	req := httptest.NewRequest("GET", "/v1/reachability?from=4600&departAt=06:25&maxDuration=10m&isochrones=5m,10m&format=geojson", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("expected the GeoJSON content type, got %v", ct)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string           `json:"type"`
+				Coordinates [][][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("expected a feature collection of two isochrones, got %v", rec.Body.String())
+	}
+
+	// The longest isochrone comes first, so that the shorter ones are drawn on top of it.
+	for i, duration := range []float64{600, 300} {
+		f := collection.Features[i]
+		if f.Type != "Feature" || f.Geometry.Type != "MultiPolygon" || len(f.Geometry.Coordinates) == 0 {
+			t.Errorf("expected a MultiPolygon feature, got %v", f)
+		}
+		if f.Properties["duration"] != duration || f.Properties["departAt"] != "2024-05-15T06:25:00+03:00" {
+			t.Errorf("unexpected properties %v", f.Properties)
+		}
+	}
+
+	// The exterior rings are counterclockwise and the holes clockwise, so the signed areas of the rings add up to
+	// the area of the isochrone.
+	area := func(polygons [][][][2]float64) float64 {
+		total := 0.0
+		for _, polygon := range polygons {
+			for i, ring := range polygon {
+				if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+					t.Errorf("expected a closed ring, got %v", ring)
+				}
+
+				ringArea := 0.0
+				for j := 0; j < len(ring)-1; j++ {
+					ringArea += ring[j][0]*ring[j+1][1] - ring[j+1][0]*ring[j][1]
+				}
+				if (i == 0) != (ringArea > 0) {
+					t.Errorf("expected counterclockwise exterior rings and clockwise holes, got %v", polygon)
+				}
+				total += ringArea / 2
+			}
+		}
+		return total
+	}
+
+	// Ten minutes reach further than five.
+	if area(collection.Features[0].Geometry.Coordinates) <= area(collection.Features[1].Geometry.Coordinates) {
+		t.Errorf("expected the ten minute isochrone to be larger than the five minute one")
+	}
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"net/http"
+	"time"
+)
+
+// getJourneyEstimate returns the realtime estimate of the journey, or nil if there is none. When the request is for
+// a service date, only the estimate for that date is returned.
+func getJourneyEstimate(service *service.JourneysDataService, journey *model.Journey, times *serviceDayTimes) *model.JourneyEstimate {
+	estimate := service.Realtime.Journey(journey)
+	if estimate == nil || (times != nil && !estimate.ServiceDate.Equal(times.serviceDay)) {
+		return nil
+	}
+	return estimate
+}
+
+// callEstimate returns the estimate of the i:th call of the journey, or an estimate without a delay if the journey
+// has no estimate.
+func callEstimate(estimate *model.JourneyEstimate, i int) model.CallEstimate {
+	if estimate == nil || i < 0 || i >= len(estimate.Calls) {
+		return model.CallEstimate{}
+	}
+	return estimate.Calls[i]
+}
+
+// expectedTime returns the scheduled GTFS time shifted by the delay.
+func expectedTime(scheduled string, delay time.Duration) string {
+	if delay == 0 {
+		return scheduled
+	}
+
+	offset, err := utils.ParseGtfsTime(scheduled)
+	if err != nil {
+		return scheduled
+	}
+
+	return utils.FormatGtfsTime(offset + delay)
+}
+
+// setRealtimeCacheControl keeps the response out of the caches when it may hold realtime estimates, which would go
+// stale long before the scheduled data.
+func setRealtimeCacheControl(service *service.JourneysDataService, rw http.ResponseWriter) {
+	if service.Realtime != nil && service.Realtime.State != nil {
+		setNoStoreCacheControl(rw)
+	}
+}
+
+// setNoStoreCacheControl keeps the response out of the caches, which key the responses by the URL. It is used
+// for the responses which depend on the current time, so that the same URL would get a different response later.
+func setNoStoreCacheControl(rw http.ResponseWriter) {
+	rw.Header().Set("Cache-Control", "no-store")
+}
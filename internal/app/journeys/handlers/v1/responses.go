@@ -7,13 +7,25 @@ import (
 )
 
 type APIEntity interface {
-	Line | Journey | JourneyPattern | Route | StopPoint | Municipality | StopPointJourney
+	Line | Journey | JourneyPattern | JourneyPatternSegment | Route | StopPoint | Municipality | StopPointJourney | Departure | LineTimetable | Plan | ReachableStopPoint
 }
 
 func sendSuccessResponse[T APIEntity](body []T, fieldExclusions string, w http.ResponseWriter) {
 	sendJson(newSuccessResponse(filterBodyElements(apiEntitiesToArrayOfAnyMaps(body), fieldExclusions)), w)
 }
 
+func sendFailResponse(message string, status int, w http.ResponseWriter) {
+	response, err := json.Marshal(apiFailResponse{Status: "fail", Data: apiFailData{Message: message}})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	sendResponse(response, w)
+}
+
 func filterBodyElements(bodyElementsAsArrayOfAnyMaps []map[string]any, fieldExclusions string) []map[string]any {
 	if len(fieldExclusions) > 0 {
 		bodyElementsAsArrayOfAnyMaps = removeExcludedFields(bodyElementsAsArrayOfAnyMaps, fieldExclusions)
@@ -1,53 +1,157 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
 	"net/http"
+	"strconv"
 )
 
+const defaultPolylinePrecision = 5
+const maxPolylinePrecision = 7
+const maxGeometryTolerance = 1000.0
+
+var errInvalidGeometry = errors.New("invalid geometry, expected projection or encodedPolyline")
+var errInvalidPrecision = errors.New(fmt.Sprintf("invalid precision, expected a number between 1 and %v", maxPolylinePrecision))
+var errInvalidTolerance = errors.New(fmt.Sprintf("invalid tolerance, expected meters between 0 and %v", maxGeometryTolerance))
+
+// routeGeometryFields are the fields of a route replaced by the geometry in GeoJSON.
+var routeGeometryFields = []string{"geographicCoordinateProjection", "encodedPolyline"}
+
 func HandleGetAllRoutes(service *service.JourneysDataService, baseUrl string) func(http.ResponseWriter, *http.Request) {
 	return func(rw http.ResponseWriter, req *http.Request) {
+		geometry, err := getRouteGeometryQueryParameters(req)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
 		modelRoutes := service.Routes.Search(getQueryParameters(req))
 
 		var routes []Route
 		for _, ml := range modelRoutes {
-			routes = append(routes, convertRoute(ml, baseUrl))
+			routes = append(routes, convertRoute(ml, baseUrl, geometry))
 		}
 
-		sendSuccessResponse(routes, getExcludeFieldsQueryParameter(req), rw)
+		sendEntities(routes, func(i int) *GeoJsonGeometry { return shapeGeometry(geometry.simplify(modelRoutes[i].Shape)) }, routeGeometryFields, req, rw)
 	}
 }
 
 func HandleGetOneRoute(service *service.JourneysDataService, baseUrl string) func(http.ResponseWriter, *http.Request) {
 	return func(rw http.ResponseWriter, req *http.Request) {
+		geometry, err := getRouteGeometryQueryParameters(req)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
 		mr, err := service.Routes.GetOneById(mux.Vars(req)["name"])
 		if err != nil {
-			sendSuccessResponse([]Route{}, getExcludeFieldsQueryParameter(req), rw)
+			sendEntities([]Route{}, nil, routeGeometryFields, req, rw)
 			return
 		}
 
-		routes := []Route{convertRoute(mr, baseUrl)}
-		sendSuccessResponse(routes, getExcludeFieldsQueryParameter(req), rw)
+		routes := []Route{convertRoute(mr, baseUrl, geometry)}
+		sendEntities(routes, func(int) *GeoJsonGeometry { return shapeGeometry(geometry.simplify(mr.Shape)) }, routeGeometryFields, req, rw)
 	}
 }
 
-func convertRoute(route *model.Route, baseUrl string) Route {
-	var name string
+// routeGeometry selects the representation of the shapes of the routes, and how much they are simplified.
+type routeGeometry struct {
+	encodedPolyline bool
+	precision       int
+	// tolerance is the Douglas-Peucker tolerance in meters, zero keeps every point.
+	tolerance float64
+}
+
+func getRouteGeometryQueryParameters(req *http.Request) (routeGeometry, error) {
+	query := req.URL.Query()
+	result := routeGeometry{precision: defaultPolylinePrecision}
+
+	switch query.Get("geometry") {
+	case "", "projection":
+	case "encodedPolyline":
+		result.encodedPolyline = true
+	default:
+		return routeGeometry{}, errInvalidGeometry
+	}
+
+	if v := query.Get("precision"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 || p > maxPolylinePrecision {
+			return routeGeometry{}, errInvalidPrecision
+		}
+		result.precision = p
+	}
 
-	if len(route.JourneyPatterns) > 0 && len(route.JourneyPatterns[0].StopPoints) > 0 {
-		var firstStopPoint = route.JourneyPatterns[0].StopPoints[0]
-		var lastStopPoint = route.JourneyPatterns[0].StopPoints[len(route.JourneyPatterns[0].StopPoints)-1]
-		name = fmt.Sprintf("%v - %v", firstStopPoint.Name, lastStopPoint.Name)
+	if v := query.Get("tolerance"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 || t > maxGeometryTolerance {
+			return routeGeometry{}, errInvalidTolerance
+		}
+		result.tolerance = t
 	}
 
+	return result, nil
+}
+
+func (g routeGeometry) simplify(shape []model.ShapePoint) []model.ShapePoint {
+	if g.tolerance == 0 {
+		return shape
+	}
+
+	var result []model.ShapePoint
+	for _, c := range utils.SimplifyCoordinates(shapeCoordinates(shape), g.tolerance) {
+		result = append(result, model.ShapePoint{Latitude: c[0], Longitude: c[1]})
+	}
+
+	return result
+}
+
+// encode returns the simplified shape in the selected encoding, either as the coordinate projection or as the encoded
+// polyline.
+func (g routeGeometry) encode(shape []model.ShapePoint) (string, string) {
+	coords := shapeCoordinates(g.simplify(shape))
+	if g.encodedPolyline {
+		return "", utils.EncodePolyline(coords, g.precision)
+	}
+	return utils.EncodeCoordinateProjection(coords), ""
+}
+
+func shapeCoordinates(shape []model.ShapePoint) [][]float64 {
+	coords := make([][]float64, 0, len(shape))
+	for _, p := range shape {
+		coords = append(coords, []float64{p.Latitude, p.Longitude})
+	}
+	return coords
+}
+
+// routeName names a route after the first and the last stop point of its first journey pattern.
+func routeName(route *model.Route) string {
+	if len(route.JourneyPatterns) == 0 || len(route.JourneyPatterns[0].StopPoints) == 0 {
+		return ""
+	}
+
+	var firstStopPoint = route.JourneyPatterns[0].StopPoints[0]
+	var lastStopPoint = route.JourneyPatterns[0].StopPoints[len(route.JourneyPatterns[0].StopPoints)-1]
+	return fmt.Sprintf("%v - %v", firstStopPoint.Name, lastStopPoint.Name)
+}
+
+func convertRoute(route *model.Route, baseUrl string, geometry routeGeometry) Route {
 	converted := Route{
-		Projection: route.GeoProjection,
-		Url:        fmt.Sprintf("%v%v/%v", baseUrl, routePrefix, route.Id),
-		LineUrl:    fmt.Sprintf("%v%v/%v", baseUrl, linePrefix, route.Line.Name),
-		Name:       name,
+		Url:     fmt.Sprintf("%v%v/%v", baseUrl, routePrefix, route.Id),
+		LineUrl: fmt.Sprintf("%v%v/%v", baseUrl, linePrefix, route.Line.Name),
+		Name:    routeName(route),
+	}
+
+	if geometry.encodedPolyline || geometry.tolerance > 0 {
+		converted.Projection, converted.EncodedPolyline = geometry.encode(route.Shape)
+	} else {
+		converted.Projection = route.GeoProjection
 	}
 
 	for _, v := range route.Journeys {
@@ -72,7 +176,7 @@ func convertRoute(route *model.Route, baseUrl string) Route {
 			Url:             fmt.Sprintf("%v%v/%v", baseUrl, journeyPatternPrefix, v.Id),
 			OriginStop:      originStop,
 			DestinationStop: destinationStop,
-			Name:            name,
+			Name:            converted.Name,
 		})
 	}
 
@@ -80,7 +184,8 @@ func convertRoute(route *model.Route, baseUrl string) Route {
 }
 
 type Route struct {
-	Projection      string                `json:"geographicCoordinateProjection"`
+	Projection      string                `json:"geographicCoordinateProjection,omitempty"`
+	EncodedPolyline string                `json:"encodedPolyline,omitempty"`
 	Url             string                `json:"url"`
 	Name            string                `json:"name"`
 	LineUrl         string                `json:"lineUrl"`
@@ -32,12 +32,43 @@ func TestGetRoutes(t *testing.T) {
 			[]Route{},
 			false, one,
 		},
+		{"/v1/routes/1501146007035?geometry=encodedPolyline",
+			[]Route{withEncodedPolyline(rm[routeUrl("1501146007035")], "k_fvJgcjqCoE~@")},
+			false, one,
+		},
+		{"/v1/routes/1501146007035?geometry=encodedPolyline&precision=6",
+			[]Route{withEncodedPolyline(rm[routeUrl("1501146007035")], "wbegtBoinvl@_`A~R")},
+			false, one,
+		},
+		{"/v1/routes/1504270174600?tolerance=50",
+			[]Route{withProjection(rm[routeUrl("1504270174600")], "6146557,2364305:-36,-339")},
+			false, one,
+		},
+		{"/v1/routes/1504270174600?tolerance=1",
+			[]Route{rm[routeUrl("1504270174600")]},
+			false, one,
+		},
+		{"/v1/routes?geometry=foo", []Route{}, true, all},
+		{"/v1/routes?geometry=encodedPolyline&precision=8", []Route{}, true, all},
+		{"/v1/routes/1504270174600?tolerance=-1", []Route{}, true, one},
+		{"/v1/routes/1504270174600?tolerance=foo", []Route{}, true, one},
 	}
 
 	runRouterTestCases(t, testCases)
 
 }
 
+func withEncodedPolyline(route Route, polyline string) Route {
+	route.Projection = ""
+	route.EncodedPolyline = polyline
+	return route
+}
+
+func withProjection(route Route, projection string) Route {
+	route.Projection = projection
+	return route
+}
+
 func getRouteMap() map[string]Route {
 	result := make(map[string]Route)
 
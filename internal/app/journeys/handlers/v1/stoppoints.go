@@ -1,15 +1,55 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const defaultSearchLimit = 10
+const maxSearchLimit = 50
+
+const defaultNearbyRadius = 500.0
+const maxNearbyRadius = 5000.0
+
+var errInvalidNear = errors.New("invalid near, expected lat,lon")
+var errInvalidRadius = errors.New(fmt.Sprintf("invalid radius, expected meters between 0 and %v", maxNearbyRadius))
+var errMissingSearchQuery = errors.New("missing q")
+var errInvalidSearchLimit = errors.New(fmt.Sprintf("invalid limit, expected a number between 1 and %v", maxSearchLimit))
+
+// stopPointGeometryFields are the fields of a stop point replaced by the geometry in GeoJSON.
+var stopPointGeometryFields = []string{"location"}
+
 func HandleGetAllStopPoints(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("near") != "" {
+			lat, lon, radius, err := getNearQueryParameters(req)
+			if err != nil {
+				sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+				return
+			}
+
+			nearby := service.StopPoints.Nearby(lat, lon, radius, getQueryParameters(req))
+
+			var stopPoints []StopPoint
+			for _, nsp := range nearby {
+				sp := convertStopPoint(nsp.StopPoint, baseUrl)
+				distance := math.Round(nsp.Distance)
+				sp.Distance = &distance
+				stopPoints = append(stopPoints, sp)
+			}
+
+			sendEntities(stopPoints, func(i int) *GeoJsonGeometry { return pointGeometry(nearby[i].StopPoint) }, stopPointGeometryFields, req, rw)
+			return
+		}
+
 		modelStopPoints := service.StopPoints.Search(getQueryParameters(req))
 
 		var stopPoints []StopPoint
@@ -17,7 +57,36 @@ func HandleGetAllStopPoints(service *service.JourneysDataService, baseUrl string
 			stopPoints = append(stopPoints, convertStopPoint(msp, baseUrl))
 		}
 
-		sendSuccessResponse(stopPoints, getExcludeFieldsQueryParameter(req), rw)
+		sendEntities(stopPoints, func(i int) *GeoJsonGeometry { return pointGeometry(modelStopPoints[i]) }, stopPointGeometryFields, req, rw)
+	}
+}
+
+func HandleSearchStopPoints(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query().Get("q")
+		if strings.TrimSpace(query) == "" {
+			sendFailResponse(errMissingSearchQuery.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		limit := defaultSearchLimit
+		if v := req.URL.Query().Get("limit"); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil || l < 1 || l > maxSearchLimit {
+				sendFailResponse(errInvalidSearchLimit.Error(), http.StatusBadRequest, rw)
+				return
+			}
+			limit = l
+		}
+
+		matches := service.StopPoints.SearchByName(query, limit)
+
+		var stopPoints []StopPoint
+		for _, m := range matches {
+			stopPoints = append(stopPoints, convertStopPoint(m.StopPoint, baseUrl))
+		}
+
+		sendEntities(stopPoints, func(i int) *GeoJsonGeometry { return pointGeometry(matches[i].StopPoint) }, stopPointGeometryFields, req, rw)
 	}
 }
 
@@ -25,12 +94,12 @@ func HandleGetOneStopPoint(service *service.JourneysDataService, baseUrl string)
 	return func(rw http.ResponseWriter, req *http.Request) {
 		msp, err := service.StopPoints.GetOneById(mux.Vars(req)["name"])
 		if err != nil {
-			sendSuccessResponse([]StopPoint{}, getExcludeFieldsQueryParameter(req), rw)
+			sendEntities([]StopPoint{}, nil, stopPointGeometryFields, req, rw)
 			return
 		}
 
 		stopPoints := []StopPoint{convertStopPoint(msp, baseUrl)}
-		sendSuccessResponse(stopPoints, getExcludeFieldsQueryParameter(req), rw)
+		sendEntities(stopPoints, func(int) *GeoJsonGeometry { return pointGeometry(msp) }, stopPointGeometryFields, req, rw)
 	}
 }
 
@@ -38,19 +107,60 @@ func HandleGetJourneysForStopPoint(service *service.JourneysDataService, baseUrl
 	return func(rw http.ResponseWriter, req *http.Request) {
 		stopPointId := mux.Vars(req)["name"]
 
+		times, err := getServiceDayTimes(req, service.Clock)
+		if err != nil {
+			sendFailResponse("invalid serviceDate, expected YYYY-MM-DD", http.StatusBadRequest, rw)
+			return
+		}
+
 		searchParams := getQueryParameters(req)
+		if _, err := service.Clock.ParseDateRange(searchParams); err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
 		searchParams["stopPointId"] = stopPointId
 		modelJourneys := service.Journeys.Search(searchParams, excludeInactive)
 
 		var stopPointJourneys []StopPointJourney
 		for _, mj := range modelJourneys {
-			stopPointJourneys = append(stopPointJourneys, convertStopPointJourney(stopPointId, mj, baseUrl, vehicleActivityBaseUrl))
+			stopPointJourneys = append(stopPointJourneys, convertStopPointJourney(stopPointId, mj, getJourneyEstimate(service, mj, times), baseUrl, vehicleActivityBaseUrl, times))
 		}
 
+		setRealtimeCacheControl(service, rw)
 		sendSuccessResponse(stopPointJourneys, getExcludeFieldsQueryParameter(req), rw)
 	}
 }
 
+func getNearQueryParameters(req *http.Request) (float64, float64, float64, error) {
+	query := req.URL.Query()
+
+	parts := strings.Split(query.Get("near"), ",")
+	if len(parts) != 2 {
+		return 0, 0, 0, errInvalidNear
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, 0, errInvalidNear
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, 0, errInvalidNear
+	}
+
+	radius := defaultNearbyRadius
+	if v := query.Get("radius"); v != "" {
+		radius, err = strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 || radius > maxNearbyRadius {
+			return 0, 0, 0, errInvalidRadius
+		}
+	}
+
+	return lat, lon, radius, nil
+}
+
 func convertStopPoint(stopPoint *model.StopPoint, baseUrl string) StopPoint {
 	return StopPoint{
 		Url:          fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, stopPoint.ShortName),
@@ -70,12 +180,14 @@ func convertStopPointMunicipality(municipality *model.Municipality, baseUrl stri
 	}
 }
 
-func convertStopPointJourney(stopPointId string, j *model.Journey, baseUrl string, vehicleActivityBaseUrl string) StopPointJourney {
+func convertStopPointJourney(stopPointId string, j *model.Journey, estimate *model.JourneyEstimate, baseUrl string, vehicleActivityBaseUrl string, times *serviceDayTimes) StopPointJourney {
 	var arrivalTime, departureTime string
-	for _, c := range j.Calls {
+	callIndex := -1
+	for i, c := range j.Calls {
 		if c.StopPoint != nil && c.StopPoint.ShortName == stopPointId {
 			arrivalTime = c.ArrivalTime
 			departureTime = c.DepartureTime
+			callIndex = i
 		}
 	}
 
@@ -99,7 +211,7 @@ func convertStopPointJourney(stopPointId string, j *model.Journey, baseUrl strin
 		gtfsInfo = StopPointJourneyGtfsInfo{TripId: j.GtfsInfo.TripId}
 	}
 
-	return StopPointJourney{
+	journey := StopPointJourney{
 		JourneyUrl:           fmt.Sprintf("%v%v/%v", baseUrl, journeysPrefix, j.Id),
 		StopPointUrl:         fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, stopPointId),
 		ActivityUrl:          fmt.Sprintf("%v%v?journeyRef=%v", vehicleActivityBaseUrl, "/vehicle-activity", j.ActivityId),
@@ -115,9 +227,26 @@ func convertStopPointJourney(stopPointId string, j *model.Journey, baseUrl strin
 		DayTypeExceptions:    dayTypeExceptions,
 		DepartureTime:        departureTime,
 		ArrivalTime:          arrivalTime,
+		DepartureDateTime:    times.format(departureTime),
+		ArrivalDateTime:      times.format(arrivalTime),
 		ValidFrom:            j.ValidFrom,
 		ValidTo:              j.ValidTo,
+		Cancelled:            estimate != nil && estimate.Cancelled,
+		Realtime:             estimate != nil,
 	}
+
+	if estimate != nil {
+		e := callEstimate(estimate, callIndex)
+		delay := int(e.DepartureDelay / time.Second)
+		journey.ExpectedDepartureTime = expectedTime(departureTime, e.DepartureDelay)
+		journey.ExpectedArrivalTime = expectedTime(arrivalTime, e.ArrivalDelay)
+		journey.ExpectedDepartureDateTime = times.format(journey.ExpectedDepartureTime)
+		journey.ExpectedArrivalDateTime = times.format(journey.ExpectedArrivalTime)
+		journey.Delay = &delay
+		journey.Skipped = e.Skipped
+	}
+
+	return journey
 }
 
 type StopPoint struct {
@@ -127,6 +256,8 @@ type StopPoint struct {
 	Location     string                `json:"location"`
 	TariffZone   string                `json:"tariffZone"`
 	Municipality StopPointMunicipality `json:"municipality"`
+	// Distance is the distance in meters from the location of a nearby stops query.
+	Distance *float64 `json:"distance,omitempty"`
 }
 
 type StopPointMunicipality struct {
@@ -145,6 +276,8 @@ type StopPointJourney struct {
 	LineId               string                      `json:"lineId"`
 	DepartureTime        string                      `json:"departureTime"`
 	ArrivalTime          string                      `json:"arrivalTime"`
+	DepartureDateTime    string                      `json:"departureDateTime,omitempty"`
+	ArrivalDateTime      string                      `json:"arrivalDateTime,omitempty"`
 	HeadSign             string                      `json:"headSign"`
 	Direction            string                      `json:"directionId"`
 	WheelchairAccessible bool                        `json:"wheelchairAccessible"`
@@ -153,6 +286,17 @@ type StopPointJourney struct {
 	DayTypeExceptions    []StopPointDayTypeException `json:"dayTypeExceptions"`
 	ValidFrom            string                      `json:"validFrom"`
 	ValidTo              string                      `json:"validTo"`
+	// The expected times are the scheduled times shifted by the realtime delays. They and the delay are only set
+	// when the journey has a realtime estimate.
+	ExpectedDepartureTime     string `json:"expectedDepartureTime,omitempty"`
+	ExpectedArrivalTime       string `json:"expectedArrivalTime,omitempty"`
+	ExpectedDepartureDateTime string `json:"expectedDepartureDateTime,omitempty"`
+	ExpectedArrivalDateTime   string `json:"expectedArrivalDateTime,omitempty"`
+	// Delay is the realtime delay of the departure in seconds.
+	Delay     *int `json:"delay,omitempty"`
+	Skipped   bool `json:"skipped"`
+	Cancelled bool `json:"cancelled"`
+	Realtime  bool `json:"realtime"`
 }
 
 type StopPointJourneyGtfsInfo struct {
@@ -4,6 +4,8 @@ package v1
 
 import (
 	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"slices"
 	"testing"
 	"time"
 )
@@ -54,6 +56,7 @@ func TestStopPointRoutes(t *testing.T) {
 					Url:  stopPointMunicipalityUrl("211"),
 					Name: "Kangasala",
 				},
+				nil,
 			},
 		}, false, all},
 		{"/v1/stop-points/4600", []StopPoint{sp["4600"]}, false, one},
@@ -64,11 +67,43 @@ func TestStopPointRoutes(t *testing.T) {
 		{"/v1/stop-points?location=61,23:62,23.X", []StopPoint{}, false, all},
 		{"/v1/stop-points?location=61,2X:62,23.8", []StopPoint{}, false, all},
 		{"/v1/stop-points?location=61,23:6X,23.8", []StopPoint{}, false, all},
+		{"/v1/stop-points?location=61,23:62,23.8&name=Pirkkala", []StopPoint{sp["7015"]}, false, all},
+		{"/v1/stop-points?near=61.4445,23.87235", []StopPoint{withDistance(sp["3615"], 0), withDistance(sp["3607"], 341)}, false, all},
+		{"/v1/stop-points?near=61.4445,23.87235&radius=300", []StopPoint{withDistance(sp["3615"], 0)}, false, all},
+		{"/v1/stop-points?near=61.4659,23.64734&radius=1000", []StopPoint{withDistance(sp["7015"], 0), withDistance(sp["7017"], 278)}, false, all},
+		{"/v1/stop-points?near=61.4659,23.64734&radius=1000&name=Suupantori", []StopPoint{withDistance(sp["7017"], 278)}, false, all},
+		{"/v1/stop-points?near=60.1699,24.9384", []StopPoint{}, false, all},
+		{"/v1/stop-points?near=61.4659", []StopPoint{}, true, all},
+		{"/v1/stop-points?near=61.4659,23.64734&radius=10000", []StopPoint{}, true, all},
 	}
 
 	runRouterTestCases(t, testCases)
 }
 
+func TestStopPointSearchRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	search := handlerConfig{handler: HandleSearchStopPoints(dataService, ""), url: "/v1/stop-points/search"}
+
+	var sp = getStopPointMap()
+	testCases := []routerTestCase[StopPoint]{
+		{"/v1/stop-points/search?q=Nayttelijankatu", []StopPoint{sp["3615"]}, false, search},
+		{"/v1/stop-points/search?q=vallin", []StopPoint{sp["8171"]}, false, search},
+		{"/v1/stop-points/search?q=sudenkorenontie", []StopPoint{sp["8149"]}, false, search},
+		{"/v1/stop-points/search?q=4600", []StopPoint{sp["4600"]}, false, search},
+		{"/v1/stop-points/search?q=helsinki", []StopPoint{}, false, search},
+		{"/v1/stop-points/search?q=", []StopPoint{}, true, search},
+		{"/v1/stop-points/search?q=vatiala&limit=100", []StopPoint{}, true, search},
+	}
+
+	runRouterTestCases(t, testCases)
+}
+
+func withDistance(stopPoint StopPoint, distance float64) StopPoint {
+	stopPoint.Distance = &distance
+	return stopPoint
+}
+
 func getStopPointMap() map[string]StopPoint {
 	result := make(map[string]StopPoint)
 
@@ -144,10 +179,74 @@ func TestStopPointJourneyRoutes(t *testing.T) {
 	testCases := []routerTestCase[StopPointJourney]{
 		{"/v1/stop-points/3607/journeys", stopPoint3607Journeys, false, journeys},
 		{"/v1/stop-points/7015/journeys", stopPoint7015Journeys, false, journeys},
-		{"/v1/stop-points/3607/journeys/active", filterActiveJourneys(stopPoint3607Journeys), false, activeJourneys},
-		{"/v1/stop-points/7015/journeys/active", filterActiveJourneys(stopPoint7015Journeys), false, activeJourneys},
+		{"/v1/stop-points/3607/journeys/active", stopPoint3607Journeys[1:], false, activeJourneys},
+		{"/v1/stop-points/7015/journeys/active", stopPoint7015Journeys, false, activeJourneys},
 		{"/v1/stop-points/nonexistent/journeys", []StopPointJourney{}, false, journeys},
 		{"/v1/stop-points/nonexistent/journeys/active", []StopPointJourney{}, false, activeJourneys},
+		{"/v1/stop-points/3607/journeys?date=2000-01-01", stopPoint3607Journeys[:1], false, journeys},
+		{"/v1/stop-points/3607/journeys/active?date=2000-01-01", stopPoint3607Journeys[:1], false, activeJourneys},
+		{"/v1/stop-points/3607/journeys?date=2021-05-13", []StopPointJourney{}, false, journeys},
+		{"/v1/stop-points/3607/journeys?startDate=2021-05-13&endDate=2021-05-14", stopPoint3607Journeys[1:], false, journeys},
+		{"/v1/stop-points/3607/journeys?date=13.5.2021", []StopPointJourney{}, true, journeys},
+	}
+
+	runRouterTestCases(t, testCases)
+}
+
+func TestStopPointActiveJourneyRoutes(t *testing.T) {
+	// The weekday journeys do not run on Saturdays, nor on 2021-05-13 which calendar_dates.txt removes from their
+	// service. The weekend journey is valid only in 2000.
+	saturday := newJourneysTestDataService(t)
+	saturday.Clock.Now = func() time.Time { return time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC) }
+	removedDate := newJourneysTestDataService(t)
+	removedDate.Clock.Now = func() time.Time { return time.Date(2021, 5, 13, 12, 0, 0, 0, time.UTC) }
+	dayAfterRemovedDate := newJourneysTestDataService(t)
+	dayAfterRemovedDate.Clock.Now = func() time.Time { return time.Date(2021, 5, 14, 12, 0, 0, 0, time.UTC) }
+
+	activeOn := func(dataService *service.JourneysDataService) handlerConfig {
+		return handlerConfig{handler: HandleGetJourneysForStopPoint(dataService, "", "", true), url: "/v1/stop-points/{name}/journeys/active"}
+	}
+
+	testCases := []routerTestCase[StopPointJourney]{
+		{"/v1/stop-points/3607/journeys/active", []StopPointJourney{}, false, activeOn(saturday)},
+		{"/v1/stop-points/7015/journeys/active", []StopPointJourney{}, false, activeOn(saturday)},
+		{"/v1/stop-points/3607/journeys/active", []StopPointJourney{}, false, activeOn(removedDate)},
+		{"/v1/stop-points/7015/journeys/active", []StopPointJourney{}, false, activeOn(removedDate)},
+		{"/v1/stop-points/3607/journeys/active", getJourneysForStopPoint("3607")[1:], false, activeOn(dayAfterRemovedDate)},
+	}
+
+	runRouterTestCases(t, testCases)
+}
+
+func TestStopPointJourneyRealtimeRoutes(t *testing.T) {
+	dataService := newRealtimeTestDataService(t)
+
+	journeys := handlerConfig{handler: HandleGetJourneysForStopPoint(dataService, "", "", false), url: "/v1/stop-points/{name}/journeys"}
+
+	noDelay, lateDelay := 0, 900
+
+	stopPoint3607Journeys := slices.Clone(getJourneysForStopPoint("3607"))
+	stopPoint3607Journeys[1].ExpectedDepartureTime = "07:36:00"
+	stopPoint3607Journeys[1].ExpectedArrivalTime = "07:36:00"
+	stopPoint3607Journeys[1].Delay = &lateDelay
+	stopPoint3607Journeys[1].Realtime = true
+
+	stopPoint7015Journeys := slices.Clone(getJourneysForStopPoint("7015"))
+	stopPoint7015Journeys[0].ExpectedDepartureTime = "14:44:45"
+	stopPoint7015Journeys[0].ExpectedArrivalTime = "14:44:45"
+	stopPoint7015Journeys[0].Delay = &noDelay
+	stopPoint7015Journeys[0].Cancelled = true
+	stopPoint7015Journeys[0].Realtime = true
+
+	// The estimates are for the service day of testNow, so on the next day the journey runs on schedule.
+	nextDay := getJourneysForStopPoint("7015")
+	nextDay[0].DepartureDateTime = "2024-05-16T14:44:45+03:00"
+	nextDay[0].ArrivalDateTime = "2024-05-16T14:44:45+03:00"
+
+	testCases := []routerTestCase[StopPointJourney]{
+		{"/v1/stop-points/3607/journeys", stopPoint3607Journeys, false, journeys},
+		{"/v1/stop-points/7015/journeys", stopPoint7015Journeys, false, journeys},
+		{"/v1/stop-points/7015/journeys?serviceDate=2024-05-16", nextDay, false, journeys},
 	}
 
 	runRouterTestCases(t, testCases)
@@ -231,18 +330,3 @@ func getJourneysForStopPoint(stopPointId string) []StopPointJourney {
 
 	return []StopPointJourney{}
 }
-
-// Helper function to filter active journeys
-func filterActiveJourneys(journeys []StopPointJourney) []StopPointJourney {
-	now := time.Now()
-	currentDate := fmt.Sprintf("%d%02d%02d", now.Year(), now.Month(), now.Day())
-
-	var activeJourneys []StopPointJourney
-	for _, journey := range journeys {
-		if journey.ValidFrom <= currentDate && journey.ValidTo >= currentDate {
-			activeJourneys = append(activeJourneys, journey)
-		}
-	}
-
-	return activeJourneys
-}
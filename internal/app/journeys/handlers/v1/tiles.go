@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"net/http"
+	"strconv"
+)
+
+const vectorTileContentType = "application/vnd.mapbox-vector-tile"
+
+// tileCacheControl lets the map libraries and the proxies keep the tiles for an hour. The tiles only change with the
+// scheduled data.
+const tileCacheControl = "public, max-age=3600"
+
+const stopPointsTileLayer = "stop-points"
+const routesTileLayer = "routes"
+
+var errInvalidTile = errors.New(fmt.Sprintf("invalid tile, expected z between 0 and %v, and x and y between 0 and 2^z-1", service.MaxTileZoom))
+
+func HandleGetTile(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		z, x, y, err := getTileCoordinates(mux.Vars(req))
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		features := service.Tiles.Tile(z, x, y)
+
+		stopPoints := newVectorTileLayer(stopPointsTileLayer)
+		for _, sp := range features.StopPoints {
+			properties := map[string]any{
+				"shortName":  sp.StopPoint.ShortName,
+				"name":       sp.StopPoint.Name,
+				"tariffZone": sp.StopPoint.TariffZone,
+				"url":        fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, sp.StopPoint.ShortName),
+			}
+			if sp.StopPoint.Municipality != nil {
+				properties["municipalityName"] = sp.StopPoint.Municipality.Name
+			}
+
+			stopPoints.Features = append(stopPoints.Features, utils.VectorTileFeature{
+				Type:       utils.VectorTilePoint,
+				Geometry:   [][][2]int{{{sp.X, sp.Y}}},
+				Properties: properties,
+			})
+		}
+
+		routes := newVectorTileLayer(routesTileLayer)
+		for _, r := range features.Routes {
+			properties := map[string]any{
+				"routeId": r.Route.Id,
+				"name":    routeName(r.Route),
+				"url":     fmt.Sprintf("%v%v/%v", baseUrl, routePrefix, r.Route.Id),
+			}
+			if r.Route.Line != nil {
+				properties["lineName"] = r.Route.Line.Name
+				properties["lineDescription"] = r.Route.Line.Description
+			}
+
+			routes.Features = append(routes.Features, utils.VectorTileFeature{
+				Type:       utils.VectorTileLineString,
+				Geometry:   r.Parts,
+				Properties: properties,
+			})
+		}
+
+		// The stop points are drawn on top of the routes.
+		rw.Header().Set("Content-Type", vectorTileContentType)
+		rw.Header().Set("Cache-Control", tileCacheControl)
+		sendResponse(utils.EncodeVectorTile([]utils.VectorTileLayer{routes, stopPoints}), rw)
+	}
+}
+
+func newVectorTileLayer(name string) utils.VectorTileLayer {
+	return utils.VectorTileLayer{Name: name, Extent: service.TileExtent}
+}
+
+func getTileCoordinates(vars map[string]string) (int, int, int, error) {
+	z, err := strconv.Atoi(vars["z"])
+	if err != nil || z < 0 || z > service.MaxTileZoom {
+		return 0, 0, 0, errInvalidTile
+	}
+
+	x, err := strconv.Atoi(vars["x"])
+	if err != nil || x < 0 || x >= 1<<z {
+		return 0, 0, 0, errInvalidTile
+	}
+
+	y, err := strconv.Atoi(vars["y"])
+	if err != nil || y < 0 || y >= 1<<z {
+		return 0, 0, 0, errInvalidTile
+	}
+
+	return z, x, y, nil
+}
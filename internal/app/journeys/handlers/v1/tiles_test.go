@@ -0,0 +1,116 @@
+//go:build journeys_tiles_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+func TestGetTile(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc(`/v1/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt`, HandleGetTile(dataService, ""))
+
+	testCases := []struct {
+		target         string
+		expectedStatus int
+		// expected holds the features of each layer, as the number of features followed by the sorted string values of
+		// the layer.
+		expected map[string][]any
+	}{
+		{"/v1/tiles/14/9283/4620.mvt", http.StatusOK, map[string][]any{
+			"routes":      {1, "/routes/1501146007035", "1501146007035", "1A", "Vatiala - Pirkkala (lentoasema)", "Vatiala - Sudenkorennontie"},
+			"stop-points": {1, "/stop-points/4600", "4600", "B", "Kangasala", "Vatiala"},
+		}},
+		{"/v1/tiles/10/580/288.mvt", http.StatusOK, map[string][]any{
+			"routes": {1, "/routes/1501146007035", "1501146007035", "1A", "Vatiala - Pirkkala (lentoasema)", "Vatiala - Sudenkorennontie"},
+		}},
+		{"/v1/tiles/14/0/0.mvt", http.StatusOK, map[string][]any{}},
+		{"/v1/tiles/21/0/0.mvt", http.StatusBadRequest, nil},
+		{"/v1/tiles/2/4/0.mvt", http.StatusBadRequest, nil},
+		{"/v1/tiles/2/0/4.mvt", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.target, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		testutil.CompareVariablesAndPrintResults(t, tc.expectedStatus, rec.Code, tc.target)
+		if tc.expectedStatus != http.StatusOK {
+			continue
+		}
+
+		testutil.CompareVariablesAndPrintResults(t, vectorTileContentType, rec.Header().Get("Content-Type"), tc.target)
+		testutil.CompareVariablesAndPrintResults(t, tileCacheControl, rec.Header().Get("Cache-Control"), tc.target)
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, decodeTestVectorTile(t, rec.Body.Bytes()), tc.target)
+	}
+}
+
+// decodeTestVectorTile reads the layers of a vector tile, and returns the number of features and the string values
+// of each.
+func decodeTestVectorTile(t *testing.T, tile []byte) map[string][]any {
+	result := make(map[string][]any)
+
+	for _, layer := range decodeTestProtobuf(t, tile)[3] {
+		fields := decodeTestProtobuf(t, layer)
+
+		var values []string
+		for _, v := range fields[4] {
+			if s, ok := decodeTestProtobuf(t, v)[1]; ok {
+				values = append(values, string(s[0]))
+			}
+		}
+		sort.Strings(values)
+
+		layerResult := []any{len(fields[2])}
+		for _, v := range values {
+			layerResult = append(layerResult, v)
+		}
+		result[string(fields[1][0])] = layerResult
+	}
+
+	return result
+}
+
+// decodeTestProtobuf returns the length delimited fields of a protobuf message by field number, skipping the others.
+func decodeTestProtobuf(t *testing.T, message []byte) map[int][][]byte {
+	result := make(map[int][][]byte)
+
+	varint := func() uint64 {
+		var v uint64
+		for shift := 0; len(message) > 0; shift += 7 {
+			b := message[0]
+			message = message[1:]
+			v |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		return v
+	}
+
+	for len(message) > 0 {
+		key := varint()
+		switch key & 0x7 {
+		case 0:
+			varint()
+		case 1:
+			message = message[8:]
+		case 2:
+			l := varint()
+			result[int(key>>3)] = append(result[int(key>>3)], message[:l])
+			message = message[l:]
+		default:
+			t.Fatalf("unexpected wire type %v", key&0x7)
+		}
+	}
+
+	return result
+}
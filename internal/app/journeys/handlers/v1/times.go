@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"net/http"
+	"time"
+)
+
+// serviceDayTimes formats the GTFS times of a service day as ISO-8601 timestamps in the agency timezone. A nil
+// serviceDayTimes formats every time as an empty string, which leaves the timestamps out of the responses.
+type serviceDayTimes struct {
+	clock      *service.Clock
+	serviceDay time.Time
+}
+
+// getServiceDayTimes returns the formatter for the serviceDate query parameter, or nil if the parameter is not set.
+func getServiceDayTimes(r *http.Request, clock *service.Clock) (*serviceDayTimes, error) {
+	value := r.URL.Query().Get("serviceDate")
+	if value == "" {
+		return nil, nil
+	}
+
+	serviceDay, err := clock.ParseDate(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serviceDayTimes{clock: clock, serviceDay: serviceDay}, nil
+}
+
+func (t *serviceDayTimes) format(gtfsTime string) string {
+	if t == nil {
+		return ""
+	}
+
+	offset, err := utils.ParseGtfsTime(gtfsTime)
+	if err != nil {
+		return ""
+	}
+
+	return t.clock.At(t.serviceDay, offset).Format(time.RFC3339)
+}
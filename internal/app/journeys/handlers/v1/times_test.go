@@ -0,0 +1,48 @@
+//go:build journeys_journeys_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServiceDayTimes(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &service.Clock{Location: helsinki}
+
+	testCases := []struct {
+		target   string
+		gtfsTime string
+		expected string
+	}{
+		{"/v1/journeys", "06:30:00", ""},
+		{"/v1/journeys?serviceDate=2024-05-15", "06:30:00", "2024-05-15T06:30:00+03:00"},
+		{"/v1/journeys?serviceDate=2024-05-15", "25:10:00", "2024-05-16T01:10:00+03:00"},
+		// Daylight saving time starts on 2024-03-31, so the service day starts at 23:00 on the previous date.
+		{"/v1/journeys?serviceDate=2024-03-31", "00:30:00", "2024-03-30T23:30:00+02:00"},
+		{"/v1/journeys?serviceDate=2024-03-31", "06:30:00", "2024-03-31T06:30:00+03:00"},
+		{"/v1/journeys?serviceDate=2024-03-31", "25:10:00", "2024-04-01T01:10:00+03:00"},
+		// Daylight saving time ends on 2024-10-27, so the service day starts at 01:00 of the date.
+		{"/v1/journeys?serviceDate=2024-10-27", "00:30:00", "2024-10-27T01:30:00+03:00"},
+	}
+
+	for _, tc := range testCases {
+		times, err := getServiceDayTimes(httptest.NewRequest("GET", tc.target, nil), clock)
+		if err != nil {
+			t.Errorf("%v: %v", tc.target, err)
+			continue
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, times.format(tc.gtfsTime), tc.target+" "+tc.gtfsTime)
+	}
+
+	if _, err := getServiceDayTimes(httptest.NewRequest("GET", "/v1/journeys?serviceDate=31.3.2024", nil), clock); err == nil {
+		t.Errorf("expected an error for an invalid serviceDate")
+	}
+}
@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"net/http"
+	"time"
+)
+
+func HandleGetLineTimetables(service *service.JourneysDataService, baseUrl string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		date, err := getTimetableDate(req, service.Clock)
+		if err != nil {
+			sendFailResponse(err.Error(), http.StatusBadRequest, rw)
+			return
+		}
+
+		ml, err := service.Lines.GetOneById(mux.Vars(req)["name"])
+		if err != nil {
+			sendSuccessResponse([]LineTimetable{}, getExcludeFieldsQueryParameter(req), rw)
+			return
+		}
+
+		var timetables []LineTimetable
+		for _, mt := range service.Journeys.Timetables(ml.Name, date, req.URL.Query().Get("direction")) {
+			timetables = append(timetables, convertLineTimetable(mt, baseUrl))
+		}
+
+		sendSuccessResponse(timetables, getExcludeFieldsQueryParameter(req), rw)
+	}
+}
+
+// getTimetableDate reads the service day of a timetable query, which defaults to the current service day.
+func getTimetableDate(req *http.Request, clock *service.Clock) (time.Time, error) {
+	v := req.URL.Query().Get("date")
+	if v == "" {
+		return clock.Date(clock.Current()), nil
+	}
+
+	date, err := clock.ParseDate(v)
+	if err != nil {
+		return time.Time{}, service.ErrInvalidDate
+	}
+
+	return date, nil
+}
+
+func convertLineTimetable(t *model.Timetable, baseUrl string) LineTimetable {
+	journeys := make([]LineTimetableJourney, 0, len(t.Journeys))
+	for _, j := range t.Journeys {
+		journeys = append(journeys, LineTimetableJourney{
+			JourneyUrl:    fmt.Sprintf("%v%v/%v", baseUrl, journeysPrefix, j.Id),
+			HeadSign:      j.HeadSign,
+			DepartureTime: j.DepartureTime,
+			ArrivalTime:   j.ArrivalTime,
+		})
+	}
+
+	stopPoints := make([]LineTimetableStopPoint, 0, len(t.StopPoints))
+	for i, sp := range t.StopPoints {
+		times := make([]string, 0, len(t.Journeys))
+		for column, c := range t.Calls[i] {
+			switch {
+			case c == nil:
+				times = append(times, "")
+			case c == t.Journeys[column].Calls[len(t.Journeys[column].Calls)-1]:
+				// The journey ends at the stop point.
+				times = append(times, c.ArrivalTime)
+			default:
+				times = append(times, c.DepartureTime)
+			}
+		}
+
+		stopPoints = append(stopPoints, LineTimetableStopPoint{
+			Url:       fmt.Sprintf("%v%v/%v", baseUrl, stopPointPrefix, sp.ShortName),
+			ShortName: sp.ShortName,
+			Name:      sp.Name,
+			Times:     times,
+		})
+	}
+
+	return LineTimetable{
+		LineUrl:     fmt.Sprintf("%v%v/%v", baseUrl, linePrefix, t.Line.Name),
+		Direction:   t.Direction,
+		ServiceDate: t.ServiceDate.Format("2006-01-02"),
+		Journeys:    journeys,
+		StopPoints:  stopPoints,
+	}
+}
+
+type LineTimetable struct {
+	LineUrl     string                   `json:"lineUrl"`
+	Direction   string                   `json:"directionId"`
+	ServiceDate string                   `json:"serviceDate"`
+	Journeys    []LineTimetableJourney   `json:"journeys"`
+	StopPoints  []LineTimetableStopPoint `json:"stopPoints"`
+}
+
+type LineTimetableJourney struct {
+	JourneyUrl    string `json:"journeyUrl"`
+	HeadSign      string `json:"headSign"`
+	DepartureTime string `json:"departureTime"`
+	ArrivalTime   string `json:"arrivalTime"`
+}
+
+// LineTimetableStopPoint is a row of the timetable. Times holds the time of each journey at the stop point, in the
+// order of the journeys of the timetable, or an empty string if the journey does not call at the stop point.
+type LineTimetableStopPoint struct {
+	Url       string   `json:"url"`
+	ShortName string   `json:"shortName"`
+	Name      string   `json:"name"`
+	Times     []string `json:"times"`
+}
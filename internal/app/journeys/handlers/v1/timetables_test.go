@@ -0,0 +1,53 @@
+//go:build journeys_lines_tests || journeys_tests || all_tests
+
+package v1
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jlundan/journeys-api/internal/app/journeys/service"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLineTimetablesRoutes(t *testing.T) {
+	dataService := newJourneysTestDataService(t)
+
+	timetables := handlerConfig{handler: HandleGetLineTimetables(dataService, ""), url: "/v1/lines/{name}/timetable"}
+
+	line1A := LineTimetable{
+		LineUrl:     "/lines/1A",
+		Direction:   "0",
+		ServiceDate: "2024-05-15",
+		Journeys: []LineTimetableJourney{
+			{JourneyUrl: "/journeys/7020295685", HeadSign: "Lentoasema", DepartureTime: "06:30:00", ArrivalTime: "06:32:30"},
+		},
+		StopPoints: []LineTimetableStopPoint{
+			{Url: "/stop-points/4600", ShortName: "4600", Name: "Vatiala", Times: []string{"06:30:00"}},
+			{Url: "/stop-points/8171", ShortName: "8171", Name: "Vällintie", Times: []string{"06:31:30"}},
+			{Url: "/stop-points/8149", ShortName: "8149", Name: "Sudenkorennontie", Times: []string{"06:32:30"}},
+		},
+	}
+
+	testCases := []routerTestCase[LineTimetable]{
+		{"/v1/lines/1A/timetable?date=2024-05-15", []LineTimetable{line1A}, false, timetables},
+		{"/v1/lines/1A/timetable?date=2024-05-15&direction=0", []LineTimetable{line1A}, false, timetables},
+		// The current time of the test data service is on 2024-05-15.
+		{"/v1/lines/1A/timetable", []LineTimetable{line1A}, false, timetables},
+		{"/v1/lines/1A/timetable?date=2024-05-15&direction=1", []LineTimetable{}, false, timetables},
+		{"/v1/lines/1A/timetable?date=2024-05-18", []LineTimetable{}, false, timetables},
+		{"/v1/lines/nonexistent/timetable", []LineTimetable{}, false, timetables},
+		{"/v1/lines/1A/timetable?date=18.5.2024", []LineTimetable{}, true, timetables},
+	}
+
+	runRouterTestCases(t, testCases)
+
+	// An invalid date is reported like in the other handlers.
+	router := mux.NewRouter()
+	router.HandleFunc(timetables.url, timetables.handler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/lines/1A/timetable?date=18.5.2024", nil))
+	if !strings.Contains(rec.Body.String(), service.ErrInvalidDate.Error()) {
+		t.Errorf("expected the invalid date message, got %v", rec.Body.String())
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Context interface {
 	Lines() Lines
 	JourneyPatterns() JourneyPatterns
@@ -55,6 +57,19 @@ type JourneyPattern struct {
 	StopPoints []*StopPoint
 	Route      *Route
 	Journeys   []*Journey
+	// Segments are the parts of the route between consecutive stop points, one less than there are stop points.
+	Segments []*JourneyPatternSegment
+	// Length is the distance from the first stop point to the last one along the route in meters.
+	Length float64
+}
+
+// JourneyPatternSegment is the part of the route of a journey pattern from a stop point to the next one.
+type JourneyPatternSegment struct {
+	From  *StopPoint
+	To    *StopPoint
+	Shape []ShapePoint
+	// Distance is the length of the segment along the shape in meters.
+	Distance float64
 }
 
 type StopPoint struct {
@@ -63,9 +78,22 @@ type StopPoint struct {
 	Latitude     float64
 	Longitude    float64
 	TariffZone   string
+	PlatformCode string
 	Municipality *Municipality
 }
 
+// NearbyStopPoint is a stop point together with its distance from a location in meters.
+type NearbyStopPoint struct {
+	StopPoint *StopPoint
+	Distance  float64
+}
+
+// StopPointMatch is a stop point matching a search, together with the relevance of the match.
+type StopPointMatch struct {
+	StopPoint *StopPoint
+	Score     float64
+}
+
 type Municipality struct {
 	PublicCode string
 	Name       string
@@ -88,10 +116,13 @@ type Journey struct {
 	ArrivalTime          string
 	DepartureTime        string
 	ActivityId           string
+	// Length is the distance from the first call to the last one along the route in meters.
+	Length float64
 }
 
 type JourneyGtfsInfo struct {
-	TripId string
+	TripId    string
+	ServiceId string
 }
 
 type DayTypeException struct {
@@ -104,6 +135,116 @@ type JourneyCall struct {
 	DepartureTime string
 	ArrivalTime   string
 	StopPoint     *StopPoint
+	// DistanceTraveled is the distance from the first call of the journey along the route in meters.
+	DistanceTraveled float64
+	// StopSequence is the stop_sequence of the GTFS stop time of the call.
+	StopSequence int
+}
+
+// Departure is a call of a journey at a stop point on a specific service day.
+type Departure struct {
+	Journey *Journey
+	// Call is the call at the stop point, so its StopPoint is always set.
+	Call *JourneyCall
+	// ServiceDate is the date of the service day, as noon of the date in the agency timezone.
+	ServiceDate time.Time
+	// CallIndex is the index of Call in the calls of the journey.
+	CallIndex int
+	// Time is the absolute departure time.
+	Time time.Time
+	// Estimate is the realtime estimate of the journey on the service day, or nil if there is none.
+	Estimate *JourneyEstimate
+	// ExpectedTime is Time shifted by the realtime delay of the departure.
+	ExpectedTime time.Time
+}
+
+// JourneyEstimate is the realtime estimate of a journey on a specific service day.
+type JourneyEstimate struct {
+	// ServiceDate is the date of the service day, as noon of the date in the agency timezone.
+	ServiceDate time.Time
+	Cancelled   bool
+	// Calls are the estimates of the calls of the journey, one for each call.
+	Calls []CallEstimate
+}
+
+// CallEstimate is the realtime estimate of a call, as the delays from the scheduled times. A call with no estimate
+// before it in the journey has no delay.
+type CallEstimate struct {
+	ArrivalDelay   time.Duration
+	DepartureDelay time.Duration
+	Skipped        bool
+}
+
+// Timetable is the stop-by-journey matrix of the journeys of a line in one direction on a service day.
+type Timetable struct {
+	Line      *Line
+	Direction string
+	// ServiceDate is the date of the service day, as noon of the date in the agency timezone.
+	ServiceDate time.Time
+	// StopPoints are the rows of the matrix, in the order the journeys call at them.
+	StopPoints []*StopPoint
+	// Journeys are the columns of the matrix, sorted by departure time.
+	Journeys []*Journey
+	// Calls holds the call of each journey at each stop point, Calls[row][column]. It is nil if the journey does
+	// not call at the stop point.
+	Calls [][]*JourneyCall
+}
+
+// Place is an origin or a destination of a trip plan: a stop point, or a location which is not a stop point. The
+// coordinates of a stop point place are the coordinates of the stop point.
+type Place struct {
+	StopPoint *StopPoint
+	Latitude  float64
+	Longitude float64
+}
+
+type ItineraryLegMode string
+
+const (
+	ItineraryLegWalk    ItineraryLegMode = "walk"
+	ItineraryLegTransit ItineraryLegMode = "transit"
+)
+
+// Itinerary is a planned trip from one place to another, made of consecutive walking and transit legs.
+type Itinerary struct {
+	Legs []*ItineraryLeg
+	// Transfers is the number of changes between journeys, one less than the number of transit legs.
+	Transfers int
+}
+
+// ItineraryLeg is a part of an itinerary. A transit leg rides a journey from the first of its calls to the last.
+type ItineraryLeg struct {
+	Mode      ItineraryLegMode
+	From      Place
+	To        Place
+	Departure time.Time
+	Arrival   time.Time
+	// Distance is the walking distance in meters. It is zero for transit legs.
+	Distance float64
+	Journey  *Journey
+	// ServiceDate is the date of the service day of the journey, as noon of the date in the agency timezone.
+	ServiceDate time.Time
+	Calls       []*JourneyCall
+}
+
+// ReachableStopPoint is a stop point reachable from a place, with the earliest arrival at it.
+type ReachableStopPoint struct {
+	StopPoint *StopPoint
+	Arrival   time.Time
+	// Transfers is the number of changes between journeys on the fastest way to the stop point.
+	Transfers int
+}
+
+// Transfer is a rule of transfers.txt for changing journeys from a stop point to another one, or at the same stop
+// point when From and To are the same.
+type Transfer struct {
+	From *StopPoint
+	To   *StopPoint
+	// Forbidden tells that journeys cannot be changed between the stop points.
+	Forbidden bool
+	// MinTransferTime is the time needed for the transfer, used instead of the default minimum transfer time.
+	// It is nil when the rule does not give one.
+	MinTransferTime *time.Duration
 }
 
 type Route struct {
@@ -113,4 +254,11 @@ type Route struct {
 	JourneyPatterns []*JourneyPattern
 	Journeys        []*Journey
 	GeoProjection   string
+	// Shape holds the points of the GTFS shape of the route, in order.
+	Shape []ShapePoint
+}
+
+type ShapePoint struct {
+	Latitude  float64
+	Longitude float64
 }
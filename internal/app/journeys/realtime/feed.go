@@ -0,0 +1,365 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Incrementality tells whether a feed message holds the whole realtime dataset, or only changes to the previous one.
+type Incrementality int
+
+const (
+	FullDataset  Incrementality = 0
+	Differential Incrementality = 1
+)
+
+// TripScheduleRelationship is the relation of a realtime trip to the static schedule.
+type TripScheduleRelationship int
+
+const (
+	TripScheduled   TripScheduleRelationship = 0
+	TripAdded       TripScheduleRelationship = 1
+	TripUnscheduled TripScheduleRelationship = 2
+	TripCanceled    TripScheduleRelationship = 3
+	TripReplacement TripScheduleRelationship = 5
+	TripDuplicated  TripScheduleRelationship = 6
+	TripDeleted     TripScheduleRelationship = 7
+)
+
+// StopScheduleRelationship is the relation of a realtime stop time to the static schedule.
+type StopScheduleRelationship int
+
+const (
+	StopScheduled   StopScheduleRelationship = 0
+	StopSkipped     StopScheduleRelationship = 1
+	StopNoData      StopScheduleRelationship = 2
+	StopUnscheduled StopScheduleRelationship = 3
+)
+
+// VehicleStopStatus tells where the vehicle is in relation to its current stop.
+type VehicleStopStatus int
+
+const (
+	IncomingAt  VehicleStopStatus = 0
+	StoppedAt   VehicleStopStatus = 1
+	InTransitTo VehicleStopStatus = 2
+)
+
+// FeedMessage is a GTFS-Realtime feed message, https://gtfs.org/realtime/reference/. Only the trip updates and the
+// vehicle positions are decoded, the alerts and the extensions are skipped.
+type FeedMessage struct {
+	Header   FeedHeader
+	Entities []*FeedEntity
+}
+
+type FeedHeader struct {
+	Version        string
+	Incrementality Incrementality
+	// Timestamp is the creation time of the message in POSIX time.
+	Timestamp uint64
+}
+
+type FeedEntity struct {
+	Id         string
+	IsDeleted  bool
+	TripUpdate *TripUpdate
+	Vehicle    *VehiclePosition
+}
+
+type TripDescriptor struct {
+	TripId               string
+	RouteId              string
+	DirectionId          *uint32
+	StartTime            string
+	StartDate            string
+	ScheduleRelationship TripScheduleRelationship
+}
+
+type VehicleDescriptor struct {
+	Id           string
+	Label        string
+	LicensePlate string
+}
+
+type TripUpdate struct {
+	Trip            TripDescriptor
+	Vehicle         *VehicleDescriptor
+	StopTimeUpdates []*StopTimeUpdate
+	Timestamp       uint64
+	// Delay is the delay of the trip in seconds, when the stop time updates do not tell it.
+	Delay *int32
+}
+
+type StopTimeUpdate struct {
+	StopSequence         *uint32
+	StopId               string
+	Arrival              *StopTimeEvent
+	Departure            *StopTimeEvent
+	ScheduleRelationship StopScheduleRelationship
+}
+
+// StopTimeEvent is the realtime arrival or departure at a stop, given as a delay in seconds from the schedule, as an
+// absolute POSIX time, or both.
+type StopTimeEvent struct {
+	Delay       *int32
+	Time        *int64
+	Uncertainty *int32
+}
+
+type VehiclePosition struct {
+	Trip                *TripDescriptor
+	Vehicle             *VehicleDescriptor
+	Position            *Position
+	CurrentStopSequence *uint32
+	StopId              string
+	CurrentStatus       VehicleStopStatus
+	Timestamp           uint64
+}
+
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	Bearing   *float64
+	Speed     *float64
+}
+
+var errMissingHeader = errors.New("invalid feed message: header is missing")
+
+// DecodeFeedMessage decodes a GTFS-Realtime FeedMessage protobuf.
+func DecodeFeedMessage(data []byte) (*FeedMessage, error) {
+	var message FeedMessage
+	hasHeader := false
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			hasHeader = true
+			return decodeFeedHeader(f.bytes, &message.Header)
+		case 2:
+			entity, err := decodeFeedEntity(f.bytes)
+			if err != nil {
+				return err
+			}
+			message.Entities = append(message.Entities, entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed message: %w", err)
+	}
+
+	if !hasHeader {
+		return nil, errMissingHeader
+	}
+
+	return &message, nil
+}
+
+func decodeFeedHeader(data []byte, header *FeedHeader) error {
+	return readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			header.Version = string(f.bytes)
+		case 2:
+			header.Incrementality = Incrementality(f.varint)
+		case 3:
+			header.Timestamp = f.varint
+		}
+		return nil
+	})
+}
+
+func decodeFeedEntity(data []byte) (*FeedEntity, error) {
+	var entity FeedEntity
+
+	err := readProtoFields(data, func(f protoField) error {
+		var err error
+		switch f.number {
+		case 1:
+			entity.Id = string(f.bytes)
+		case 2:
+			entity.IsDeleted = f.varint != 0
+		case 3:
+			entity.TripUpdate, err = decodeTripUpdate(f.bytes)
+		case 4:
+			entity.Vehicle, err = decodeVehiclePosition(f.bytes)
+		}
+		return err
+	})
+
+	return &entity, err
+}
+
+func decodeTripDescriptor(data []byte) (*TripDescriptor, error) {
+	var trip TripDescriptor
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			trip.TripId = string(f.bytes)
+		case 2:
+			trip.StartTime = string(f.bytes)
+		case 3:
+			trip.StartDate = string(f.bytes)
+		case 4:
+			trip.ScheduleRelationship = TripScheduleRelationship(f.varint)
+		case 5:
+			trip.RouteId = string(f.bytes)
+		case 6:
+			v := uint32(f.varint)
+			trip.DirectionId = &v
+		}
+		return nil
+	})
+
+	return &trip, err
+}
+
+func decodeVehicleDescriptor(data []byte) (*VehicleDescriptor, error) {
+	var vehicle VehicleDescriptor
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			vehicle.Id = string(f.bytes)
+		case 2:
+			vehicle.Label = string(f.bytes)
+		case 3:
+			vehicle.LicensePlate = string(f.bytes)
+		}
+		return nil
+	})
+
+	return &vehicle, err
+}
+
+func decodeTripUpdate(data []byte) (*TripUpdate, error) {
+	var update TripUpdate
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			trip, err := decodeTripDescriptor(f.bytes)
+			if err != nil {
+				return err
+			}
+			update.Trip = *trip
+		case 2:
+			stu, err := decodeStopTimeUpdate(f.bytes)
+			if err != nil {
+				return err
+			}
+			update.StopTimeUpdates = append(update.StopTimeUpdates, stu)
+		case 3:
+			vehicle, err := decodeVehicleDescriptor(f.bytes)
+			if err != nil {
+				return err
+			}
+			update.Vehicle = vehicle
+		case 4:
+			update.Timestamp = f.varint
+		case 5:
+			v := int32(f.varint)
+			update.Delay = &v
+		}
+		return nil
+	})
+
+	return &update, err
+}
+
+func decodeStopTimeUpdate(data []byte) (*StopTimeUpdate, error) {
+	var update StopTimeUpdate
+
+	err := readProtoFields(data, func(f protoField) error {
+		var err error
+		switch f.number {
+		case 1:
+			v := uint32(f.varint)
+			update.StopSequence = &v
+		case 2:
+			update.Arrival, err = decodeStopTimeEvent(f.bytes)
+		case 3:
+			update.Departure, err = decodeStopTimeEvent(f.bytes)
+		case 4:
+			update.StopId = string(f.bytes)
+		case 5:
+			update.ScheduleRelationship = StopScheduleRelationship(f.varint)
+		}
+		return err
+	})
+
+	return &update, err
+}
+
+func decodeStopTimeEvent(data []byte) (*StopTimeEvent, error) {
+	var event StopTimeEvent
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			v := int32(f.varint)
+			event.Delay = &v
+		case 2:
+			v := int64(f.varint)
+			event.Time = &v
+		case 3:
+			v := int32(f.varint)
+			event.Uncertainty = &v
+		}
+		return nil
+	})
+
+	return &event, err
+}
+
+func decodeVehiclePosition(data []byte) (*VehiclePosition, error) {
+	var vehicle VehiclePosition
+
+	err := readProtoFields(data, func(f protoField) error {
+		var err error
+		switch f.number {
+		case 1:
+			vehicle.Trip, err = decodeTripDescriptor(f.bytes)
+		case 2:
+			vehicle.Position, err = decodePosition(f.bytes)
+		case 3:
+			v := uint32(f.varint)
+			vehicle.CurrentStopSequence = &v
+		case 4:
+			vehicle.CurrentStatus = VehicleStopStatus(f.varint)
+		case 5:
+			vehicle.Timestamp = f.varint
+		case 7:
+			vehicle.StopId = string(f.bytes)
+		case 8:
+			vehicle.Vehicle, err = decodeVehicleDescriptor(f.bytes)
+		}
+		return err
+	})
+
+	return &vehicle, err
+}
+
+func decodePosition(data []byte) (*Position, error) {
+	var position Position
+
+	err := readProtoFields(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			position.Latitude = float64(math.Float32frombits(f.fixed32))
+		case 2:
+			position.Longitude = float64(math.Float32frombits(f.fixed32))
+		case 3:
+			v := float64(math.Float32frombits(f.fixed32))
+			position.Bearing = &v
+		case 5:
+			v := float64(math.Float32frombits(f.fixed32))
+			position.Speed = &v
+		}
+		return nil
+	})
+
+	return &position, err
+}
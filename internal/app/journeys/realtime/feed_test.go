@@ -0,0 +1,163 @@
+package realtime
+
+import (
+	"encoding/binary"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"math"
+	"testing"
+)
+
+// testMessage builds protobuf messages for the tests.
+type testMessage []byte
+
+func (m testMessage) varint(field int, v uint64) testMessage {
+	m = appendTestVarint(m, uint64(field<<3|wireVarint))
+	return appendTestVarint(m, v)
+}
+
+func (m testMessage) bytes(field int, v []byte) testMessage {
+	m = appendTestVarint(m, uint64(field<<3|wireLengthDelimited))
+	m = appendTestVarint(m, uint64(len(v)))
+	return append(m, v...)
+}
+
+func (m testMessage) string(field int, v string) testMessage {
+	return m.bytes(field, []byte(v))
+}
+
+func (m testMessage) message(field int, v testMessage) testMessage {
+	return m.bytes(field, v)
+}
+
+func (m testMessage) float(field int, v float32) testMessage {
+	m = appendTestVarint(m, uint64(field<<3|wireFixed32))
+	return binary.LittleEndian.AppendUint32(m, math.Float32bits(v))
+}
+
+func (m testMessage) double(field int, v float64) testMessage {
+	m = appendTestVarint(m, uint64(field<<3|wireFixed64))
+	return binary.LittleEndian.AppendUint64(m, math.Float64bits(v))
+}
+
+func appendTestVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func testHeader(incrementality Incrementality, timestamp uint64) testMessage {
+	return testMessage{}.string(1, "2.0").varint(2, uint64(incrementality)).varint(3, timestamp)
+}
+
+func testTripUpdate(trip testMessage, stopTimeUpdates ...testMessage) testMessage {
+	tu := testMessage{}.message(1, trip)
+	for _, stu := range stopTimeUpdates {
+		tu = tu.message(2, stu)
+	}
+	return tu
+}
+
+func testEntity(id string, field int, v testMessage) testMessage {
+	return testMessage{}.string(1, id).message(field, v)
+}
+
+func TestDecodeFeedMessage(t *testing.T) {
+	// The negative int32 values are encoded as ten byte varints.
+	arrivalDelay := int64(-30)
+
+	data := testMessage{}.
+		message(1, testHeader(FullDataset, 1715770000)).
+		message(2, testEntity("1", 3, testTripUpdate(
+			testMessage{}.string(1, "T1").string(5, "1").varint(6, 1).varint(4, uint64(TripScheduled)),
+			testMessage{}.varint(1, 2).string(4, "B").
+				message(2, testMessage{}.varint(1, uint64(arrivalDelay))).
+				message(3, testMessage{}.varint(2, 1715770120).varint(3, 10)),
+			testMessage{}.varint(1, 3).varint(5, uint64(StopSkipped)),
+		).message(3, testMessage{}.string(1, "V1").string(2, "12")).varint(5, 60))).
+		message(2, testMessage{}.string(1, "2").varint(2, 1)).
+		message(2, testEntity("3", 4, testMessage{}.
+			message(1, testMessage{}.string(1, "T1")).
+			message(2, testMessage{}.float(1, 61.5).float(2, 23.75).float(3, 90).double(4, 1234).float(5, 10)).
+			varint(3, 2).varint(4, uint64(StoppedAt)).varint(5, 1715770010).string(7, "B").
+			message(8, testMessage{}.string(1, "V1").string(3, "ABC-123")))).
+		// An unknown field, which is skipped.
+		string(1000, "extension")
+
+	message, err := DecodeFeedMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seq2, seq3 := uint32(2), uint32(3)
+	direction := uint32(1)
+	delay32, uncertainty := int32(-30), int32(10)
+	tripDelay := int32(60)
+	arrivalTime := int64(1715770120)
+	bearing, speed := 90.0, 10.0
+
+	expected := &FeedMessage{
+		Header: FeedHeader{Version: "2.0", Incrementality: FullDataset, Timestamp: 1715770000},
+		Entities: []*FeedEntity{
+			{
+				Id: "1",
+				TripUpdate: &TripUpdate{
+					Trip:    TripDescriptor{TripId: "T1", RouteId: "1", DirectionId: &direction},
+					Vehicle: &VehicleDescriptor{Id: "V1", Label: "12"},
+					StopTimeUpdates: []*StopTimeUpdate{
+						{
+							StopSequence: &seq2,
+							StopId:       "B",
+							Arrival:      &StopTimeEvent{Delay: &delay32},
+							Departure:    &StopTimeEvent{Time: &arrivalTime, Uncertainty: &uncertainty},
+						},
+						{StopSequence: &seq3, ScheduleRelationship: StopSkipped},
+					},
+					Delay: &tripDelay,
+				},
+			},
+			{Id: "2", IsDeleted: true},
+			{
+				Id: "3",
+				Vehicle: &VehiclePosition{
+					Trip:                &TripDescriptor{TripId: "T1"},
+					Vehicle:             &VehicleDescriptor{Id: "V1", LicensePlate: "ABC-123"},
+					Position:            &Position{Latitude: 61.5, Longitude: 23.75, Bearing: &bearing, Speed: &speed},
+					CurrentStopSequence: &seq2,
+					StopId:              "B",
+					CurrentStatus:       StoppedAt,
+					Timestamp:           1715770010,
+				},
+			},
+		},
+	}
+
+	testutil.CompareVariablesAndPrintResults(t, expected, message, "feed message")
+}
+
+func TestDecodeFeedMessageErrors(t *testing.T) {
+	valid := testMessage{}.message(1, testHeader(FullDataset, 1)).message(2, testMessage{}.string(1, "1"))
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"no header", testMessage{}.message(2, testMessage{}.string(1, "1"))},
+		{"truncated", valid[:len(valid)-1]},
+		{"truncated varint", []byte{0x08, 0x80}},
+		{"field number 0", []byte{0x00, 0x01}},
+		{"group", []byte{0x0b}},
+		{"invalid entity", testMessage{}.message(1, testHeader(FullDataset, 1)).bytes(2, []byte{0x1a, 0x05, 0x01})},
+	}
+
+	for _, tc := range testCases {
+		if _, err := DecodeFeedMessage(tc.data); err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		}
+	}
+
+	if _, err := DecodeFeedMessage(valid); err != nil {
+		t.Errorf("expected the valid message to decode, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultFetchTimeout = 30 * time.Second
+
+// maxFeedSize limits the size of a feed message read from a source.
+const maxFeedSize = 64 << 20
+
+var errNoSource = errors.New("realtime source has neither a URL nor a path")
+
+// Source is where the GTFS-Realtime feed messages are read from, either a URL or a local file. The file is read
+// again on every fetch, so that another process can keep it up to date.
+type Source struct {
+	Url  string
+	Path string
+	// Client is used to fetch the URL, defaults to a client with a 30 second timeout.
+	Client *http.Client
+}
+
+// Fetch reads and decodes a feed message from the source.
+func (s Source) Fetch() (*FeedMessage, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case s.Url != "":
+		data, err = s.fetchUrl()
+	case s.Path != "":
+		data, err = os.ReadFile(s.Path)
+	default:
+		return nil, errNoSource
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeFeedMessage(data)
+}
+
+func (s Source) String() string {
+	if s.Url != "" {
+		return s.Url
+	}
+	return s.Path
+}
+
+func (s Source) fetchUrl() ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultFetchTimeout}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/x-protobuf")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %v: unexpected status %v", s.Url, res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFeedSize {
+		return nil, fmt.Errorf("fetching %v: feed message is larger than %v bytes", s.Url, maxFeedSize)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,298 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JourneyUpdate is a trip update matched to a journey of the static data.
+type JourneyUpdate struct {
+	Journey    *model.Journey
+	Line       *model.Line
+	TripUpdate *TripUpdate
+	// Calls are the stop time updates matched to the calls of the journey, in the order of the calls. The stop time
+	// updates which do not match a call are left out.
+	Calls []*CallUpdate
+}
+
+// CallUpdate is a stop time update matched to a call of a journey.
+type CallUpdate struct {
+	Index          int
+	Call           *model.JourneyCall
+	StopTimeUpdate *StopTimeUpdate
+}
+
+// VehicleActivity is a vehicle position matched to the static data. The journey, the line and the stop point are
+// nil when the position does not tell them, or they are not found.
+type VehicleActivity struct {
+	Vehicle   *VehiclePosition
+	Journey   *model.Journey
+	Line      *model.Line
+	StopPoint *model.StopPoint
+}
+
+// State is the realtime state built from the GTFS-Realtime feed messages and matched to the static data of the
+// repository. It is safe for concurrent use.
+type State struct {
+	Repository *repository.JourneysRepository
+
+	mu        sync.RWMutex
+	entities  map[string]*FeedEntity
+	timestamp time.Time
+	journeys  map[string]*JourneyUpdate
+	vehicles  []*VehicleActivity
+
+	once sync.Once
+	// journeysByStart are the journeys by their line and the departure time of their first call, for matching the
+	// trips which are given without a trip_id.
+	journeysByStart map[*model.Line]map[time.Duration][]*model.Journey
+}
+
+func NewState(repository *repository.JourneysRepository) *State {
+	return &State{Repository: repository, entities: make(map[string]*FeedEntity)}
+}
+
+// Apply updates the state with a feed message. A full dataset replaces the previous state, a differential message
+// replaces and deletes the entities it names.
+func (s *State) Apply(message *FeedMessage) {
+	s.once.Do(s.initialize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message.Header.Incrementality == FullDataset {
+		s.entities = make(map[string]*FeedEntity)
+	}
+
+	for _, entity := range message.Entities {
+		if entity.IsDeleted {
+			delete(s.entities, entity.Id)
+			continue
+		}
+		s.entities[entity.Id] = entity
+	}
+
+	if message.Header.Timestamp != 0 {
+		s.timestamp = time.Unix(int64(message.Header.Timestamp), 0)
+	}
+	s.match()
+}
+
+// JourneyUpdate returns the trip update of the journey.
+func (s *State) JourneyUpdate(journeyId string) (*JourneyUpdate, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	update, ok := s.journeys[journeyId]
+	return update, ok
+}
+
+// Vehicles returns the vehicle positions, ordered by the entity ids.
+func (s *State) Vehicles() []*VehicleActivity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.vehicles
+}
+
+// Timestamp returns the creation time of the latest feed message.
+func (s *State) Timestamp() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.timestamp
+}
+
+// Run fetches a feed message from the source at the interval and applies it, until the context is done. The errors
+// are logged, and the previous state is kept until a fetch succeeds.
+func (s *State) Run(ctx context.Context, source Source, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		message, err := source.Fetch()
+		if err != nil {
+			log.Println(fmt.Sprintf("realtime feed %v: %v", source, err))
+		} else {
+			s.Apply(message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *State) initialize() {
+	s.journeysByStart = make(map[*model.Line]map[time.Duration][]*model.Journey)
+
+	for _, journey := range s.Repository.Journeys.All {
+		start, err := utils.ParseGtfsTime(journey.DepartureTime)
+		if err != nil {
+			continue
+		}
+
+		if s.journeysByStart[journey.Line] == nil {
+			s.journeysByStart[journey.Line] = make(map[time.Duration][]*model.Journey)
+		}
+		s.journeysByStart[journey.Line][start] = append(s.journeysByStart[journey.Line][start], journey)
+	}
+}
+
+// match rebuilds the matched trip updates and vehicle positions from the entities. The lock must be held.
+func (s *State) match() {
+	ids := make([]string, 0, len(s.entities))
+	for id := range s.entities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	journeys := make(map[string]*JourneyUpdate)
+	vehicles := make([]*VehicleActivity, 0)
+	unmatched := 0
+
+	for _, id := range ids {
+		entity := s.entities[id]
+
+		if tu := entity.TripUpdate; tu != nil {
+			journey := s.findJourney(&tu.Trip)
+			if journey == nil {
+				unmatched++
+				continue
+			}
+
+			journeys[journey.Id] = &JourneyUpdate{
+				Journey:    journey,
+				Line:       s.findLine(&tu.Trip, journey),
+				TripUpdate: tu,
+				Calls:      matchCalls(journey, tu.StopTimeUpdates, s.Repository.StopPoints.ById),
+			}
+		}
+
+		if vp := entity.Vehicle; vp != nil {
+			activity := &VehicleActivity{Vehicle: vp}
+			if vp.Trip != nil {
+				activity.Journey = s.findJourney(vp.Trip)
+				activity.Line = s.findLine(vp.Trip, activity.Journey)
+			}
+
+			if sp, ok := s.Repository.StopPoints.ById[vp.StopId]; ok {
+				activity.StopPoint = sp
+			} else if activity.Journey != nil && vp.CurrentStopSequence != nil {
+				if i := callWithStopSequence(activity.Journey.Calls, 0, int(*vp.CurrentStopSequence)); i >= 0 {
+					activity.StopPoint = activity.Journey.Calls[i].StopPoint
+				}
+			}
+
+			vehicles = append(vehicles, activity)
+		}
+	}
+
+	if unmatched > 0 {
+		log.Println(fmt.Sprintf("realtime feed: %v trip updates did not match a journey", unmatched))
+	}
+
+	s.journeys = journeys
+	s.vehicles = vehicles
+}
+
+// findJourney returns the journey of the trip by its trip_id, or without one by the route_id, the direction_id and
+// the start_time and start_date of the trip. It returns nil if no journey or several journeys match.
+func (s *State) findJourney(trip *TripDescriptor) *model.Journey {
+	if trip.TripId != "" {
+		return s.Repository.Journeys.ById[trip.TripId]
+	}
+
+	line, ok := s.Repository.Lines.ById[trip.RouteId]
+	if !ok || trip.StartTime == "" {
+		return nil
+	}
+
+	start, err := utils.ParseGtfsTime(trip.StartTime)
+	if err != nil {
+		return nil
+	}
+
+	var date time.Time
+	if trip.StartDate != "" {
+		if date, err = time.Parse("20060102", trip.StartDate); err != nil {
+			return nil
+		}
+	}
+
+	var result *model.Journey
+	for _, journey := range s.journeysByStart[line][start] {
+		if trip.DirectionId != nil && journey.Direction != fmt.Sprint(*trip.DirectionId) {
+			continue
+		}
+		if !date.IsZero() && !s.Repository.ServiceCalendar.RunsOn(journey.GtfsInfo.ServiceId, date) {
+			continue
+		}
+		if result != nil {
+			return nil
+		}
+		result = journey
+	}
+
+	return result
+}
+
+// findLine returns the line of the trip by its route_id, falling back to the line of the journey.
+func (s *State) findLine(trip *TripDescriptor, journey *model.Journey) *model.Line {
+	if line, ok := s.Repository.Lines.ById[trip.RouteId]; ok {
+		return line
+	}
+	if journey != nil {
+		return journey.Line
+	}
+	return nil
+}
+
+// matchCalls matches the stop time updates to the calls of the journey by their stop_sequence, or without one by
+// their stop_id. The updates are in the order of the calls, so the search for each one starts after the previous
+// match, which tells apart the calls of a loop at the same stop point.
+func matchCalls(journey *model.Journey, updates []*StopTimeUpdate, stopPoints map[string]*model.StopPoint) []*CallUpdate {
+	result := make([]*CallUpdate, 0, len(updates))
+	next := 0
+
+	for _, u := range updates {
+		i := -1
+		if u.StopSequence != nil {
+			i = callWithStopSequence(journey.Calls, next, int(*u.StopSequence))
+		} else if sp, ok := stopPoints[u.StopId]; ok {
+			for j := next; j < len(journey.Calls); j++ {
+				if journey.Calls[j].StopPoint == sp {
+					i = j
+					break
+				}
+			}
+		}
+
+		if i < 0 {
+			continue
+		}
+
+		result = append(result, &CallUpdate{Index: i, Call: journey.Calls[i], StopTimeUpdate: u})
+		next = i + 1
+	}
+
+	return result
+}
+
+func callWithStopSequence(calls []*model.JourneyCall, from int, stopSequence int) int {
+	for i := from; i < len(calls); i++ {
+		if calls[i].StopSequence == stopSequence {
+			return i
+		}
+	}
+	return -1
+}
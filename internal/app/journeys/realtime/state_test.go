@@ -0,0 +1,204 @@
+package realtime
+
+import (
+	"context"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func newTestRepository(t *testing.T) *repository.JourneysRepository {
+	files := map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"JOLI,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		"routes.txt": "route_id,route_short_name,route_long_name,route_type\n" +
+			"R1,1,Vatiala - Pirkkala,3\n",
+		"stops.txt": "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"A,A,A,61.0,23.0\n" +
+			"B,B,B,61.0,23.01\n" +
+			"C,C,C,61.0,23.02\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WD,1,1,1,1,1,0,0,20250101,20251231\n" +
+			"WE,0,0,0,0,0,1,1,20250101,20251231\n",
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,shape_id,wheelchair_accessible\n" +
+			"R1,WD,T1,C,0,,1\n" +
+			"R1,WD,T2,C,0,,1\n" +
+			"R1,WE,T3,C,0,,1\n" +
+			"R1,WD,T4,A,1,,1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,07:00:00,07:00:00,A,1\n" +
+			"T1,07:02:00,07:02:00,B,2\n" +
+			"T1,07:04:00,07:04:00,C,3\n" +
+			"T2,08:00:00,08:00:00,A,1\n" +
+			"T2,08:02:00,08:02:00,B,2\n" +
+			"T2,08:04:00,08:04:00,C,3\n" +
+			"T3,08:00:00,08:00:00,A,1\n" +
+			"T3,08:02:00,08:02:00,B,2\n" +
+			"T3,08:04:00,08:04:00,C,3\n" +
+			// A loop which calls twice at A.
+			"T4,09:00:00,09:00:00,A,10\n" +
+			"T4,09:02:00,09:02:00,B,20\n" +
+			"T4,09:04:00,09:04:00,A,30\n",
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo, _ := repository.NewJourneysRepository([]string{dir}, ggtfs.CsvDialect{}, true)
+	return repo
+}
+
+// matchedCalls returns the indexes of the calls which the stop time updates of the journey are matched to.
+func matchedCalls(state *State, journeyId string) []int {
+	update, ok := state.JourneyUpdate(journeyId)
+	if !ok {
+		return nil
+	}
+
+	result := make([]int, 0)
+	for _, c := range update.Calls {
+		result = append(result, c.Index)
+	}
+	return result
+}
+
+func TestStateApply(t *testing.T) {
+	state := NewState(newTestRepository(t))
+
+	full := testMessage{}.
+		message(1, testHeader(FullDataset, 1715770000)).
+		// By the trip_id, and the stop updates by the stop_sequence.
+		message(2, testEntity("1", 3, testTripUpdate(testMessage{}.string(1, "T1"),
+			testMessage{}.varint(1, 2), testMessage{}.varint(1, 3), testMessage{}.varint(1, 4)))).
+		// By the route_id and the start time. T3 starts at the same time, but on the weekends.
+		message(2, testEntity("2", 3, testTripUpdate(testMessage{}.string(5, "R1").string(2, "08:00:00").string(3, "20250106"),
+			testMessage{}.string(4, "C")))).
+		// The stop updates of the loop by the stop_id, in order.
+		message(2, testEntity("3", 3, testTripUpdate(testMessage{}.string(1, "T4"),
+			testMessage{}.string(4, "A"), testMessage{}.string(4, "A")))).
+		message(2, testEntity("4", 3, testTripUpdate(testMessage{}.string(1, "unknown")))).
+		message(2, testEntity("5", 4, testMessage{}.
+			message(1, testMessage{}.string(1, "T1")).
+			varint(3, 3))).
+		message(2, testEntity("6", 4, testMessage{}.
+			message(2, testMessage{}.float(1, 61.5).float(2, 23.75)).
+			string(7, "B")))
+
+	message, err := DecodeFeedMessage(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Apply(message)
+
+	testutil.CompareVariablesAndPrintResults(t, []int{1, 2}, matchedCalls(state, "T1"), "T1")
+	testutil.CompareVariablesAndPrintResults(t, []int{2}, matchedCalls(state, "T2"), "T2")
+	testutil.CompareVariablesAndPrintResults(t, []int(nil), matchedCalls(state, "T3"), "T3")
+	testutil.CompareVariablesAndPrintResults(t, []int{0, 2}, matchedCalls(state, "T4"), "T4")
+	if !state.Timestamp().Equal(time.Unix(1715770000, 0)) {
+		t.Errorf("expected the timestamp of the message, got %v", state.Timestamp())
+	}
+
+	if update, _ := state.JourneyUpdate("T1"); update.Line == nil || update.Line.Name != "1" {
+		t.Errorf("expected the line of the journey for T1")
+	}
+
+	vehicles := state.Vehicles()
+	if len(vehicles) != 2 {
+		t.Fatalf("expected 2 vehicles, got %v", len(vehicles))
+	}
+	if v := vehicles[0]; v.Journey == nil || v.Journey.Id != "T1" || v.StopPoint == nil || v.StopPoint.ShortName != "C" {
+		t.Errorf("expected the first vehicle on T1 at C, got %+v", v)
+	}
+	if v := vehicles[1]; v.Journey != nil || v.Line != nil || v.StopPoint == nil || v.StopPoint.ShortName != "B" {
+		t.Errorf("expected the second vehicle at B without a journey, got %+v", v)
+	}
+
+	differential := testMessage{}.
+		message(1, testHeader(Differential, 1715770030)).
+		message(2, testMessage{}.string(1, "1").varint(2, 1)).
+		message(2, testEntity("3", 3, testTripUpdate(testMessage{}.string(1, "T4"), testMessage{}.string(4, "B"))))
+
+	if message, err = DecodeFeedMessage(differential); err != nil {
+		t.Fatal(err)
+	}
+	state.Apply(message)
+
+	testutil.CompareVariablesAndPrintResults(t, []int(nil), matchedCalls(state, "T1"), "T1 deleted")
+	testutil.CompareVariablesAndPrintResults(t, []int{2}, matchedCalls(state, "T2"), "T2 kept")
+	testutil.CompareVariablesAndPrintResults(t, []int{1}, matchedCalls(state, "T4"), "T4 replaced")
+	testutil.CompareVariablesAndPrintResults(t, 2, len(state.Vehicles()), "vehicles kept")
+
+	if message, err = DecodeFeedMessage(testMessage{}.message(1, testHeader(FullDataset, 1715770060))); err != nil {
+		t.Fatal(err)
+	}
+	state.Apply(message)
+
+	testutil.CompareVariablesAndPrintResults(t, []int(nil), matchedCalls(state, "T2"), "T2 replaced")
+	testutil.CompareVariablesAndPrintResults(t, 0, len(state.Vehicles()), "vehicles replaced")
+}
+
+func TestSourceFetch(t *testing.T) {
+	message := testMessage{}.
+		message(1, testHeader(FullDataset, 1715770000)).
+		message(2, testEntity("1", 3, testTripUpdate(testMessage{}.string(1, "T1"))))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/trip-updates" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = rw.Write(message)
+	}))
+	defer srv.Close()
+
+	file := path.Join(t.TempDir(), "trip-updates.pb")
+	if err := os.WriteFile(file, message, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		source        Source
+		errorExpected bool
+	}{
+		{Source{Url: srv.URL + "/trip-updates"}, false},
+		{Source{Url: srv.URL + "/foobar"}, true},
+		{Source{Path: file}, false},
+		{Source{Path: file + ".foobar"}, true},
+		{Source{}, true},
+	}
+
+	for _, tc := range testCases {
+		feed, err := tc.source.Fetch()
+		if tc.errorExpected {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.source)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", tc.source, err)
+			continue
+		}
+		testutil.CompareVariablesAndPrintResults(t, "T1", feed.Entities[0].TripUpdate.Trip.TripId, tc.source.String())
+	}
+
+	// With a context which is already done, Run applies one message and returns.
+	state := NewState(newTestRepository(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	state.Run(ctx, Source{Url: srv.URL + "/trip-updates"}, time.Minute)
+
+	testutil.CompareVariablesAndPrintResults(t, []int{}, matchedCalls(state, "T1"), "run")
+}
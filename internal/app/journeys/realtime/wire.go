@@ -0,0 +1,91 @@
+package realtime
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	wireVarint          = 0
+	wireFixed64         = 1
+	wireLengthDelimited = 2
+	wireFixed32         = 5
+)
+
+var errTruncated = errors.New("unexpected end of message")
+
+// protoField is a field of a protobuf message. Only the value matching the wire type of the field is set.
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64
+	fixed32  uint32
+	fixed64  uint64
+	bytes    []byte
+}
+
+// readProtoFields calls fn for each field of the protobuf message in order, and stops at the first error.
+func readProtoFields(data []byte, fn func(f protoField) error) error {
+	for len(data) > 0 {
+		key, n := readVarint(data)
+		if n == 0 {
+			return errTruncated
+		}
+		data = data[n:]
+
+		f := protoField{number: int(key >> 3), wireType: int(key & 0x7)}
+		if f.number == 0 {
+			return errors.New("invalid field number 0")
+		}
+
+		switch f.wireType {
+		case wireVarint:
+			f.varint, n = readVarint(data)
+			if n == 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			f.fixed64 = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireLengthDelimited:
+			l, n := readVarint(data)
+			if n == 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			f.bytes = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			f.fixed32 = binary.LittleEndian.Uint32(data)
+			data = data[4:]
+		default:
+			// The groups are deprecated, and not used by GTFS-Realtime.
+			return fmt.Errorf("unsupported wire type %v of field %v", f.wireType, f.number)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readVarint returns the varint at the start of data, and the number of bytes read, or 0 if data ends before it.
+func readVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -1,14 +1,11 @@
 package repository
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/csv"
 	"errors"
 	"fmt"
-	"github.com/dimchansky/utfbom"
 	"github.com/jlundan/journeys-api/pkg/ggtfs"
-	"golang.org/x/text/encoding"
 	"io"
 	"os"
 	"path"
@@ -16,100 +13,50 @@ import (
 	"strings"
 )
 
-func newGTFSBundle(gtfsPath string, skipValidation bool) *GTFSBundle {
+// newGTFSBundle loads the feeds at gtfsPaths. Several feeds are merged into one, so that they can be served as a
+// single dataset.
+func newGTFSBundle(gtfsPaths []string, dialect ggtfs.CsvDialect, skipValidation bool) *GTFSBundle {
 	bundle := GTFSBundle{}
 
-	files := []string{ggtfs.FileNameAgency, ggtfs.FileNameRoutes, ggtfs.FileNameStops, ggtfs.FileNameTrips, ggtfs.FileNameStopTimes,
-		ggtfs.FileNameCalendar, ggtfs.FileNameCalendarDate, ggtfs.FileNameShapes, "municipalities.txt"}
-
-	for _, file := range files {
-		reader, err := createCSVReaderForFile(path.Join(gtfsPath, file))
-		if err != nil {
-			bundle.Errors = append(bundle.Errors, err)
+	var feeds []*ggtfs.Feed
+	var namespaces []string
+	for _, gtfsPath := range gtfsPaths {
+		feed, errs := ggtfs.LoadFeed(gtfsPath, dialect)
+		if len(gtfsPaths) > 1 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("%v: %v", gtfsPath, err.Error())
+			}
 		}
 
-		var gtfsErrors []error
-		var municipalityError error
-
-		switch file {
-		case ggtfs.FileNameAgency:
-			bundle.Agencies, gtfsErrors = ggtfs.LoadAgencies(ggtfs.NewReader(reader))
-		case ggtfs.FileNameRoutes:
-			bundle.Routes, gtfsErrors = ggtfs.LoadRoutes(ggtfs.NewReader(reader))
-		case ggtfs.FileNameStops:
-			bundle.Stops, gtfsErrors = ggtfs.LoadStops(ggtfs.NewReader(reader))
-		case ggtfs.FileNameTrips:
-			bundle.Trips, gtfsErrors = ggtfs.LoadTrips(ggtfs.NewReader(reader))
-		case ggtfs.FileNameStopTimes:
-			bundle.StopTimes, gtfsErrors = ggtfs.LoadStopTimes(ggtfs.NewReader(reader))
-		case ggtfs.FileNameCalendar:
-			bundle.CalendarItems, gtfsErrors = ggtfs.LoadCalendar(ggtfs.NewReader(reader))
-		case ggtfs.FileNameCalendarDate:
-			bundle.CalendarDates, gtfsErrors = ggtfs.LoadCalendarDates(ggtfs.NewReader(reader))
-		case ggtfs.FileNameShapes:
-			bundle.Shapes, gtfsErrors = ggtfs.LoadShapes(ggtfs.NewReader(reader))
-		case "municipalities.txt":
-			bundle.Municipalities, municipalityError = readMunicipalities(gtfsPath)
-		}
+		bundle.Errors = append(bundle.Errors, errs...)
+		bundle.ValidationNotices = append(bundle.ValidationNotices, feed.LoadNotices...)
 
-		bundle.Errors = append(bundle.Errors, gtfsErrors...)
-		if municipalityError != nil {
-			bundle.Errors = append(bundle.Errors, municipalityError)
+		if !skipValidation {
+			bundle.ValidationNotices = append(bundle.ValidationNotices, feed.Validate(ggtfs.ValidationOptions{})...)
 		}
-	}
 
-	if !skipValidation {
-		bundle.ValidationNotices = append(bundle.ValidationNotices, ggtfs.ValidateTrips(bundle.Trips, bundle.Routes, bundle.CalendarItems, bundle.Shapes)...)
-		bundle.ValidationNotices = append(bundle.ValidationNotices, ggtfs.ValidateShapes(bundle.Shapes)...)
-		bundle.ValidationNotices = append(bundle.ValidationNotices, ggtfs.ValidateCalendarDates(bundle.CalendarDates, bundle.CalendarItems)...)
-		bundle.ValidationNotices = append(bundle.ValidationNotices, ggtfs.ValidateRoutes(bundle.Routes, bundle.Agencies)...)
-		bundle.ValidationNotices = append(bundle.ValidationNotices, ggtfs.ValidateStopTimes(bundle.StopTimes, bundle.Stops)...)
+		feeds = append(feeds, feed)
+		namespaces = append(namespaces, path.Base(path.Clean(gtfsPath)))
 	}
 
-	return &bundle
-}
-
-func createCSVReaderForFile(path string) (*csv.Reader, error) {
-	csvFile, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	if len(feeds) == 1 {
+		bundle.Feed = feeds[0]
+	} else {
+		merged, notices := ggtfs.MergeFeeds(feeds, ggtfs.MergeOptions{Namespaces: namespaces})
+		bundle.Feed = merged
+		bundle.ValidationNotices = append(bundle.ValidationNotices, notices...)
 	}
 
-	sr, _ := utfbom.Skip(csvFile)
-
-	filteredReader := newSkippingReader(sr)
+	municipalities, notices, errs := readMunicipalities(gtfsPaths, dialect)
+	bundle.Errors = append(bundle.Errors, errs...)
+	bundle.ValidationNotices = append(bundle.ValidationNotices, notices...)
+	bundle.Municipalities = municipalities
 
-	return csv.NewReader(filteredReader), nil
-}
-
-func newSkippingReader(r io.Reader) io.Reader {
-	var buf bytes.Buffer
-
-	// Use a bufio.Scanner to read through the input line by line.
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip empty lines and lines that contain only whitespace.
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		// Write non-empty lines to the buffer.
-		buf.WriteString(line + "\n")
-	}
-
-	// Return a new reader that reads from the buffer.
-	return &buf
+	return &bundle
 }
 
 type GTFSBundle struct {
-	Agencies          []*ggtfs.Agency
-	Routes            []*ggtfs.Route
-	Stops             []*ggtfs.Stop
-	Trips             []*ggtfs.Trip
-	StopTimes         []*ggtfs.StopTime
-	CalendarItems     []*ggtfs.CalendarItem
-	CalendarDates     []*ggtfs.CalendarDate
-	Shapes            []*ggtfs.Shape
+	Feed              *ggtfs.Feed
 	Municipalities    *municipalityData
 	ValidationNotices []ggtfs.ValidationNotice
 	Errors            []error
@@ -122,38 +69,100 @@ type municipalityData struct {
 	municipalityRows    [][]string
 }
 
-func readMunicipalities(gtfsPath string) (*municipalityData, error) {
-	var err error
-	m := &municipalityData{}
-	m.municipalityHeaders, m.municipalityRows, err = parseFile(fmt.Sprintf("%v/%v", gtfsPath, MunicipalityFileName), true)
-	if err != nil && !os.IsNotExist(err) {
-		return nil, err
+// readMunicipalities reads the municipalities of every feed. A municipality which is defined in several feeds is
+// taken from the first one. The notices tell which files had to be converted to UTF-8.
+func readMunicipalities(gtfsPaths []string, dialect ggtfs.CsvDialect) (*municipalityData, []ggtfs.ValidationNotice, []error) {
+	var errs []error
+	var notices []ggtfs.ValidationNotice
+	m := &municipalityData{municipalityHeaders: map[string]uint8{"id": 0, "name": 1}}
+	seen := make(map[string]bool)
+
+	for _, gtfsPath := range gtfsPaths {
+		headers, rows, fileNotices, err := parseFileWithDialect(fmt.Sprintf("%v/%v", gtfsPath, MunicipalityFileName), true, dialect)
+		notices = append(notices, fileNotices...)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		idIndex, hasId := headers["id"]
+		nameIndex, hasName := headers["name"]
+		if !hasId || !hasName {
+			errs = append(errs, fmt.Errorf("%v/%v: id and name columns are required", gtfsPath, MunicipalityFileName))
+			continue
+		}
+
+		for _, row := range rows {
+			if int(idIndex) >= len(row) || int(nameIndex) >= len(row) || seen[row[idIndex]] {
+				continue
+			}
+			seen[row[idIndex]] = true
+			m.municipalityRows = append(m.municipalityRows, []string{row[idIndex], row[nameIndex]})
+		}
 	}
 
-	return m, nil
+	return m, notices, errs
 }
 
-func parseFile(path string, firstLineAsHeaders bool) (map[string]uint8, [][]string, error) {
-	return parseFileWithDecoderAndDelimiter(path, firstLineAsHeaders, nil, ',')
+// WriteMunicipalities writes the municipalities of the feeds at gtfsPaths into dir. Nothing is written if none of
+// the feeds has municipalities.
+func WriteMunicipalities(dir string, gtfsPaths []string, dialect ggtfs.CsvDialect) error {
+	m, _, errs := readMunicipalities(gtfsPaths, dialect)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if len(m.municipalityRows) == 0 {
+		return nil
+	}
+
+	f, err := os.Create(path.Join(dir, MunicipalityFileName))
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"id", "name"})
+	_ = w.WriteAll(m.municipalityRows)
+
+	if err = w.Error(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
 }
 
-func parseFileWithDecoderAndDelimiter(path string, firstLineAsHeaders bool, decoder *encoding.Decoder, delimiter rune) (map[string]uint8, [][]string, error) {
-	csvFile, err := os.Open(path)
+// parseFileWithDialect reads a CSV file which is not a part of the GTFS specification. Like the GTFS files, it is
+// converted to UTF-8, and a notice is returned if the encoding had to be detected and was something else.
+func parseFileWithDialect(filePath string, firstLineAsHeaders bool, dialect ggtfs.CsvDialect) (map[string]uint8, [][]string, []ggtfs.ValidationNotice, error) {
+	raw, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	var r *csv.Reader
-	if decoder != nil {
-		r = csv.NewReader(decoder.Reader(trimReader{csvFile}))
-	} else {
-		r = csv.NewReader(trimReader{csvFile})
+	content, usedEncoding, err := ggtfs.DecodeToUTF8(raw, dialect.Encoding)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: %v", filePath, err.Error())
 	}
 
-	if delimiter != ',' {
-		r.Comma = delimiter
+	var notices []ggtfs.ValidationNotice
+	if dialect.Encoding == ggtfs.EncodingAuto && usedEncoding != ggtfs.EncodingUTF8 {
+		notices = append(notices, ggtfs.CharacterEncodingConvertedNotice{
+			FileName: path.Base(filePath),
+			Encoding: usedEncoding,
+		})
 	}
 
+	if dialect.Delimiter == 0 {
+		dialect.Delimiter = ggtfs.DetectDelimiter(content)
+	}
+
+	r := csv.NewReader(trimReader{bytes.NewReader(content)})
+	dialect.Apply(r)
+
 	var headers = map[string]uint8{}
 	var data = make([][]string, 0)
 
@@ -164,7 +173,7 @@ func parseFileWithDecoderAndDelimiter(path string, firstLineAsHeaders bool, deco
 			break
 		}
 		if err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("%v: %v", path, err.Error()))
+			return nil, nil, notices, errors.New(fmt.Sprintf("%v: %v", filePath, err.Error()))
 		}
 
 		if firstLineAsHeaders && !headersRead {
@@ -181,7 +190,7 @@ func parseFileWithDecoderAndDelimiter(path string, firstLineAsHeaders bool, deco
 		}
 
 	}
-	return headers, data, nil
+	return headers, data, notices, nil
 }
 
 var trailingWs = regexp.MustCompile(`\s\n`)
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"testing"
+)
+
+func TestMunicipalitiesEncodingNotice(t *testing.T) {
+	dir := writeTestFeed(t, map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"JOLI,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		// Mänttä-Vilppula in Windows-1252.
+		MunicipalityFileName: "id,name\n" +
+			"508,M\xe4ntt\xe4-Vilppula\n",
+	})
+
+	bundle := newGTFSBundle([]string{dir}, ggtfs.CsvDialect{}, true)
+
+	var found bool
+	for _, notice := range bundle.ValidationNotices {
+		if n, ok := notice.(ggtfs.CharacterEncodingConvertedNotice); ok && n.FileName == MunicipalityFileName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the conversion of %v to be reported, got %v", MunicipalityFileName, bundle.ValidationNotices)
+	}
+
+	if rows := bundle.Municipalities.municipalityRows; len(rows) != 1 || rows[0][1] != "Mänttä-Vilppula" {
+		t.Errorf("expected the municipality in UTF-8, got %v", rows)
+	}
+}
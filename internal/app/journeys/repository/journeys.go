@@ -16,7 +16,7 @@ import (
 
 func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips []*ggtfs.Trip, calendarItems []*ggtfs.CalendarItem,
 	calendarDates []*ggtfs.CalendarDate, stopPointDataStore JourneysStopPointsRepository, lineDataStore JourneysLinesRepository,
-	routeDataStore JourneysRoutesRepository) (*JourneysJourneyRepository, *JourneysJourneyPatternRepository) {
+	routeDataStore *JourneysRoutesRepository, serviceCalendar *ServiceCalendar) (*JourneysJourneyRepository, *JourneysJourneyPatternRepository) {
 
 	var all = make([]*model.Journey, 0)
 	var byId = make(map[string]*model.Journey)
@@ -26,6 +26,7 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 
 	var tripIdToJourneyPattern = make(map[string]*model.JourneyPattern)
 	var tripIdToJourneyCalls = make(map[string][]*model.JourneyCall)
+	var tripIdToCallDistances = make(map[string][]float64)
 	var tripIdToStopTimes = make(map[string][]*ggtfs.StopTime)
 
 	for i, st := range stopTimes {
@@ -107,11 +108,18 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 				log.Println(fmt.Sprintf("stoptime (on gtfs row %v): DepartureTime is missing", stopTime.LineNumber))
 			}
 
+			var stopSequence int
+			if stopTime.StopSequence != nil {
+				stopSequence, _ = strconv.Atoi(strings.TrimSpace(*stopTime.StopSequence))
+			}
+
 			tripIdToJourneyCalls[tripId] = append(tripIdToJourneyCalls[tripId], &model.JourneyCall{ // tripId is already trimmed for spaces
 				DepartureTime: departureTime,
 				ArrivalTime:   arrivalTime,
 				StopPoint:     sp,
+				StopSequence:  stopSequence,
 			})
+			tripIdToCallDistances[tripId] = append(tripIdToCallDistances[tripId], parseShapeDistTraveled(stopTime.ShapeDistTraveled))
 
 			if jp != nil {
 				jp.StopPoints = append(jp.StopPoints, sp)
@@ -127,6 +135,9 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 		tripIdToJourneyPattern[tripId] = journeyPatternsById[stopListHash]
 	}
 
+	// The stop points of a journey pattern are placed on each route once, by the journey pattern id and the route id.
+	placements := make(map[string]*stopPlacement)
+
 	calendarMap := buildCalendarMap(calendarItems)
 	calendarDateMap := buildCalendarDatesMap(calendarDates)
 
@@ -146,11 +157,6 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 			continue
 		}
 
-		if trip.ShapeId == nil {
-			fmt.Println(fmt.Sprintf("trip with no ShapeId detected, ignoring it. GTFS trip row: %v", trip.LineNumber))
-			continue
-		}
-
 		if trip.ServiceId == nil {
 			fmt.Println(fmt.Sprintf("trip with no ServiceId detected, ignoring it. GTFS trip row: %v", trip.LineNumber))
 			continue
@@ -158,7 +164,6 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 
 		tripId := strings.TrimSpace(*trip.Id)
 		routeId := strings.TrimSpace(*trip.RouteId)
-		shapeId := strings.TrimSpace(*trip.ShapeId)
 		serviceId := strings.TrimSpace(*trip.ServiceId)
 
 		jp, ok := tripIdToJourneyPattern[tripId]
@@ -173,16 +178,22 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 			continue
 		}
 
-		route, routeFound := routeDataStore.ById[shapeId]
-		if !routeFound {
-			fmt.Println(fmt.Sprintf("Journey with no route detected, ignoring it: %v", tripId))
-			continue
-		}
-
 		cMapItem, ok := calendarMap[serviceId]
 		if !ok {
-			fmt.Println(fmt.Sprintf("Journey with no service detected, ignoring it: %v", tripId))
-			continue
+			// Services may be defined in calendar_dates.txt only, in which case the validity range is derived
+			// from the operating dates.
+			first, last, hasDates := serviceCalendar.Range(serviceId)
+			if !hasDates {
+				fmt.Println(fmt.Sprintf("Journey with no service detected, ignoring it: %v", tripId))
+				continue
+			}
+
+			cMapItem = calendarFileRow{
+				serviceId: serviceId,
+				startDate: first.Format("2006-01-02"),
+				endDate:   last.Format("2006-01-02"),
+				dayTypes:  make([]string, 0),
+			}
 		}
 
 		cdMapItem, ok := calendarDateMap[serviceId]
@@ -196,6 +207,20 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 			continue
 		}
 
+		var route *model.Route
+		if ggtfs.StringIsNilOrEmpty(trip.ShapeId) {
+			// shape_id is optional, the trips without one get a route drawn through their stop points. The route is
+			// generated only for a journey which is kept, since a journey sets the line of its route.
+			route = generatedRoute(routeDataStore, jp)
+		} else {
+			var routeFound bool
+			route, routeFound = routeDataStore.ById[strings.TrimSpace(*trip.ShapeId)]
+			if !routeFound {
+				fmt.Println(fmt.Sprintf("Journey with no route detected, ignoring it: %v", tripId))
+				continue
+			}
+		}
+
 		dtParts := strings.Split(calls[0].DepartureTime, ":")
 		dt := strings.Join(dtParts[:2], "")
 
@@ -230,7 +255,8 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 			Direction:            directionId,
 			WheelchairAccessible: wheelChairAccessible == "1",
 			GtfsInfo: &model.JourneyGtfsInfo{
-				TripId: tripId,
+				TripId:    tripId,
+				ServiceId: serviceId,
 			},
 			DayTypes:          cMapItem.dayTypes,
 			DayTypeExceptions: cdMapItem,
@@ -245,6 +271,18 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 			ActivityId:        activityId,
 		}
 
+		placementKey := jp.Id + "/" + route.Id
+		placement, ok := placements[placementKey]
+		if !ok {
+			placement = placeStopPoints(jp, route, routeDataStore.shapeDistances[route.Id], tripIdToCallDistances[tripId])
+			placements[placementKey] = placement
+		}
+
+		for i, c := range calls {
+			c.DistanceTraveled = placement.distances[i]
+		}
+		journey.Length = placement.length()
+
 		jp.Route = route
 
 		route.Journeys = append(route.Journeys, &journey)
@@ -265,14 +303,42 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 		return all[x].Id < all[y].Id
 	})
 
+	sort.Slice(routeDataStore.All, func(x, y int) bool {
+		return routeDataStore.All[x].Id < routeDataStore.All[y].Id
+	})
+
 	sort.Slice(allJourneyPatterns, func(x, y int) bool {
 		return allJourneyPatterns[x].Id < allJourneyPatterns[y].Id
 	})
 
+	for _, jp := range allJourneyPatterns {
+		var placement *stopPlacement
+		if jp.Route != nil {
+			placement = placements[jp.Id+"/"+jp.Route.Id]
+		}
+		if placement == nil {
+			placement = placeStopPoints(jp, jp.Route, nil, nil)
+		}
+
+		jp.Segments = placement.segments()
+		jp.Length = placement.length()
+	}
+
+	byStopPoint := make(map[string][]*model.Journey)
+	for _, journey := range all {
+		for i, c := range journey.Calls {
+			if callsEarlierAt(journey.Calls[:i], c.StopPoint) {
+				continue
+			}
+			byStopPoint[c.StopPoint.ShortName] = append(byStopPoint[c.StopPoint.ShortName], journey)
+		}
+	}
+
 	return &JourneysJourneyRepository{
 			All:          all,
 			ById:         byId,
 			ByActivityId: byActivityId,
+			ByStopPoint:  byStopPoint,
 		},
 		&JourneysJourneyPatternRepository{
 			All:  allJourneyPatterns,
@@ -280,6 +346,17 @@ func newJourneysAndJourneyPatternsRepository(stopTimes []*ggtfs.StopTime, trips
 		}
 }
 
+// callsEarlierAt reports whether one of the calls is at the stop point. Journeys on loop routes call at some stop
+// points twice.
+func callsEarlierAt(calls []*model.JourneyCall, stopPoint *model.StopPoint) bool {
+	for _, c := range calls {
+		if c.StopPoint == stopPoint {
+			return true
+		}
+	}
+	return false
+}
+
 func routeContainsJourneyPattern(route *model.Route, journeyPattern *model.JourneyPattern) bool {
 	for _, jp := range route.JourneyPatterns {
 		if jp.Id == journeyPattern.Id {
@@ -294,6 +371,8 @@ type JourneysJourneyRepository struct {
 	All          []*model.Journey
 	ById         map[string]*model.Journey
 	ByActivityId map[string]*model.Journey
+	// ByStopPoint lists the journeys calling at each stop point, by the short name of the stop point.
+	ByStopPoint map[string][]*model.Journey
 }
 
 type JourneysJourneyPatternRepository struct {
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"math"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func writeTestFeed(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCalendarDatesOnlyServices(t *testing.T) {
+	dir := writeTestFeed(t, map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"JOLI,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		"routes.txt": "route_id,route_short_name,route_long_name,route_type\n" +
+			"1,1,Vatiala - Pirkkala,3\n",
+		"stops.txt": "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"4600,4600,Vatiala,61.47561,23.97756\n" +
+			"8171,8171,Vällintie,61.48067,23.97002\n",
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,shape_id,wheelchair_accessible\n" +
+			"1,HOLIDAYS,T1,Pirkkala,0,SH1,1\n" +
+			"1,UNKNOWN,T2,Pirkkala,0,SH1,1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,07:00:00,07:00:00,4600,1\n" +
+			"T1,07:02:00,07:02:00,8171,2\n" +
+			"T2,08:00:00,08:00:00,4600,1\n" +
+			"T2,08:02:00,08:02:00,8171,2\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"HOLIDAYS,20251224,1\n" +
+			"HOLIDAYS,20251226,1\n" +
+			"HOLIDAYS,20251225,1\n",
+		"shapes.txt": "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n" +
+			"SH1,61.47561,23.97756,1\n" +
+			"SH1,61.48067,23.97002,2\n",
+	})
+
+	repo, _ := NewJourneysRepository([]string{dir}, ggtfs.CsvDialect{}, true)
+
+	if _, ok := repo.Journeys.ById["T2"]; ok {
+		t.Error("expected the journey without a service to be ignored")
+	}
+
+	journey, ok := repo.Journeys.ById["T1"]
+	if !ok {
+		t.Fatal("expected the journey of a calendar_dates-only service to be built")
+	}
+
+	if journey.ValidFrom != "2025-12-24" || journey.ValidTo != "2025-12-26" {
+		t.Errorf("expected validity 2025-12-24 - 2025-12-26, got %v - %v", journey.ValidFrom, journey.ValidTo)
+	}
+
+	if len(journey.DayTypes) != 0 {
+		t.Errorf("expected no day types, got %v", journey.DayTypes)
+	}
+
+	if len(journey.DayTypeExceptions) != 3 {
+		t.Errorf("expected the operating dates as day type exceptions, got %v", len(journey.DayTypeExceptions))
+	}
+
+	if dates := repo.ServiceCalendar.Dates(journey.GtfsInfo.ServiceId, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)); len(dates) != 3 {
+		t.Errorf("expected three operating dates, got %v", dates)
+	}
+}
+
+func TestJourneyDistances(t *testing.T) {
+	dir := writeTestFeed(t, map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"JOLI,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		"routes.txt": "route_id,route_short_name,route_long_name,route_type\n" +
+			"1,1,Vatiala - Pirkkala,3\n",
+		"stops.txt": "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"A,A,A,61.0,23.0\n" +
+			"B,B,B,61.001,23.015\n" +
+			"C,C,C,61.0,23.02\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WD,1,1,1,1,1,0,0,20250101,20251231\n",
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,shape_id,wheelchair_accessible\n" +
+			"1,WD,T1,C,0,SH1,1\n" +
+			"1,WD,T2,C,0,SH2,1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,shape_dist_traveled\n" +
+			"T1,07:00:00,07:00:00,A,1,0\n" +
+			"T1,07:02:00,07:02:00,B,2,0.54\n" +
+			"T1,07:04:00,07:04:00,C,3,1.08\n" +
+			"T2,08:00:00,08:00:00,A,1,\n" +
+			"T2,08:02:00,08:02:00,B,2,\n" +
+			"T2,08:04:00,08:04:00,C,3,\n",
+		// The points of the shapes are not in order in the file.
+		"shapes.txt": "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence,shape_dist_traveled\n" +
+			"SH1,61.0,23.02,3,1.08\n" +
+			"SH1,61.0,23.0,1,0\n" +
+			"SH1,61.0,23.01,2,0.54\n" +
+			"SH2,61.0,23.0,1,\n" +
+			"SH2,61.0,23.02,3,\n" +
+			"SH2,61.0,23.01,2,\n",
+	})
+
+	repo, _ := NewJourneysRepository([]string{dir}, ggtfs.CsvDialect{}, true)
+
+	testCases := []struct {
+		tripId   string
+		expected []float64
+	}{
+		// B is placed by shape_dist_traveled at the second point of the shape.
+		{"T1", []float64{0, 539, 1078}},
+		// Without shape_dist_traveled B is placed on the shape nearest to it.
+		{"T2", []float64{0, 809, 1078}},
+	}
+
+	for _, tc := range testCases {
+		journey, ok := repo.Journeys.ById[tc.tripId]
+		if !ok {
+			t.Fatalf("expected journey %v", tc.tripId)
+		}
+
+		var distances []float64
+		for _, c := range journey.Calls {
+			distances = append(distances, math.Round(c.DistanceTraveled))
+		}
+
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, distances, tc.tripId)
+		testutil.CompareVariablesAndPrintResults(t, 1078.0, math.Round(journey.Length), tc.tripId)
+		testutil.CompareVariablesAndPrintResults(t, 1078.0, math.Round(journey.JourneyPattern.Length), tc.tripId)
+	}
+
+	if route := repo.Routes.ById["SH1"]; route.GeoProjection != "6100000,2300000:0,-1000:0,-1000" {
+		t.Errorf("expected the points of the shape in sequence, got %v", route.GeoProjection)
+	}
+}
+
+func TestTripsWithoutShape(t *testing.T) {
+	dir := writeTestFeed(t, map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"JOLI,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		"routes.txt": "route_id,route_short_name,route_long_name,route_type\n" +
+			"1,1,Vatiala - Pirkkala,3\n",
+		"stops.txt": "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"A,A,A,61.0,23.0\n" +
+			"B,B,B,61.0,23.01\n" +
+			"C,C,C,61.01,23.01\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WD,1,1,1,1,1,0,0,20250101,20251231\n",
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,shape_id,wheelchair_accessible\n" +
+			"1,WD,T1,C,0,,1\n" +
+			"1,WD,T2,C,0,,1\n" +
+			"1,WD,T3,B,1,,1\n" +
+			"1,XX,T4,A,1,,1\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,07:00:00,07:00:00,A,1\n" +
+			"T1,07:02:00,07:02:00,B,2\n" +
+			"T1,07:04:00,07:04:00,C,3\n" +
+			"T2,08:00:00,08:00:00,A,1\n" +
+			"T2,08:02:00,08:02:00,B,2\n" +
+			"T2,08:04:00,08:04:00,C,3\n" +
+			"T3,09:00:00,09:00:00,C,1\n" +
+			"T3,09:02:00,09:02:00,B,2\n" +
+			"T4,10:00:00,10:00:00,B,1\n" +
+			"T4,10:02:00,10:02:00,A,2\n",
+	})
+
+	repo, _ := NewJourneysRepository([]string{dir}, ggtfs.CsvDialect{}, true)
+
+	for _, tripId := range []string{"T1", "T2", "T3"} {
+		if _, ok := repo.Journeys.ById[tripId]; !ok {
+			t.Fatalf("expected journey %v", tripId)
+		}
+	}
+
+	t1, t2, t3 := repo.Journeys.ById["T1"], repo.Journeys.ById["T2"], repo.Journeys.ById["T3"]
+
+	// The trips with the same stop points share the route.
+	if t1.Route != t2.Route || t1.Route == t3.Route {
+		t.Errorf("expected a route per sequence of stop points, got %v, %v and %v", t1.Route.Id, t2.Route.Id, t3.Route.Id)
+	}
+
+	testutil.CompareVariablesAndPrintResults(t, generatedRouteIdPrefix+t1.JourneyPattern.Id, t1.Route.Id, "T1")
+	testutil.CompareVariablesAndPrintResults(t, "6100000,2300000:0,-1000:-1000,0", t1.Route.GeoProjection, "T1")
+	testutil.CompareVariablesAndPrintResults(t, 2, len(repo.Routes.All), "routes")
+	if repo.Routes.ById[t1.Route.Id] != t1.Route {
+		t.Errorf("expected route %v in the repository", t1.Route.Id)
+	}
+	testutil.CompareVariablesAndPrintResults(t, 1, len(t1.Route.JourneyPatterns), "T1")
+
+	// T4 has no service, so it is ignored without leaving a route behind.
+	if _, ok := repo.Journeys.ById["T4"]; ok {
+		t.Errorf("expected journey T4 to be ignored")
+	}
+	for _, route := range repo.Routes.All {
+		if route.Line == nil {
+			t.Errorf("expected route %v to have a line", route.Id)
+		}
+	}
+
+	// The stop points are at the points of the route.
+	var distances []float64
+	for _, c := range t1.Calls {
+		distances = append(distances, math.Round(c.DistanceTraveled))
+	}
+	testutil.CompareVariablesAndPrintResults(t, []float64{0, 539, 1651}, distances, "T1")
+}
@@ -2,16 +2,25 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"log"
+	"strings"
+	"time"
 )
 
-func NewJourneysRepository(gtfsPath string, skipValidation bool) (*JourneysRepository, []error) {
-	bundle := newGTFSBundle(gtfsPath, skipValidation)
+// NewJourneysRepository builds the repository from the GTFS feeds at gtfsPaths. When several paths are given, the
+// feeds are merged and served as one dataset.
+func NewJourneysRepository(gtfsPaths []string, dialect ggtfs.CsvDialect, skipValidation bool) (*JourneysRepository, []error) {
+	bundle := newGTFSBundle(gtfsPaths, dialect, skipValidation)
 
-	linesRepository := newLinesRepository(bundle.Routes)
-	routesRepository := newRoutesRepository(bundle.Shapes)
+	serviceCalendar := NewServiceCalendar(bundle.Feed.CalendarItems, bundle.Feed.CalendarDates)
+	linesRepository := newLinesRepository(bundle.Feed.Routes)
+	routesRepository := newRoutesRepository(bundle.Feed.Shapes)
 	municipalitiesRepository := newMunicipalitiesRepository(*bundle.Municipalities)
-	stopPointsRepository := newStopPointsRepository(bundle.Stops, municipalitiesRepository)
-	journeyRepository, journeyPatternRepository := newJourneysAndJourneyPatternsRepository(bundle.StopTimes, bundle.Trips, bundle.CalendarItems, bundle.CalendarDates, *stopPointsRepository, *linesRepository, *routesRepository)
+	stopPointsRepository := newStopPointsRepository(bundle.Feed.Stops, municipalitiesRepository)
+	transfersRepository := newTransfersRepository(bundle.Feed.Transfers, stopPointsRepository)
+	journeyRepository, journeyPatternRepository := newJourneysAndJourneyPatternsRepository(bundle.Feed.StopTimes, bundle.Feed.Trips, bundle.Feed.CalendarItems, bundle.Feed.CalendarDates, *stopPointsRepository, *linesRepository, routesRepository, serviceCalendar)
 
 	errs := getBundleErrorsNotices(bundle)
 
@@ -22,6 +31,9 @@ func NewJourneysRepository(gtfsPath string, skipValidation bool) (*JourneysRepos
 		Routes:          routesRepository,
 		Journeys:        journeyRepository,
 		JourneyPatterns: journeyPatternRepository,
+		Transfers:       transfersRepository,
+		ServiceCalendar: serviceCalendar,
+		Timezone:        agencyTimezone(bundle.Feed.Agencies),
 	}, errs
 }
 
@@ -32,6 +44,31 @@ type JourneysRepository struct {
 	Routes          *JourneysRoutesRepository
 	Journeys        *JourneysJourneyRepository
 	JourneyPatterns *JourneysJourneyPatternRepository
+	Transfers       *JourneysTransfersRepository
+	ServiceCalendar *ServiceCalendar
+	// Timezone is the agency timezone of the feed. Service days and times of day in the feed are relative to it.
+	Timezone *time.Location
+}
+
+// agencyTimezone returns the timezone of the first agency which has a valid agency_timezone. The specification
+// requires all agencies of a feed to share the timezone. Falls back to the local timezone of the server.
+func agencyTimezone(agencies []*ggtfs.Agency) *time.Location {
+	for _, a := range agencies {
+		if a == nil || ggtfs.StringIsNilOrEmpty(a.Timezone) {
+			continue
+		}
+
+		tz, err := time.LoadLocation(strings.TrimSpace(*a.Timezone))
+		if err != nil {
+			log.Println(fmt.Sprintf("agency (on gtfs line %v): cannot load timezone %v", a.LineNumber, *a.Timezone))
+			continue
+		}
+
+		return tz
+	}
+
+	log.Println("agency timezone not found, using the local timezone of the server")
+	return time.Local
 }
 
 func getBundleErrorsNotices(bundle *GTFSBundle) []error {
@@ -3,8 +3,10 @@ package repository
 import (
 	"fmt"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
 	"github.com/jlundan/journeys-api/pkg/ggtfs"
 	"log"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,6 +17,7 @@ func newRoutesRepository(shapes []*ggtfs.Shape) *JourneysRoutesRepository {
 	var byId = make(map[string]*model.Route)
 
 	var shapeIdToCoords = make(map[string][][]float64)
+	var shapeIdToPoints = make(map[string][]shapeRow)
 	for i, shape := range shapes {
 		if shape == nil {
 			fmt.Println(fmt.Sprintf("Nil shape detected, number %v in the shapes array, newRoutesRepository function", i))
@@ -49,21 +52,50 @@ func newRoutesRepository(shapes []*ggtfs.Shape) *JourneysRoutesRepository {
 			log.Println(fmt.Sprintf("shape (on gtfs line %v): lon is missing", shape.LineNumber))
 		}
 
-		if _, ok := shapeIdToCoords[shapeId]; !ok {
-			shapeIdToCoords[shapeId] = make([][]float64, 0)
+		var sequence int
+		if shape.PtSequence != nil {
+			sequence, _ = strconv.Atoi(strings.TrimSpace(*shape.PtSequence))
 		}
 
-		shapeIdToCoords[shapeId] = append(shapeIdToCoords[shapeId], []float64{lat, lon})
+		shapeIdToPoints[shapeId] = append(shapeIdToPoints[shapeId], shapeRow{
+			sequence:     sequence,
+			coords:       []float64{lat, lon},
+			distTraveled: parseShapeDistTraveled(shape.DistTraveled),
+		})
+	}
+
+	var shapeDistances = make(map[string][]float64)
+
+	for shapeId, points := range shapeIdToPoints {
+		sort.SliceStable(points, func(x, y int) bool {
+			return points[x].sequence < points[y].sequence
+		})
+
+		coords := make([][]float64, 0, len(points))
+		distances := make([]float64, 0, len(points))
+		for _, p := range points {
+			coords = append(coords, p.coords)
+			distances = append(distances, p.distTraveled)
+		}
+
+		if distancesAreIncreasing(distances) {
+			shapeDistances[shapeId] = distances
+		}
+
+		shapeIdToCoords[shapeId] = coords
 	}
 
 	for shapeId, coords := range shapeIdToCoords {
-		projection, err := createCoordinateProjection(coords)
-		if err != nil {
-			continue
+		projection := utils.EncodeCoordinateProjection(coords)
+		shape := make([]model.ShapePoint, 0, len(coords))
+		for _, c := range coords {
+			shape = append(shape, model.ShapePoint{Latitude: c[0], Longitude: c[1]})
 		}
+
 		route := &model.Route{
 			Id:            shapeId,
 			GeoProjection: projection,
+			Shape:         shape,
 			// Line, Name , JourneyPatterns and Journeys are populated while building journeys
 		}
 
@@ -76,36 +108,78 @@ func newRoutesRepository(shapes []*ggtfs.Shape) *JourneysRoutesRepository {
 	})
 
 	return &JourneysRoutesRepository{
-		All:  all,
-		ById: byId,
+		All:            all,
+		ById:           byId,
+		shapeDistances: shapeDistances,
 	}
 }
 
-func createCoordinateProjection(coords [][]float64) (string, error) {
-	if coords == nil || len(coords) == 0 {
-		return "", nil
-	}
+// generatedRouteIdPrefix starts the ids of the routes generated for the trips without a shape. The rest of the id is
+// the id of the journey pattern, so the id stays the same as long as the stop points of the trips do.
+const generatedRouteIdPrefix = "generated-"
 
-	projection := ""
-	var lastLat int64
-	var lastLon int64
+// generatedRoute returns the route of straight segments through the stop points of the journey pattern, adding it to
+// the repository the first time it is asked for.
+func generatedRoute(repository *JourneysRoutesRepository, jp *model.JourneyPattern) *model.Route {
+	id := generatedRouteIdPrefix + jp.Id
+	if route, ok := repository.ById[id]; ok {
+		return route
+	}
 
-	for k, v := range coords {
-		lat := int64(v[0] * 100000)
-		lon := int64(v[1] * 100000)
-		if k == 0 {
-			projection += fmt.Sprintf("%v,%v", lat, lon)
-		} else {
-			projection += fmt.Sprintf(":%v,%v", lastLat-lat, lastLon-lon)
-		}
+	coords := make([][]float64, 0, len(jp.StopPoints))
+	shape := make([]model.ShapePoint, 0, len(jp.StopPoints))
+	for _, sp := range jp.StopPoints {
+		coords = append(coords, []float64{sp.Latitude, sp.Longitude})
+		shape = append(shape, model.ShapePoint{Latitude: sp.Latitude, Longitude: sp.Longitude})
+	}
 
-		lastLat = lat
-		lastLon = lon
+	route := &model.Route{
+		Id:            id,
+		GeoProjection: utils.EncodeCoordinateProjection(coords),
+		Shape:         shape,
 	}
-	return projection, nil
+
+	repository.All = append(repository.All, route)
+	repository.ById[id] = route
+
+	return route
 }
 
 type JourneysRoutesRepository struct {
 	All  []*model.Route
 	ById map[string]*model.Route
+	// shapeDistances are the shape_dist_traveled values of the points of the shapes, by shape id, for the shapes which
+	// have them for every point.
+	shapeDistances map[string][]float64
+}
+
+type shapeRow struct {
+	sequence     int
+	coords       []float64
+	distTraveled float64
+}
+
+// parseShapeDistTraveled parses an optional shape_dist_traveled value, returning NaN if it is missing or malformed.
+func parseShapeDistTraveled(value *string) float64 {
+	if ggtfs.StringIsNilOrEmpty(value) {
+		return math.NaN()
+	}
+
+	d, err := strconv.ParseFloat(strings.TrimSpace(*value), 64)
+	if err != nil {
+		return math.NaN()
+	}
+
+	return d
+}
+
+// distancesAreIncreasing reports whether the shape_dist_traveled values are all present, and do not decrease. The
+// values are in the units of the feed, and only usable for placing stops on a shape when they are complete.
+func distancesAreIncreasing(distances []float64) bool {
+	for i, d := range distances {
+		if math.IsNaN(d) || (i > 0 && d < distances[i-1]) {
+			return false
+		}
+	}
+	return len(distances) > 0
 }
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"math"
+)
+
+// stopPlacement is a journey pattern placed on the shape of a route: the position of each stop point on the shape,
+// and its distance from the first stop point along the shape.
+type stopPlacement struct {
+	stopPoints []*model.StopPoint
+	// coords are the coordinates of the shape, nil if the route has no shape. The distances are then measured along
+	// straight lines between the stop points.
+	coords    [][]float64
+	positions []utils.LinePosition
+	distances []float64
+}
+
+// placeStopPoints places the stop points of the journey pattern on the shape of the route. The shape_dist_traveled
+// values of the calls of a journey are used when both the shape and the calls have them, otherwise the stop points
+// are placed in order on the shape as near to the stop points as possible. callDistances may be nil.
+func placeStopPoints(jp *model.JourneyPattern, route *model.Route, shapeDistances []float64, callDistances []float64) *stopPlacement {
+	result := &stopPlacement{stopPoints: jp.StopPoints, distances: make([]float64, len(jp.StopPoints))}
+
+	if route != nil && len(route.Shape) >= 2 {
+		for _, p := range route.Shape {
+			result.coords = append(result.coords, []float64{p.Latitude, p.Longitude})
+		}
+	}
+
+	if result.coords == nil {
+		for i := 1; i < len(jp.StopPoints); i++ {
+			from, to := jp.StopPoints[i-1], jp.StopPoints[i]
+			result.distances[i] = result.distances[i-1] + ggtfs.HaversineDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+		}
+		return result
+	}
+
+	if len(shapeDistances) == len(result.coords) && len(callDistances) == len(jp.StopPoints) && distancesAreIncreasing(callDistances) {
+		for _, d := range callDistances {
+			result.positions = append(result.positions, utils.LocateByDistance(shapeDistances, d))
+		}
+	} else {
+		stops := make([][]float64, 0, len(jp.StopPoints))
+		for _, sp := range jp.StopPoints {
+			stops = append(stops, []float64{sp.Latitude, sp.Longitude})
+		}
+		result.positions = utils.LocateOnLine(result.coords, stops)
+	}
+
+	lengths := utils.CumulativeLengths(result.coords)
+	for i, p := range result.positions {
+		result.distances[i] = utils.LengthAt(lengths, p) - utils.LengthAt(lengths, result.positions[0])
+	}
+
+	return result
+}
+
+// length returns the distance from the first stop point to the last one.
+func (p *stopPlacement) length() float64 {
+	if len(p.distances) == 0 {
+		return 0
+	}
+	return p.distances[len(p.distances)-1]
+}
+
+// segments cuts the shape at the stop points. Without a shape, the segments are straight lines from a stop point to
+// the next one.
+func (p *stopPlacement) segments() []*model.JourneyPatternSegment {
+	result := make([]*model.JourneyPatternSegment, 0)
+
+	for i := 1; i < len(p.stopPoints); i++ {
+		from, to := p.stopPoints[i-1], p.stopPoints[i]
+
+		var line [][]float64
+		if p.coords != nil {
+			line = utils.CutLine(p.coords, p.positions[i-1], p.positions[i])
+		} else {
+			line = [][]float64{{from.Latitude, from.Longitude}, {to.Latitude, to.Longitude}}
+		}
+
+		shape := make([]model.ShapePoint, 0, len(line))
+		for _, c := range line {
+			shape = append(shape, model.ShapePoint{Latitude: c[0], Longitude: c[1]})
+		}
+
+		result = append(result, &model.JourneyPatternSegment{
+			From:     from,
+			To:       to,
+			Shape:    shape,
+			Distance: math.Max(0, p.distances[i]-p.distances[i-1]),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"math"
+	"testing"
+)
+
+func TestStopPlacement_Segments(t *testing.T) {
+	a := &model.StopPoint{ShortName: "A", Latitude: 61.0001, Longitude: 23.0}
+	b := &model.StopPoint{ShortName: "B", Latitude: 61.0001, Longitude: 23.01}
+	c := &model.StopPoint{ShortName: "C", Latitude: 61.0, Longitude: 23.02}
+
+	straight := &model.Route{Shape: testShape([2]float64{61.0, 23.0}, [2]float64{61.0, 23.005}, [2]float64{61.0, 23.02})}
+	loop := &model.Route{Shape: testShape([2]float64{61.0, 23.0}, [2]float64{61.0, 23.01}, [2]float64{61.0002, 23.01}, [2]float64{61.0002, 23.0})}
+
+	testCases := []struct {
+		id       string
+		jp       *model.JourneyPattern
+		expected []string
+	}{
+		{"shape", &model.JourneyPattern{StopPoints: []*model.StopPoint{a, b, c}, Route: straight}, []string{
+			"A-B [{61 23} {61 23.005} {61 23.01}] 539",
+			"B-C [{61 23.01} {61 23.02}] 539",
+		}},
+		// The loop passes A twice, the first stop at A is placed at the start and the second one on the way back.
+		{"loop", &model.JourneyPattern{StopPoints: []*model.StopPoint{a, b, a}, Route: loop}, []string{
+			"A-B [{61 23} {61 23.01} {61.0001 23.01}] 550",
+			"B-A [{61.0001 23.01} {61.0002 23.01} {61.0002 23}] 550",
+		}},
+		{"no-shape", &model.JourneyPattern{StopPoints: []*model.StopPoint{a, c}, Route: &model.Route{}}, []string{
+			"A-C [{61.0001 23} {61 23.02}] 1078",
+		}},
+		{"one-stop", &model.JourneyPattern{StopPoints: []*model.StopPoint{a}, Route: straight}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		segments := make([]string, 0)
+		for _, s := range placeStopPoints(tc.jp, tc.jp.Route, nil, nil).segments() {
+			segments = append(segments, fmt.Sprintf("%v-%v %v %v", s.From.ShortName, s.To.ShortName, s.Shape, math.Round(s.Distance)))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, segments, tc.id)
+	}
+}
+
+func TestPlaceStopPoints(t *testing.T) {
+	a := &model.StopPoint{ShortName: "A", Latitude: 61.0, Longitude: 23.0}
+	b := &model.StopPoint{ShortName: "B", Latitude: 61.001, Longitude: 23.015}
+	c := &model.StopPoint{ShortName: "C", Latitude: 61.0, Longitude: 23.02}
+
+	jp := &model.JourneyPattern{StopPoints: []*model.StopPoint{a, b, c}}
+	route := &model.Route{Shape: testShape([2]float64{61.0, 23.0}, [2]float64{61.0, 23.01}, [2]float64{61.0, 23.02})}
+
+	testCases := []struct {
+		id             string
+		route          *model.Route
+		shapeDistances []float64
+		callDistances  []float64
+		expected       []float64
+	}{
+		// B is off the shape, nearest to the point 23.015.
+		{"geometry", route, nil, nil, []float64{0, 809, 1078}},
+		// The feed places B at the second point of the shape, in kilometers.
+		{"shape-dist-traveled", route, []float64{0, 0.54, 1.08}, []float64{0, 0.54, 1.08}, []float64{0, 539, 1078}},
+		{"incomplete-shape-dist-traveled", route, []float64{0, 0.54, 1.08}, []float64{0, math.NaN(), 1.08}, []float64{0, 809, 1078}},
+		{"no-shape", &model.Route{}, nil, nil, []float64{0, 816, 1108}},
+	}
+
+	for _, tc := range testCases {
+		placement := placeStopPoints(jp, tc.route, tc.shapeDistances, tc.callDistances)
+
+		distances := make([]float64, 0)
+		for _, d := range placement.distances {
+			distances = append(distances, math.Round(d))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, distances, tc.id)
+		testutil.CompareVariablesAndPrintResults(t, tc.expected[len(tc.expected)-1], math.Round(placement.length()), tc.id)
+	}
+}
+
+func testShape(coords ...[2]float64) []model.ShapePoint {
+	shape := make([]model.ShapePoint, 0, len(coords))
+	for _, c := range coords {
+		shape = append(shape, model.ShapePoint{Latitude: c[0], Longitude: c[1]})
+	}
+	return shape
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServiceCalendar answers which dates every service_id operates on, according to calendar.txt and
+// calendar_dates.txt. The weekly patterns of calendar.txt are evaluated when asked, only the exception dates of
+// calendar_dates.txt are stored, so the size of the calendar does not grow with the length of the periods. Dates are
+// calendar dates without a time zone: only the year, month and day of the times passed to the methods are used.
+type ServiceCalendar struct {
+	services   map[string]*serviceDates
+	serviceIds []string
+}
+
+// serviceDates is the weekly pattern of a service and its exceptions. Dates are stored as keys returned by dateKey.
+type serviceDates struct {
+	periods []servicePeriod
+	added   map[int]struct{}
+	removed map[int]struct{}
+}
+
+type servicePeriod struct {
+	weekdays   [7]bool
+	start, end time.Time
+}
+
+// NewServiceCalendar builds the calendar of the services defined in calendarItems and calendarDates.
+func NewServiceCalendar(calendarItems []*ggtfs.CalendarItem, calendarDates []*ggtfs.CalendarDate) *ServiceCalendar {
+	calendar := &ServiceCalendar{services: make(map[string]*serviceDates)}
+
+	for i, ci := range calendarItems {
+		if ci == nil {
+			log.Println(fmt.Sprintf("Nil calendar item detected, number %v in the calendarItems array, NewServiceCalendar function", i))
+			continue
+		}
+
+		if ci.ServiceId == nil || ci.StartDate == nil || ci.EndDate == nil {
+			log.Println(fmt.Sprintf("malformed calendar item, GTFS row: %v", ci.LineNumber))
+			continue
+		}
+
+		startDate, err := time.Parse("20060102", strings.TrimSpace(*ci.StartDate))
+		if err != nil {
+			log.Println(fmt.Sprintf("Error parsing start date for calendar item, GTFS row: %v", ci.LineNumber))
+			continue
+		}
+
+		endDate, err := time.Parse("20060102", strings.TrimSpace(*ci.EndDate))
+		if err != nil {
+			log.Println(fmt.Sprintf("Error parsing end date for calendar item, GTFS row: %v", ci.LineNumber))
+			continue
+		}
+
+		period := servicePeriod{start: startDate, end: endDate}
+		for d, v := range []*string{ci.Sunday, ci.Monday, ci.Tuesday, ci.Wednesday, ci.Thursday, ci.Friday, ci.Saturday} {
+			period.weekdays[d] = v != nil && strings.TrimSpace(*v) == "1"
+		}
+
+		service := calendar.service(strings.TrimSpace(*ci.ServiceId))
+		service.periods = append(service.periods, period)
+	}
+
+	// Exceptions are applied after all calendar rows, so that their order in the files does not matter.
+	for i, cd := range calendarDates {
+		if cd == nil {
+			log.Println(fmt.Sprintf("Nil calendar date detected, number %v in the calendarDates array, NewServiceCalendar function", i))
+			continue
+		}
+
+		if cd.ServiceId == nil || cd.Date == nil || cd.ExceptionType == nil {
+			log.Println(fmt.Sprintf("malformed calendar date, GTFS row: %v", cd.LineNumber))
+			continue
+		}
+
+		date, err := time.Parse("20060102", strings.TrimSpace(*cd.Date))
+		if err != nil {
+			log.Println(fmt.Sprintf("Error parsing date for calendar date, GTFS row: %v", cd.LineNumber))
+			continue
+		}
+
+		service := calendar.service(strings.TrimSpace(*cd.ServiceId))
+
+		switch strings.TrimSpace(*cd.ExceptionType) {
+		case "1":
+			service.added[dateKey(date)] = struct{}{}
+			delete(service.removed, dateKey(date))
+		case "2":
+			service.removed[dateKey(date)] = struct{}{}
+			delete(service.added, dateKey(date))
+		}
+	}
+
+	for id := range calendar.services {
+		calendar.serviceIds = append(calendar.serviceIds, id)
+	}
+	sort.Strings(calendar.serviceIds)
+
+	return calendar
+}
+
+func (c *ServiceCalendar) service(serviceId string) *serviceDates {
+	service, ok := c.services[serviceId]
+	if !ok {
+		service = &serviceDates{added: make(map[int]struct{}), removed: make(map[int]struct{})}
+		c.services[serviceId] = service
+	}
+	return service
+}
+
+// RunsOn reports whether the service operates on the date.
+func (c *ServiceCalendar) RunsOn(serviceId string, date time.Time) bool {
+	service, ok := c.services[serviceId]
+	return ok && service.runsOn(date)
+}
+
+// Dates returns the operating dates of the service between from and to, both included, in ascending order as
+// midnight UTC.
+func (c *ServiceCalendar) Dates(serviceId string, from time.Time, to time.Time) []time.Time {
+	service, ok := c.services[serviceId]
+	if !ok {
+		return nil
+	}
+
+	var dates []time.Time
+	for d := dateFromKey(dateKey(from)); !d.After(dateFromKey(dateKey(to))); d = d.AddDate(0, 0, 1) {
+		if service.runsOn(d) {
+			dates = append(dates, d)
+		}
+	}
+
+	return dates
+}
+
+// ServicesOn returns the sorted ids of the services operating on the date.
+func (c *ServiceCalendar) ServicesOn(date time.Time) []string {
+	var ids []string
+	for _, id := range c.serviceIds {
+		if c.services[id].runsOn(date) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Range returns the first and the last operating date of the service. The result is false if the service
+// never operates.
+func (c *ServiceCalendar) Range(serviceId string) (time.Time, time.Time, bool) {
+	service, ok := c.services[serviceId]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	first, firstFound := service.firstDate()
+	last, lastFound := service.lastDate()
+	if !firstFound || !lastFound {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return first, last, true
+}
+
+func (s *serviceDates) runsOn(date time.Time) bool {
+	key := dateKey(date)
+	if _, ok := s.added[key]; ok {
+		return true
+	}
+	if _, ok := s.removed[key]; ok {
+		return false
+	}
+
+	d := dateFromKey(key)
+	for _, p := range s.periods {
+		if !d.Before(p.start) && !d.After(p.end) && p.weekdays[d.Weekday()] {
+			return true
+		}
+	}
+	return false
+}
+
+// firstDate looks for the first operating date. Every period is walked forward from its start until the first date
+// which is not removed, so the walk is bounded by the number of exceptions rather than the length of the period.
+func (s *serviceDates) firstDate() (time.Time, bool) {
+	var first time.Time
+	found := false
+
+	for key := range s.added {
+		if d := dateFromKey(key); !found || d.Before(first) {
+			first, found = d, true
+		}
+	}
+
+	for _, p := range s.periods {
+		if d, ok := s.walkPeriod(p, p.start, 1); ok && (!found || d.Before(first)) {
+			first, found = d, true
+		}
+	}
+
+	return first, found
+}
+
+// lastDate is the counterpart of firstDate, walking the periods backward from their end.
+func (s *serviceDates) lastDate() (time.Time, bool) {
+	var last time.Time
+	found := false
+
+	for key := range s.added {
+		if d := dateFromKey(key); !found || d.After(last) {
+			last, found = d, true
+		}
+	}
+
+	for _, p := range s.periods {
+		if d, ok := s.walkPeriod(p, p.end, -1); ok && (!found || d.After(last)) {
+			last, found = d, true
+		}
+	}
+
+	return last, found
+}
+
+// walkPeriod returns the first date of the period, starting from the given date in the given direction, on which
+// the weekly pattern applies and which is not removed.
+func (s *serviceDates) walkPeriod(p servicePeriod, from time.Time, step int) (time.Time, bool) {
+	if p.weekdays == [7]bool{} {
+		return time.Time{}, false
+	}
+
+	for d := from; !d.Before(p.start) && !d.After(p.end); d = d.AddDate(0, 0, step) {
+		if _, removed := s.removed[dateKey(d)]; !removed && p.weekdays[d.Weekday()] {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func dateKey(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+func dateFromKey(k int) time.Time {
+	return time.Date(k/10000, time.Month(k/100%100), k%100, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func calendarItem(serviceId string, days string, startDate string, endDate string) *ggtfs.CalendarItem {
+	d := strings.Split(days, "")
+	return &ggtfs.CalendarItem{
+		ServiceId: strPtr(serviceId),
+		Monday:    strPtr(d[0]),
+		Tuesday:   strPtr(d[1]),
+		Wednesday: strPtr(d[2]),
+		Thursday:  strPtr(d[3]),
+		Friday:    strPtr(d[4]),
+		Saturday:  strPtr(d[5]),
+		Sunday:    strPtr(d[6]),
+		StartDate: strPtr(startDate),
+		EndDate:   strPtr(endDate),
+	}
+}
+
+func calendarDate(serviceId string, date string, exceptionType string) *ggtfs.CalendarDate {
+	return &ggtfs.CalendarDate{ServiceId: strPtr(serviceId), Date: strPtr(date), ExceptionType: strPtr(exceptionType)}
+}
+
+func TestServiceCalendarRunsOn(t *testing.T) {
+	calendar := NewServiceCalendar(
+		[]*ggtfs.CalendarItem{
+			calendarItem("WD", "1111100", "20250101", "20250131"),
+			calendarItem("SAT", "0000010", "20250101", "20250131"),
+			calendarItem("BROKEN", "1111111", "2025-01-01", "20250131"),
+		},
+		[]*ggtfs.CalendarDate{
+			calendarDate("WD", "20250106", "2"),
+			calendarDate("SAT", "20250106", "1"),
+			calendarDate("XMAS", "20251225", "1"),
+			calendarDate("WD", "20250301", "1"),
+		},
+	)
+
+	tests := []struct {
+		serviceId string
+		date      string
+		expected  bool
+	}{
+		{"WD", "2025-01-01", true},
+		{"WD", "2025-01-04", false},
+		{"WD", "2025-01-06", false},
+		{"WD", "2025-01-07", true},
+		{"WD", "2025-01-31", true},
+		{"WD", "2025-02-03", false},
+		{"WD", "2025-03-01", true},
+		{"SAT", "2025-01-04", true},
+		{"SAT", "2025-01-06", true},
+		{"SAT", "2025-01-07", false},
+		{"XMAS", "2025-12-25", true},
+		{"XMAS", "2025-12-24", false},
+		{"BROKEN", "2025-01-07", false},
+		{"UNKNOWN", "2025-01-07", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%s", tt.serviceId, tt.date), func(t *testing.T) {
+			date, _ := time.Parse("2006-01-02", tt.date)
+			if calendar.RunsOn(tt.serviceId, date) != tt.expected {
+				t.Errorf("expected RunsOn(%v, %v) to be %v", tt.serviceId, tt.date, tt.expected)
+			}
+		})
+	}
+
+	if services := strings.Join(calendar.ServicesOn(time.Date(2025, 1, 6, 12, 0, 0, 0, time.Local)), ","); services != "SAT" {
+		t.Errorf("expected only SAT to run on 2025-01-06, got %v", services)
+	}
+
+	first, last, ok := calendar.Range("WD")
+	if !ok || first.Format("20060102") != "20250101" || last.Format("20060102") != "20250301" {
+		t.Errorf("unexpected range for WD: %v - %v", first, last)
+	}
+
+	if dates := calendar.Dates("XMAS", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)); len(dates) != 1 || !dates[0].Equal(time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected a single date for XMAS, got %v", dates)
+	}
+
+	if _, _, ok := calendar.Range("UNKNOWN"); ok {
+		t.Error("expected no range for an unknown service")
+	}
+}
+
+func TestServiceCalendarLongPeriod(t *testing.T) {
+	// A period of thousands of years is evaluated when asked instead of being expanded into dates.
+	calendar := NewServiceCalendar(
+		[]*ggtfs.CalendarItem{calendarItem("ALWAYS", "1111100", "19000101", "99991231")},
+		[]*ggtfs.CalendarDate{
+			calendarDate("ALWAYS", "19000101", "2"),
+			calendarDate("ALWAYS", "99991231", "2"),
+			calendarDate("ALWAYS", "20250104", "1"),
+		},
+	)
+
+	if !calendar.RunsOn("ALWAYS", time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)) || calendar.RunsOn("ALWAYS", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected ALWAYS to run on Friday 2025-01-03 but not on Sunday 2025-01-05")
+	}
+
+	first, last, ok := calendar.Range("ALWAYS")
+	if !ok || first.Format("20060102") != "19000102" || last.Format("20060102") != "99991230" {
+		t.Errorf("unexpected range for ALWAYS: %v - %v", first, last)
+	}
+
+	dates := calendar.Dates("ALWAYS", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC))
+	if len(dates) != 6 {
+		t.Errorf("expected five weekdays and the added Saturday in the first week of 2025, got %v", dates)
+	}
+}
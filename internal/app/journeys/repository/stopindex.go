@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"math"
+	"sort"
+)
+
+// stopIndexCellSize is the size of the grid cells in degrees, about 1.1 km in latitude.
+const stopIndexCellSize = 0.01
+
+const metersPerDegreeLatitude = 111320.0
+
+// StopPointIndex is a grid index of the stop points by location.
+type StopPointIndex struct {
+	cells map[stopIndexCell][]*model.StopPoint
+}
+
+type stopIndexCell struct {
+	lat int
+	lon int
+}
+
+func NewStopPointIndex(stopPoints []*model.StopPoint) *StopPointIndex {
+	index := &StopPointIndex{cells: make(map[stopIndexCell][]*model.StopPoint)}
+
+	for _, sp := range stopPoints {
+		cell := cellOf(sp.Latitude, sp.Longitude)
+		index.cells[cell] = append(index.cells[cell], sp)
+	}
+
+	return index
+}
+
+// Within returns the stop points within radius meters of the location, nearest first.
+func (i *StopPointIndex) Within(lat float64, lon float64, radius float64) []model.NearbyStopPoint {
+	result := make([]model.NearbyStopPoint, 0)
+
+	latDelta := radius / metersPerDegreeLatitude
+	// Near the poles the longitude delta grows without bounds, so the search covers every longitude there.
+	lonDelta := 180.0
+	if c := math.Cos(lat * math.Pi / 180); c > 0.01 {
+		lonDelta = math.Min(180, radius/(metersPerDegreeLatitude*c))
+	}
+
+	minCell := cellOf(lat-latDelta, lon-lonDelta)
+	maxCell := cellOf(lat+latDelta, lon+lonDelta)
+
+	for cellLat := minCell.lat; cellLat <= maxCell.lat; cellLat++ {
+		for cellLon := minCell.lon; cellLon <= maxCell.lon; cellLon++ {
+			for _, sp := range i.cells[stopIndexCell{lat: cellLat, lon: cellLon}] {
+				if d := ggtfs.HaversineDistance(lat, lon, sp.Latitude, sp.Longitude); d <= radius {
+					result = append(result, model.NearbyStopPoint{StopPoint: sp, Distance: d})
+				}
+			}
+		}
+	}
+
+	sort.Slice(result, func(x, y int) bool {
+		if result[x].Distance != result[y].Distance {
+			return result[x].Distance < result[y].Distance
+		}
+		return result[x].StopPoint.ShortName < result[y].StopPoint.ShortName
+	})
+
+	return result
+}
+
+func cellOf(lat float64, lon float64) stopIndexCell {
+	return stopIndexCell{lat: int(math.Floor(lat / stopIndexCellSize)), lon: int(math.Floor(lon / stopIndexCellSize))}
+}
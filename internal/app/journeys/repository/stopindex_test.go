@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"math"
+	"testing"
+)
+
+func TestStopPointIndex_Within(t *testing.T) {
+	stopPoints := []*model.StopPoint{
+		{ShortName: "3615", Latitude: 61.4445, Longitude: 23.87235},
+		{ShortName: "3607", Latitude: 61.44173, Longitude: 23.86961},
+		{ShortName: "7017", Latitude: 61.46546, Longitude: 23.64219},
+		// On the other side of a grid cell boundary from the query locations below.
+		{ShortName: "B1", Latitude: 61.40001, Longitude: 23.8},
+		{ShortName: "B2", Latitude: 61.39999, Longitude: 23.8},
+	}
+
+	index := NewStopPointIndex(stopPoints)
+
+	tests := []struct {
+		name     string
+		lat      float64
+		lon      float64
+		radius   float64
+		expected []string
+	}{
+		{"exact", 61.4445, 23.87235, 1, []string{"3615"}},
+		{"nearest-first", 61.4445, 23.87235, 500, []string{"3615", "3607"}},
+		{"nearest-first-reversed", 61.44173, 23.86961, 500, []string{"3607", "3615"}},
+		{"far", 61.4445, 23.87235, 20000, []string{"3615", "3607", "B1", "B2", "7017"}},
+		{"across-cells", 61.4, 23.8, 10, []string{"B2", "B1"}},
+		{"nothing", 60.1699, 24.9384, 1000, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := index.Within(tt.lat, tt.lon, tt.radius)
+
+			ids := make([]string, 0)
+			previous := 0.0
+			for _, n := range result {
+				ids = append(ids, n.StopPoint.ShortName)
+				if n.Distance < previous || n.Distance > tt.radius {
+					t.Errorf("unexpected distance %v for %v", n.Distance, n.StopPoint.ShortName)
+				}
+				previous = n.Distance
+			}
+
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+
+	if d := index.Within(61.4445, 23.87235, 500)[1].Distance; math.Round(d) != 341 {
+		t.Errorf("expected a distance of 341 m, got %v", d)
+	}
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores of a query word matching a word of a stop point name. The score of a stop point is the sum of the scores
+// of the query words, and every query word has to match.
+const (
+	exactWordScore  = 1.0
+	prefixWordScore = 0.75
+	typoWordScore   = 0.5
+	typoPenalty     = 0.1
+	// Bonuses for a query matching the beginning of the name, or the whole name.
+	namePrefixBonus = 0.5
+	exactNameBonus  = 1.0
+)
+
+// StopPointNameIndex is a search index of the stop point names and short names. Names are matched word by word,
+// ignoring case and diacritics, so "hameenk" finds "Hämeenkatu". Words may be prefixes of the name words, and
+// longer words may contain typos.
+type StopPointNameIndex struct {
+	// words holds the distinct words of the names in ascending order, for the prefix searches.
+	words       []string
+	byWord      map[string][]*model.StopPoint
+	foldedNames map[*model.StopPoint]string
+}
+
+func NewStopPointNameIndex(stopPoints []*model.StopPoint) *StopPointNameIndex {
+	index := &StopPointNameIndex{
+		byWord:      make(map[string][]*model.StopPoint),
+		foldedNames: make(map[*model.StopPoint]string),
+	}
+
+	for _, sp := range stopPoints {
+		name := utils.FoldString(sp.Name)
+		index.foldedNames[sp] = name
+
+		seen := make(map[string]bool)
+		for _, w := range append(searchWords(name), utils.FoldString(sp.ShortName)) {
+			if w == "" || seen[w] {
+				continue
+			}
+			seen[w] = true
+
+			if _, ok := index.byWord[w]; !ok {
+				index.words = append(index.words, w)
+			}
+			index.byWord[w] = append(index.byWord[w], sp)
+		}
+	}
+
+	sort.Strings(index.words)
+
+	return index
+}
+
+// Search returns the stop points matching the query, the most relevant first. At most limit matches are returned.
+func (i *StopPointNameIndex) Search(query string, limit int) []model.StopPointMatch {
+	result := make([]model.StopPointMatch, 0)
+
+	folded := utils.FoldString(strings.TrimSpace(query))
+	queryWords := searchWords(folded)
+	if len(queryWords) == 0 {
+		return result
+	}
+
+	scores := make(map[*model.StopPoint]float64)
+	for n, qw := range queryWords {
+		best := make(map[*model.StopPoint]float64)
+		for w, score := range i.matchingWords(qw) {
+			for _, sp := range i.byWord[w] {
+				if score > best[sp] {
+					best[sp] = score
+				}
+			}
+		}
+
+		for sp, score := range best {
+			// Every query word has to match, so only the stop points matched by the previous words remain.
+			if previous, ok := scores[sp]; ok || n == 0 {
+				scores[sp] = previous + score
+			}
+		}
+		for sp := range scores {
+			if _, ok := best[sp]; !ok {
+				delete(scores, sp)
+			}
+		}
+	}
+
+	for sp, score := range scores {
+		name := i.foldedNames[sp]
+		if name == folded {
+			score += exactNameBonus
+		} else if strings.HasPrefix(name, folded) {
+			score += namePrefixBonus
+		}
+		result = append(result, model.StopPointMatch{StopPoint: sp, Score: score})
+	}
+
+	sort.Slice(result, func(x, y int) bool {
+		if result[x].Score != result[y].Score {
+			return result[x].Score > result[y].Score
+		}
+		if len(result[x].StopPoint.Name) != len(result[y].StopPoint.Name) {
+			return len(result[x].StopPoint.Name) < len(result[y].StopPoint.Name)
+		}
+		if result[x].StopPoint.Name != result[y].StopPoint.Name {
+			return result[x].StopPoint.Name < result[y].StopPoint.Name
+		}
+		return result[x].StopPoint.ShortName < result[y].StopPoint.ShortName
+	})
+
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// matchingWords returns the indexed words matching the query word with their scores.
+func (i *StopPointNameIndex) matchingWords(queryWord string) map[string]float64 {
+	result := make(map[string]float64)
+
+	start := sort.SearchStrings(i.words, queryWord)
+	for _, w := range i.words[start:] {
+		if !strings.HasPrefix(w, queryWord) {
+			break
+		}
+		if w == queryWord {
+			result[w] = exactWordScore
+		} else {
+			result[w] = prefixWordScore
+		}
+	}
+
+	allowedTypos := allowedTypos(queryWord)
+	if allowedTypos == 0 {
+		return result
+	}
+
+	queryRunes := []rune(queryWord)
+	for _, w := range i.words {
+		if _, ok := result[w]; ok {
+			continue
+		}
+
+		wordRunes := []rune(w)
+		if len(wordRunes) < len(queryRunes)-allowedTypos {
+			continue
+		}
+
+		distance := utils.EditDistance(queryWord, w)
+		// The query word may also be the beginning of the word, typed with typos.
+		if len(wordRunes) > len(queryRunes) {
+			distance = min(distance, utils.EditDistance(queryWord, string(wordRunes[:len(queryRunes)])))
+		}
+
+		if distance <= allowedTypos {
+			result[w] = typoWordScore - typoPenalty*float64(distance-1)
+		}
+	}
+
+	return result
+}
+
+// allowedTypos returns the number of typos tolerated in the query word. Short words have to match exactly, as
+// almost any short word is within a typo or two of another one.
+func allowedTypos(queryWord string) int {
+	switch n := len([]rune(queryWord)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"strings"
+	"testing"
+)
+
+func TestStopPointNameIndex_Search(t *testing.T) {
+	index := NewStopPointNameIndex([]*model.StopPoint{
+		{ShortName: "0001", Name: "Keskustori H"},
+		{ShortName: "0002", Name: "Keskustori G"},
+		{ShortName: "0010", Name: "Hämeenkatu 10"},
+		{ShortName: "0020", Name: "Hämeenpuisto"},
+		{ShortName: "3615", Name: "Näyttelijänkatu"},
+		{ShortName: "0030", Name: "Pyynikintori"},
+		{ShortName: "0031", Name: "Pyynikki"},
+		{ShortName: "0040", Name: "Itsenäisyydenkatu"},
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		limit    int
+		expected string
+	}{
+		{"diacritics", "Hameenkatu", 10, "0010"},
+		{"case", "HÄMEENKATU", 10, "0010"},
+		{"prefix", "hameen", 10, "0020 0010"},
+		{"exact-name-first", "keskustori h", 10, "0001"},
+		{"autocomplete", "keskustori", 10, "0002 0001"},
+		{"typo", "hamenkatu", 10, "0010"},
+		{"transposition", "naytetlijankatu", 10, "3615"},
+		{"typo-in-prefix", "pyyniik", 10, "0031 0030"},
+		{"exact-before-typo", "pyynikki", 10, "0031 0030"},
+		{"short-name", "3615", 10, "3615"},
+		{"all-words-must-match", "hameenkatu keskustori", 10, ""},
+		{"short-words-are-not-fuzzy", "pyy", 10, "0031 0030"},
+		{"limit", "keskustori", 1, "0002"},
+		{"no-match", "helsinki", 10, ""},
+		{"empty", "  ", 10, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, m := range index.Search(tt.query, tt.limit) {
+				ids = append(ids, m.StopPoint.ShortName)
+			}
+
+			if got := strings.Join(ids, " "); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -57,15 +57,21 @@ func newStopPointsRepository(stops []*ggtfs.Stop, municipalityDataStore *Journey
 			log.Println(fmt.Sprintf("stop-point (on gtfs line %v): tariffZone is missing", stop.LineNumber))
 		}
 
+		var platformCode string
+		if stop.PlatformCode != nil {
+			platformCode = strings.TrimSpace(*stop.PlatformCode)
+		}
+
 		s := model.StopPoint{
-			Name:       name,
-			ShortName:  shortName,
-			Latitude:   math.Round(lat*100000) / 100000,
-			Longitude:  math.Round(lon*100000) / 100000,
-			TariffZone: tariffZone,
+			Name:         name,
+			ShortName:    shortName,
+			Latitude:     math.Round(lat*100000) / 100000,
+			Longitude:    math.Round(lon*100000) / 100000,
+			TariffZone:   tariffZone,
+			PlatformCode: platformCode,
 		}
 
-		if !ggtfs.StringIsNilOrEmpty(stop.Extensions.MunicipalityId) {
+		if stop.Extensions != nil && !ggtfs.StringIsNilOrEmpty(stop.Extensions.MunicipalityId) {
 			if m, ok := municipalityDataStore.ById[*stop.Extensions.MunicipalityId]; ok {
 				s.Municipality = m
 			} else {
@@ -83,12 +89,16 @@ func newStopPointsRepository(stops []*ggtfs.Stop, municipalityDataStore *Journey
 	})
 
 	return &JourneysStopPointsRepository{
-		All:  all,
-		ById: byId,
+		All:       all,
+		ById:      byId,
+		Index:     NewStopPointIndex(all),
+		NameIndex: NewStopPointNameIndex(all),
 	}
 }
 
 type JourneysStopPointsRepository struct {
-	All  []*model.StopPoint
-	ById map[string]*model.StopPoint
+	All       []*model.StopPoint
+	ById      map[string]*model.StopPoint
+	Index     *StopPointIndex
+	NameIndex *StopPointNameIndex
 }
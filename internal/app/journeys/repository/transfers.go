@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newTransfersRepository builds the transfer rules between stop points. Rules for specific routes or trips are
+// skipped, since the planner changes journeys by stop point only.
+func newTransfersRepository(transfers []*ggtfs.Transfer, stopPoints *JourneysStopPointsRepository) *JourneysTransfersRepository {
+	var all = make([]*model.Transfer, 0)
+
+	for _, t := range transfers {
+		if t == nil || ggtfs.StringIsNilOrEmpty(t.FromStopId) || ggtfs.StringIsNilOrEmpty(t.ToStopId) {
+			continue
+		}
+
+		if !ggtfs.StringIsNilOrEmpty(t.FromRouteId) || !ggtfs.StringIsNilOrEmpty(t.ToRouteId) ||
+			!ggtfs.StringIsNilOrEmpty(t.FromTripId) || !ggtfs.StringIsNilOrEmpty(t.ToTripId) {
+			continue
+		}
+
+		from, okFrom := stopPoints.ById[strings.TrimSpace(*t.FromStopId)]
+		to, okTo := stopPoints.ById[strings.TrimSpace(*t.ToStopId)]
+		if !okFrom || !okTo {
+			log.Println(fmt.Sprintf("transfer (on gtfs line %v): stop point not found", t.LineNumber))
+			continue
+		}
+
+		transfer := &model.Transfer{From: from, To: to}
+
+		var transferType string
+		if t.TransferType != nil {
+			transferType = strings.TrimSpace(*t.TransferType)
+		}
+
+		switch transferType {
+		case "", "0":
+		case "1":
+			// A timed transfer waits for the arriving journey.
+			minTransferTime := time.Duration(0)
+			transfer.MinTransferTime = &minTransferTime
+		case "2":
+			if ggtfs.StringIsNilOrEmpty(t.MinTransferTime) {
+				log.Println(fmt.Sprintf("transfer (on gtfs line %v): min_transfer_time is missing", t.LineNumber))
+				continue
+			}
+			seconds, err := strconv.Atoi(strings.TrimSpace(*t.MinTransferTime))
+			if err != nil || seconds < 0 {
+				log.Println(fmt.Sprintf("transfer (on gtfs line %v): cannot parse min_transfer_time", t.LineNumber))
+				continue
+			}
+			minTransferTime := time.Duration(seconds) * time.Second
+			transfer.MinTransferTime = &minTransferTime
+		case "3":
+			transfer.Forbidden = true
+		default:
+			// In-seat transfers and other journey specific types do not apply between stop points.
+			continue
+		}
+
+		all = append(all, transfer)
+	}
+
+	return &JourneysTransfersRepository{
+		All: all,
+	}
+}
+
+type JourneysTransfersRepository struct {
+	All []*model.Transfer
+}
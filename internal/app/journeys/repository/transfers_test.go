@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"testing"
+)
+
+func TestNewTransfersRepository(t *testing.T) {
+	stopPoints := &JourneysStopPointsRepository{ById: map[string]*model.StopPoint{
+		"S1": {ShortName: "S1"},
+		"S2": {ShortName: "S2"},
+	}}
+
+	transfer := func(from string, to string, transferType string, minTransferTime string) *ggtfs.Transfer {
+		return &ggtfs.Transfer{FromStopId: strPtr(from), ToStopId: strPtr(to), TransferType: strPtr(transferType), MinTransferTime: strPtr(minTransferTime)}
+	}
+
+	routeSpecific := transfer("S1", "S2", "3", "")
+	routeSpecific.FromRouteId = strPtr("R1")
+
+	repo := newTransfersRepository([]*ggtfs.Transfer{
+		transfer("S1", "S2", "0", ""),
+		transfer("S1", "S2", "1", ""),
+		transfer("S1", "S2", "2", "180"),
+		transfer("S2", "S2", "3", ""),
+		transfer("S1", "S2", "2", ""),
+		transfer("S1", "S2", "4", ""),
+		transfer("S1", "S3", "3", ""),
+		routeSpecific,
+	}, stopPoints)
+
+	format := func(t *model.Transfer) string {
+		minTransferTime := "-"
+		if t.MinTransferTime != nil {
+			minTransferTime = t.MinTransferTime.String()
+		}
+		return fmt.Sprintf("%v-%v forbidden=%v min=%v", t.From.ShortName, t.To.ShortName, t.Forbidden, minTransferTime)
+	}
+
+	expected := []string{
+		"S1-S2 forbidden=false min=-",
+		"S1-S2 forbidden=false min=0s",
+		"S1-S2 forbidden=false min=3m0s",
+		"S2-S2 forbidden=true min=-",
+	}
+
+	if len(repo.All) != len(expected) {
+		t.Fatalf("expected %v transfers, got %v", len(expected), len(repo.All))
+	}
+	for i, tr := range repo.All {
+		if got := format(tr); got != expected[i] {
+			t.Errorf("transfer %v: expected %v, got %v", i, expected[i], got)
+		}
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/bradfitz/gomemcache/memcache"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
-func NewMemcachedCacheMiddleware(client *memcache.Client, shortCacheDuration time.Duration, longCacheDuration time.Duration, shortCachePeriodLowerBound int, shortCachePeriodUpperBound int) (*MemcachedCacheMiddleware, error) {
+// NewMemcachedCacheMiddleware creates the cache middleware. The short cache period bounds are hours in timezone,
+// which should be the timezone of the agency so that the period follows the service day. A nil timezone uses the
+// local timezone of the server.
+func NewMemcachedCacheMiddleware(client *memcache.Client, shortCacheDuration time.Duration, longCacheDuration time.Duration, shortCachePeriodLowerBound int, shortCachePeriodUpperBound int, timezone *time.Location) (*MemcachedCacheMiddleware, error) {
 	if os.Getenv("MEMCACHED_URL") == "" {
 		return nil, errors.New("MEMCACHED_URL not set in environment, but memcached is configured. Cannot proceed")
 	}
@@ -19,6 +23,7 @@ func NewMemcachedCacheMiddleware(client *memcache.Client, shortCacheDuration tim
 		longCacheDuration:          longCacheDuration,
 		shortCachePeriodLowerBound: shortCachePeriodLowerBound,
 		shortCachePeriodUpperBound: shortCachePeriodUpperBound,
+		timezone:                   timezone,
 	}, nil
 }
 
@@ -28,6 +33,7 @@ type MemcachedCacheMiddleware struct {
 	longCacheDuration          time.Duration
 	shortCachePeriodLowerBound int
 	shortCachePeriodUpperBound int
+	timezone                   *time.Location
 }
 
 func (mcm *MemcachedCacheMiddleware) Flush() error {
@@ -40,12 +46,16 @@ func (mcm *MemcachedCacheMiddleware) Flush() error {
 
 func (mcm *MemcachedCacheMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := r.URL.String()
+		key := cacheKey(r)
 
 		item, err := mcm.client.Get(key)
 		if err == nil {
 			// Cache hit, send response
-			_, err = w.Write(item.Value)
+			header, body := decodeCachedResponse(item.Value)
+			for name, value := range header {
+				w.Header().Set(name, value)
+			}
+			_, err = w.Write(body)
 			if err != nil {
 				rw := NewResponseWriter(w)
 				next.ServeHTTP(rw, r)
@@ -56,6 +66,11 @@ func (mcm *MemcachedCacheMiddleware) Middleware(next http.Handler) http.Handler
 		rw := NewResponseWriter(w)
 		next.ServeHTTP(rw, r)
 
+		// The responses with realtime data are not cached, they would go stale in seconds.
+		if strings.Contains(rw.Header().Get("Cache-Control"), "no-store") {
+			return
+		}
+
 		// Determine expiration based on time of day
 		// Night hours (e.g., 00:00 - 05:00) have a shorter cache duration
 		// This is because the "service day" ends at night after the last service has completed. There is often
@@ -63,19 +78,64 @@ func (mcm *MemcachedCacheMiddleware) Middleware(next http.Handler) http.Handler
 		// the next day's services begin if the cache period is short.
 		var expiration int32
 		now := time.Now()
-		hour := now.Hour()
+		timezone := mcm.timezone
+		if timezone == nil {
+			timezone = time.Local
+		}
+		hour := now.In(timezone).Hour()
 
 		if hour >= mcm.shortCachePeriodLowerBound && hour <= mcm.shortCachePeriodUpperBound { // Night hours (e.g., 00:00 - 05:00)
-			expiration = int32(time.Now().Add(mcm.shortCacheDuration).Unix())
+			expiration = int32(now.Add(mcm.shortCacheDuration).Unix())
 		} else {
-			expiration = int32(time.Now().Add(mcm.longCacheDuration).Unix())
+			expiration = int32(now.Add(mcm.longCacheDuration).Unix())
 		}
 
 		// Cache the new response
-		_ = mcm.client.Set(&memcache.Item{Key: key, Value: rw.forCache.Bytes(), Expiration: expiration})
+		_ = mcm.client.Set(&memcache.Item{Key: key, Value: encodeCachedResponse(rw.Header(), rw.forCache.Bytes()), Expiration: expiration})
 	})
 }
 
+// cachedHeaders are the response headers stored with the cached responses, so that a cache hit is sent with them
+// too.
+var cachedHeaders = []string{"Content-Type", "Vary"}
+
+// cacheKey returns the key of the cached response to the request. The same URL is answered with GeoJSON when the
+// Accept header asks for it, so those responses are cached under their own key.
+func cacheKey(r *http.Request) string {
+	if strings.Contains(r.Header.Get("Accept"), "application/geo+json") {
+		return r.URL.String() + "#geojson"
+	}
+	return r.URL.String()
+}
+
+// encodeCachedResponse stores the values of cachedHeaders before the body, one per line.
+func encodeCachedResponse(header http.Header, body []byte) []byte {
+	var value bytes.Buffer
+	for _, name := range cachedHeaders {
+		value.WriteString(header.Get(name))
+		value.WriteByte('\n')
+	}
+	value.Write(body)
+	return value.Bytes()
+}
+
+// decodeCachedResponse returns the headers and the body of a response stored by encodeCachedResponse. The headers
+// without a value are left out.
+func decodeCachedResponse(value []byte) (map[string]string, []byte) {
+	header := make(map[string]string)
+	for _, name := range cachedHeaders {
+		line, rest, found := bytes.Cut(value, []byte{'\n'})
+		if !found {
+			break
+		}
+		if len(line) > 0 {
+			header[name] = string(line)
+		}
+		value = rest
+	}
+	return header, value
+}
+
 type ResponseWriter struct {
 	http.ResponseWriter
 	forCache *bytes.Buffer
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachedResponse(t *testing.T) {
+	testCases := []struct {
+		id       string
+		header   http.Header
+		body     string
+		expected map[string]string
+	}{
+		{"geojson", http.Header{"Content-Type": {"application/geo+json"}, "Vary": {"Accept"}}, "{\"type\":\"FeatureCollection\"}\n", map[string]string{"Content-Type": "application/geo+json", "Vary": "Accept"}},
+		{"tile", http.Header{"Content-Type": {"application/vnd.mapbox-vector-tile"}}, "\x1a\n\x00", map[string]string{"Content-Type": "application/vnd.mapbox-vector-tile"}},
+		{"no-headers", http.Header{}, "", map[string]string{}},
+	}
+
+	for _, tc := range testCases {
+		header, body := decodeCachedResponse(encodeCachedResponse(tc.header, []byte(tc.body)))
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, header, tc.id)
+		testutil.CompareVariablesAndPrintResults(t, tc.body, string(body), tc.id)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	testCases := []struct {
+		accept   string
+		expected string
+	}{
+		{"", "/v1/stop-points/4600"},
+		{"application/json", "/v1/stop-points/4600"},
+		{"application/geo+json", "/v1/stop-points/4600#geojson"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/v1/stop-points/4600", nil)
+		req.Header.Set("Accept", tc.accept)
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, cacheKey(req), tc.accept)
+	}
+}
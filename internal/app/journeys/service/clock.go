@@ -0,0 +1,59 @@
+package service
+
+import "time"
+
+// Clock provides the current time and service day boundaries in the agency timezone. Every time the API derives
+// from the current moment or from the times of day in the feed should go through it, so that the results do not
+// depend on the timezone of the server.
+type Clock struct {
+	Location *time.Location
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Current returns the current time in the agency timezone.
+func (c *Clock) Current() time.Time {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	return now().In(c.location())
+}
+
+// ServiceDay returns the start of the service day on the calendar date of t in the agency timezone. As defined by
+// GTFS, the service day starts at noon minus 12 hours, which differs from midnight on the days when daylight saving
+// time starts or ends.
+func (c *Clock) ServiceDay(t time.Time) time.Time {
+	local := t.In(c.location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, c.location()).Add(-12 * time.Hour)
+}
+
+// Date returns the calendar date of t in the agency timezone, as noon of that date. Noon identifies the date
+// unambiguously, unlike the start of the service day which falls on the previous date when daylight saving time
+// starts.
+func (c *Clock) Date(t time.Time) time.Time {
+	local := t.In(c.location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, c.location())
+}
+
+// At returns the absolute time of a GTFS time of day, given as the offset from the start of the service day.
+func (c *Clock) At(serviceDay time.Time, offset time.Duration) time.Time {
+	return c.ServiceDay(serviceDay).Add(offset)
+}
+
+// ParseDate parses a date in the YYYY-MM-DD format. The date is returned as noon of the date in the agency timezone,
+// like Date returns it.
+func (c *Clock) ParseDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", value, c.location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Date(date), nil
+}
+
+func (c *Clock) location() *time.Location {
+	if c == nil || c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
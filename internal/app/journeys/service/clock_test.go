@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &Clock{Location: helsinki, Now: func() time.Time {
+		return time.Date(2024, 5, 15, 22, 30, 0, 0, time.UTC)
+	}}
+
+	if got := clock.Current(); got.Location() != helsinki || got.Day() != 16 || got.Hour() != 1 {
+		t.Errorf("expected 2024-05-16 01:30 in Helsinki, got %v", got)
+	}
+
+	if got, want := clock.ServiceDay(clock.Current()), time.Date(2024, 5, 16, 0, 0, 0, 0, helsinki); !got.Equal(want) {
+		t.Errorf("expected service day %v, got %v", want, got)
+	}
+
+	// Daylight saving time starts on 2024-03-31, so the service day starts an hour before midnight.
+	dstDay := time.Date(2024, 3, 31, 10, 0, 0, 0, helsinki)
+	if got, want := clock.ServiceDay(dstDay), time.Date(2024, 3, 30, 23, 0, 0, 0, helsinki); !got.Equal(want) {
+		t.Errorf("expected service day %v, got %v", want, got)
+	}
+	if got, want := clock.At(dstDay, 25*time.Hour+30*time.Minute), time.Date(2024, 4, 1, 1, 30, 0, 0, helsinki); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	var nilClock *Clock
+	if got := nilClock.location(); got != time.Local {
+		t.Errorf("expected the local timezone, got %v", got)
+	}
+}
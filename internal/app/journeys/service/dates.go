@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"time"
+)
+
+// MaxDateRangeDays limits the length of the date ranges in queries.
+const MaxDateRangeDays = 366
+
+var ErrInvalidDate = errors.New("invalid date, expected YYYY-MM-DD")
+var ErrInvalidDateRange = errors.New("invalid date range, both startDate and endDate are required and endDate may not be before startDate")
+var ErrDateRangeTooLong = errors.New("date range too long")
+
+// DateRange is an inclusive range of dates, represented as returned by Clock.Date.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDateRange reads the date conditions of a query: date selects a single date, startDate and endDate an
+// inclusive range of dates. It returns nil if none of them is set.
+func (c *Clock) ParseDateRange(conditions map[string]string) (*DateRange, error) {
+	date, start, end := conditions["date"], conditions["startDate"], conditions["endDate"]
+
+	if date != "" {
+		if start != "" || end != "" {
+			return nil, ErrInvalidDateRange
+		}
+		d, err := c.ParseDate(date)
+		if err != nil {
+			return nil, ErrInvalidDate
+		}
+		return &DateRange{From: d, To: d}, nil
+	}
+
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	if start == "" || end == "" {
+		return nil, ErrInvalidDateRange
+	}
+
+	from, err := c.ParseDate(start)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+	to, err := c.ParseDate(end)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+
+	if to.Before(from) {
+		return nil, ErrInvalidDateRange
+	}
+	if to.After(from.AddDate(0, 0, MaxDateRangeDays-1)) {
+		return nil, ErrDateRangeTooLong
+	}
+
+	return &DateRange{From: from, To: to}, nil
+}
+
+// journeysOnDates selects the journeys which operate on a range of dates: the journeys of the service days in
+// the range, and the journeys of the service day before the range which continue past midnight into it.
+type journeysOnDates struct {
+	dates           *DateRange
+	services        map[string]struct{}
+	previousDayOnly map[string]struct{}
+}
+
+func newJourneysOnDates(calendar *repository.ServiceCalendar, dates *DateRange) *journeysOnDates {
+	j := &journeysOnDates{
+		dates:           dates,
+		services:        make(map[string]struct{}),
+		previousDayOnly: make(map[string]struct{}),
+	}
+
+	if calendar == nil {
+		return j
+	}
+
+	for d := dates.From; !d.After(dates.To); d = d.AddDate(0, 0, 1) {
+		for _, id := range calendar.ServicesOn(d) {
+			j.services[id] = struct{}{}
+		}
+	}
+
+	for _, id := range calendar.ServicesOn(dates.From.AddDate(0, 0, -1)) {
+		if _, ok := j.services[id]; !ok {
+			j.previousDayOnly[id] = struct{}{}
+		}
+	}
+
+	return j
+}
+
+func (j *journeysOnDates) includes(journey *model.Journey) bool {
+	if journey == nil {
+		return false
+	}
+
+	// Journeys without a service fall back to their validity range.
+	if journey.GtfsInfo == nil || journey.GtfsInfo.ServiceId == "" {
+		return journey.ValidFrom <= j.dates.To.Format("2006-01-02") && journey.ValidTo >= j.dates.From.Format("2006-01-02")
+	}
+
+	if _, ok := j.services[journey.GtfsInfo.ServiceId]; ok {
+		return true
+	}
+
+	if _, ok := j.previousDayOnly[journey.GtfsInfo.ServiceId]; !ok {
+		return false
+	}
+
+	arrival, err := utils.ParseGtfsTime(journey.ArrivalTime)
+	return err == nil && arrival >= 24*time.Hour
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock_ParseDateRange(t *testing.T) {
+	clock := &Clock{Location: time.UTC}
+
+	testCases := []struct {
+		id         string
+		conditions map[string]string
+		from       time.Time
+		to         time.Time
+		err        error
+	}{
+		{"none", map[string]string{"lineId": "1"}, time.Time{}, time.Time{}, nil},
+		{"date", map[string]string{"date": "2024-12-24"},
+			time.Date(2024, 12, 24, 12, 0, 0, 0, time.UTC), time.Date(2024, 12, 24, 12, 0, 0, 0, time.UTC), nil},
+		{"range", map[string]string{"startDate": "2024-12-24", "endDate": "2024-12-31"},
+			time.Date(2024, 12, 24, 12, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC), nil},
+		{"date-and-range", map[string]string{"date": "2024-12-24", "endDate": "2024-12-31"}, time.Time{}, time.Time{}, ErrInvalidDateRange},
+		{"missing-start", map[string]string{"endDate": "2024-12-31"}, time.Time{}, time.Time{}, ErrInvalidDateRange},
+		{"invalid", map[string]string{"date": "2024-12-32"}, time.Time{}, time.Time{}, ErrInvalidDate},
+		{"too-long", map[string]string{"startDate": "2024-01-01", "endDate": "2025-01-01"}, time.Time{}, time.Time{}, ErrDateRangeTooLong},
+	}
+
+	for _, tc := range testCases {
+		dates, err := clock.ParseDateRange(tc.conditions)
+		if err != tc.err {
+			t.Errorf("%v: expected error %v, got %v", tc.id, tc.err, err)
+			continue
+		}
+
+		if tc.from.IsZero() {
+			if dates != nil {
+				t.Errorf("%v: expected no dates, got %v", tc.id, dates)
+			}
+			continue
+		}
+
+		if dates == nil || !dates.From.Equal(tc.from) || !dates.To.Equal(tc.to) {
+			t.Errorf("%v: expected %v - %v, got %v", tc.id, tc.from, tc.to, dates)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"sort"
+	"time"
+)
+
+// Departures returns the scheduled departures from the stop point at or after from and before until, sorted by time.
+// At most limit departures are returned, or all of them if limit is negative. The last call of a journey is not a
+// departure, as the journey ends there.
+func (s JourneysService) Departures(stopPointId string, from time.Time, until time.Time, limit int) []*model.Departure {
+	result := make([]*model.Departure, 0)
+
+	clock := s.clock()
+	journeys := s.Repository.Journeys.ByStopPoint[stopPointId]
+
+	// Journeys of the previous service day may still depart after midnight.
+	for date := clock.Date(from).AddDate(0, 0, -1); !date.After(clock.Date(until)); date = date.AddDate(0, 0, 1) {
+		for _, journey := range journeys {
+			if !s.RunsOn(journey, date) {
+				continue
+			}
+
+			for i, c := range journey.Calls[:len(journey.Calls)-1] {
+				if c.StopPoint == nil || c.StopPoint.ShortName != stopPointId {
+					continue
+				}
+
+				offset, err := utils.ParseGtfsTime(c.DepartureTime)
+				if err != nil {
+					continue
+				}
+
+				t := clock.At(date, offset)
+				if t.Before(from) || !t.Before(until) {
+					continue
+				}
+
+				result = append(result, &model.Departure{Journey: journey, Call: c, CallIndex: i, ServiceDate: date, Time: t, ExpectedTime: t})
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(x, y int) bool {
+		return result[x].Time.Before(result[y].Time)
+	})
+
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"testing"
+	"time"
+)
+
+func TestJourneysService_Departures(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20250101"), EndDate: strPtr("20251231"),
+		}},
+		nil,
+	)
+
+	a := &model.StopPoint{ShortName: "A"}
+	b := &model.StopPoint{ShortName: "B"}
+
+	morning := &model.Journey{Id: "morning", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, Calls: []*model.JourneyCall{
+		{DepartureTime: "08:00:00", ArrivalTime: "08:00:00", StopPoint: a},
+		{DepartureTime: "08:10:00", ArrivalTime: "08:10:00", StopPoint: b},
+	}}
+	night := &model.Journey{Id: "night", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, Calls: []*model.JourneyCall{
+		{DepartureTime: "24:30:00", ArrivalTime: "24:30:00", StopPoint: a},
+		{DepartureTime: "24:40:00", ArrivalTime: "24:40:00", StopPoint: b},
+	}}
+	loop := &model.Journey{Id: "loop", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, Calls: []*model.JourneyCall{
+		{DepartureTime: "09:00:00", ArrivalTime: "09:00:00", StopPoint: a},
+		{DepartureTime: "09:10:00", ArrivalTime: "09:10:00", StopPoint: b},
+		{DepartureTime: "09:20:00", ArrivalTime: "09:20:00", StopPoint: a},
+	}}
+
+	service := JourneysService{
+		Repository: &repository.JourneysRepository{
+			Journeys: &repository.JourneysJourneyRepository{ByStopPoint: map[string][]*model.Journey{
+				"A": {loop, morning, night},
+				"B": {loop, morning, night},
+			}},
+			ServiceCalendar: calendar,
+		},
+		Clock: &Clock{Location: helsinki},
+	}
+
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, helsinki)
+	}
+
+	testCases := []struct {
+		id        string
+		stopPoint string
+		from      time.Time
+		until     time.Time
+		limit     int
+		expected  []string
+	}{
+		// Tuesday 7 January 2025
+		{"sorted", "A", at(7, 7, 0), at(7, 10, 0), 10, []string{"morning 2025-01-07T08:00:00+02:00", "loop 2025-01-07T09:00:00+02:00"}},
+		{"limit", "A", at(7, 7, 0), at(7, 10, 0), 1, []string{"morning 2025-01-07T08:00:00+02:00"}},
+		{"window-start-included", "A", at(7, 8, 0), at(7, 9, 0), 10, []string{"morning 2025-01-07T08:00:00+02:00"}},
+		{"last-call-is-not-a-departure", "B", at(7, 0, 0), at(8, 0, 0), 10, []string{"loop 2025-01-07T09:10:00+02:00"}},
+		// The night journey of Monday departs on Tuesday, and the one of Friday on Saturday.
+		{"previous-service-day", "A", at(7, 0, 0), at(7, 1, 0), 10, []string{"night 2025-01-07T00:30:00+02:00"}},
+		{"saturday-night", "A", at(11, 0, 0), at(12, 0, 0), 10, []string{"night 2025-01-11T00:30:00+02:00"}},
+		{"unknown-stop-point", "C", at(7, 0, 0), at(8, 0, 0), 10, []string{}},
+	}
+
+	for _, tc := range testCases {
+		departures := make([]string, 0)
+		for _, d := range service.Departures(tc.stopPoint, tc.from, tc.until, tc.limit) {
+			departures = append(departures, d.Journey.Id+" "+d.Time.Format(time.RFC3339))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, departures, tc.id)
+	}
+}
@@ -1,22 +1,46 @@
 package service
 
 import (
-	"fmt"
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
 	"strings"
 	"time"
 )
 
 type JourneysService struct {
 	Repository *repository.JourneysRepository
+	// Clock defaults to a clock in the timezone of the repository.
+	Clock *Clock
 }
 
+// Search returns the journeys matching the conditions in params. If the conditions select dates, the journeys
+// operating on those dates are returned. Otherwise excludeInactive limits the result to the journeys active now.
+// Invalid dates match no journeys.
 func (s JourneysService) Search(params map[string]string, excludeInactive bool) []*model.Journey {
 	result := make([]*model.Journey, 0)
+	clock := s.clock()
+	now := clock.Current()
+
+	dates, err := clock.ParseDateRange(params)
+	if err != nil {
+		return result
+	}
+
+	var onDates *journeysOnDates
+	if dates != nil {
+		onDates = newJourneysOnDates(s.Repository.ServiceCalendar, dates)
+	}
 
 	for _, journey := range s.Repository.Journeys.All {
-		if journeyMatchesConditions(journey, params, excludeInactive) {
+		if onDates != nil {
+			if !onDates.includes(journey) {
+				continue
+			}
+		} else if excludeInactive && !s.IsActive(journey, now) {
+			continue
+		}
+		if journeyMatchesConditions(journey, params) {
 			result = append(result, journey)
 		}
 	}
@@ -42,11 +66,53 @@ func (s JourneysService) GetOneById(id string) (*model.Journey, error) {
 	return nil, model.ErrNoSuchElement
 }
 
-func journeyMatchesConditions(journey *model.Journey, conditions map[string]string, excludeInactive bool) bool {
-	now := time.Now()
-	curDay := fmt.Sprintf("%d-%02d-%02d", now.Year(), now.Month(), now.Day())
+// RunsOn reports whether the journey operates on the date, according to the service calendar of the feed.
+// Journeys without a service fall back to their validity range.
+func (s JourneysService) RunsOn(journey *model.Journey, date time.Time) bool {
+	if journey == nil {
+		return false
+	}
+
+	if s.Repository.ServiceCalendar == nil || journey.GtfsInfo == nil || journey.GtfsInfo.ServiceId == "" {
+		d := date.Format("2006-01-02")
+		return journey.ValidFrom <= d && journey.ValidTo >= d
+	}
+
+	return s.Repository.ServiceCalendar.RunsOn(journey.GtfsInfo.ServiceId, date)
+}
 
-	if journey == nil || (excludeInactive && !(journey.ValidFrom <= curDay && journey.ValidTo >= curDay)) {
+// IsActive reports whether the journey runs on the service day of now, or is still running on the part of the
+// previous service day which continues after midnight. Days are determined in the agency timezone.
+func (s JourneysService) IsActive(journey *model.Journey, now time.Time) bool {
+	if journey == nil {
+		return false
+	}
+
+	clock := s.clock()
+	today := clock.Date(now)
+
+	if s.RunsOn(journey, today) {
+		return true
+	}
+
+	yesterday := today.AddDate(0, 0, -1)
+	if !s.RunsOn(journey, yesterday) {
+		return false
+	}
+
+	arrival, err := utils.ParseGtfsTime(journey.ArrivalTime)
+	return err == nil && !now.After(clock.At(yesterday, arrival))
+}
+
+func (s JourneysService) clock() *Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return &Clock{Location: s.Repository.Timezone}
+}
+
+func journeyMatchesConditions(journey *model.Journey, conditions map[string]string) bool {
+	if journey == nil {
 		return false
 	}
 
@@ -83,12 +149,11 @@ func journeyMatchesConditions(journey *model.Journey, conditions map[string]stri
 				return false
 			}
 		case "departureTime":
-			// Check also with ":00" postfix to be backwards compatible with the old API
-			if journey.DepartureTime != v && fmt.Sprintf("%v:00", v) != journey.DepartureTime {
+			if !gtfsTimeMatches(v, journey.DepartureTime) {
 				return false
 			}
 		case "arrivalTime":
-			if journey.ArrivalTime != v && fmt.Sprintf("%v:00", v) != journey.ArrivalTime {
+			if !gtfsTimeMatches(v, journey.ArrivalTime) {
 				return false
 			}
 		case "firstStopPointId":
@@ -127,3 +192,20 @@ func journeyMatchesConditions(journey *model.Journey, conditions map[string]stri
 
 	return true
 }
+
+// gtfsTimeMatches reports whether the queried time, "HH:MM" or "HH:MM:SS", matches the GTFS time. Queries are
+// matched both as service day offsets and as times of day, so "01:10" matches also 25:10:00, the time of a journey
+// which departed on the previous service day.
+func gtfsTimeMatches(query string, gtfsTime string) bool {
+	queried, err := utils.ParseGtfsTime(query)
+	if err != nil {
+		return false
+	}
+
+	offset, err := utils.ParseGtfsTime(gtfsTime)
+	if err != nil {
+		return false
+	}
+
+	return queried == offset || queried == utils.TimeOfDay(offset)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/jlundan/journeys-api/internal/app/journeys/model"
 	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
 	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
 	"testing"
 	"time"
 )
@@ -65,8 +66,6 @@ func TestJourneyMatchesConditions(t *testing.T) {
 		{id: "12", item: &journeyWithEmptyCallArr, conditions: map[string]string{"lastStopPointId": "11111"}, expected: false},
 		{id: "13", item: &journeyWithEmptyCallArr, conditions: map[string]string{"stopPointId": "11111"}, expected: false},
 		{id: "14", item: &emptyJourney, conditions: map[string]string{"gtfsTripId": "11111"}, expected: false},
-		{id: "15", item: &invalidJourneyLowerSide, conditions: nil, expected: false},
-		{id: "16", item: &invalidJourneyUpperSide, conditions: nil, expected: false},
 		{id: "17", item: &validJourney, conditions: nil, expected: true},
 		{id: "18", item: &validJourney, conditions: map[string]string{"lineId": "1A"}, expected: true},
 		{id: "19", item: &validJourney, conditions: map[string]string{"routeId": "123"}, expected: true},
@@ -81,9 +80,13 @@ func TestJourneyMatchesConditions(t *testing.T) {
 		{id: "28", item: &validJourney, conditions: map[string]string{"lastStopPointId": "3"}, expected: true},
 	}
 	for _, tc := range testCases {
-		matches := journeyMatchesConditions(tc.item, tc.conditions, true)
+		matches := journeyMatchesConditions(tc.item, tc.conditions)
 		testutil.CompareVariablesAndPrintResults(t, tc.expected, matches, tc.id)
 	}
+
+	service := JourneysService{Repository: &repository.JourneysRepository{}}
+	testutil.CompareVariablesAndPrintResults(t, false, service.IsActive(&invalidJourneyLowerSide, time.Now()), "15")
+	testutil.CompareVariablesAndPrintResults(t, false, service.IsActive(&invalidJourneyUpperSide, time.Now()), "16")
 }
 
 func TestJourneysService_Search(t *testing.T) {
@@ -171,7 +174,6 @@ func TestJourneyMatchesConditions2(t *testing.T) {
 	}{
 		{"1", nil, nil, false},
 		{"2", &model.Journey{ValidFrom: curDay, ValidTo: curDay}, nil, true},
-		{"3", &model.Journey{ValidFrom: "2022-01-01", ValidTo: "2022-12-31"}, nil, false},
 		{"4", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Line: &model.Line{Name: "1"}}, map[string]string{"lineId": "1"}, true},
 		{"5", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Line: &model.Line{Name: "2"}}, map[string]string{"lineId": "1"}, false},
 		{"6", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Route: &model.Route{Id: "route1"}}, map[string]string{"routeId": "route1"}, true},
@@ -184,6 +186,10 @@ func TestJourneyMatchesConditions2(t *testing.T) {
 		{"13", &model.Journey{ValidFrom: curDay, ValidTo: curDay, DepartureTime: "02:00"}, map[string]string{"departureTime": "01:00"}, false},
 		{"14", &model.Journey{ValidFrom: curDay, ValidTo: curDay, ArrivalTime: "02:00"}, map[string]string{"arrivalTime": "02:00"}, true},
 		{"15", &model.Journey{ValidFrom: curDay, ValidTo: curDay, ArrivalTime: "03:00"}, map[string]string{"arrivalTime": "02:00"}, false},
+		{"15a", &model.Journey{ValidFrom: curDay, ValidTo: curDay, DepartureTime: "25:10:00"}, map[string]string{"departureTime": "01:10"}, true},
+		{"15b", &model.Journey{ValidFrom: curDay, ValidTo: curDay, DepartureTime: "25:10:00"}, map[string]string{"departureTime": "25:10:00"}, true},
+		{"15c", &model.Journey{ValidFrom: curDay, ValidTo: curDay, DepartureTime: "01:10:00"}, map[string]string{"departureTime": "25:10"}, false},
+		{"15d", &model.Journey{ValidFrom: curDay, ValidTo: curDay, ArrivalTime: "24:00:30"}, map[string]string{"arrivalTime": "00:00:30"}, true},
 		{"16", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Calls: []*model.JourneyCall{{StopPoint: &model.StopPoint{ShortName: "SP1"}}}}, map[string]string{"firstStopPointId": "SP1"}, true},
 		{"17", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Calls: []*model.JourneyCall{{StopPoint: &model.StopPoint{ShortName: "SP2"}}}}, map[string]string{"firstStopPointId": "SP1"}, false},
 		{"18", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Calls: []*model.JourneyCall{{StopPoint: &model.StopPoint{ShortName: "SP1"}}, {StopPoint: &model.StopPoint{ShortName: "SP2"}}}}, map[string]string{"lastStopPointId": "SP2"}, true},
@@ -192,8 +198,122 @@ func TestJourneyMatchesConditions2(t *testing.T) {
 		{"21", &model.Journey{ValidFrom: curDay, ValidTo: curDay, Calls: []*model.JourneyCall{{StopPoint: &model.StopPoint{ShortName: "SP1"}}, {StopPoint: &model.StopPoint{ShortName: "SP3"}}}}, map[string]string{"stopPointId": "SP2"}, false},
 	}
 
+	service := JourneysService{Repository: &repository.JourneysRepository{}}
+
 	for _, tc := range testCases {
-		matches := journeyMatchesConditions(tc.journey, tc.conditions, true)
+		matches := service.IsActive(tc.journey, now) && journeyMatchesConditions(tc.journey, tc.conditions)
 		testutil.CompareVariablesAndPrintResults(t, tc.expected, matches, tc.id)
 	}
+
+	testutil.CompareVariablesAndPrintResults(t, false, service.IsActive(&model.Journey{ValidFrom: "2022-01-01", ValidTo: "2022-12-31"}, now), "3")
+}
+
+func TestJourneysService_IsActive(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20250101"), EndDate: strPtr("20251231"),
+		}},
+		[]*ggtfs.CalendarDate{
+			{ServiceId: strPtr("WD"), Date: strPtr("20250106"), ExceptionType: strPtr("2")},
+			{ServiceId: strPtr("WD"), Date: strPtr("20250330"), ExceptionType: strPtr("1")},
+		},
+	)
+
+	service := JourneysService{Repository: &repository.JourneysRepository{ServiceCalendar: calendar, Timezone: helsinki}}
+
+	dayJourney := &model.Journey{GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: "10:00:00", ArrivalTime: "10:30:00",
+		ValidFrom: "2025-01-01", ValidTo: "2025-12-31"}
+	nightJourney := &model.Journey{GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: "23:50:00", ArrivalTime: "24:40:00",
+		ValidFrom: "2025-01-01", ValidTo: "2025-12-31"}
+
+	testCases := []struct {
+		id       string
+		journey  *model.Journey
+		now      time.Time
+		expected bool
+	}{
+		{"weekday", dayJourney, time.Date(2025, 1, 7, 12, 0, 0, 0, helsinki), true},
+		{"saturday", dayJourney, time.Date(2025, 1, 4, 12, 0, 0, 0, helsinki), false},
+		{"removed-date", dayJourney, time.Date(2025, 1, 6, 12, 0, 0, 0, helsinki), false},
+		{"night-on-saturday-after-friday", nightJourney, time.Date(2025, 1, 4, 0, 20, 0, 0, helsinki), true},
+		{"night-on-saturday-after-arrival", nightJourney, time.Date(2025, 1, 4, 0, 50, 0, 0, helsinki), false},
+		{"day-on-saturday-after-friday", dayJourney, time.Date(2025, 1, 4, 0, 20, 0, 0, helsinki), false},
+		// 22:30 UTC on Friday is already Saturday in Helsinki
+		{"agency-timezone", dayJourney, time.Date(2025, 1, 3, 22, 30, 0, 0, time.UTC), false},
+		{"agency-timezone-night", nightJourney, time.Date(2025, 1, 3, 22, 30, 0, 0, time.UTC), true},
+		// Daylight saving time starts on Sunday 2025-03-30, and its service day starts on the previous date.
+		{"dst-day", dayJourney, time.Date(2025, 3, 30, 12, 0, 0, 0, helsinki), true},
+	}
+
+	for _, tc := range testCases {
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, service.IsActive(tc.journey, tc.now), tc.id)
+	}
+}
+
+func TestJourneysService_SearchByDate(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20241201"), EndDate: strPtr("20241231"),
+		}},
+		[]*ggtfs.CalendarDate{
+			{ServiceId: strPtr("WD"), Date: strPtr("20241224"), ExceptionType: strPtr("2")},
+			{ServiceId: strPtr("XMAS"), Date: strPtr("20241224"), ExceptionType: strPtr("1")},
+		},
+	)
+
+	dayJourney := &model.Journey{Id: "day", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: "10:00:00", ArrivalTime: "10:30:00"}
+	nightJourney := &model.Journey{Id: "night", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: "23:50:00", ArrivalTime: "24:40:00"}
+	xmasJourney := &model.Journey{Id: "xmas", GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "XMAS"}, DepartureTime: "12:00:00", ArrivalTime: "12:30:00"}
+	noServiceJourney := &model.Journey{Id: "no-service", ValidFrom: "2024-12-20", ValidTo: "2024-12-22", DepartureTime: "12:00:00", ArrivalTime: "12:30:00"}
+
+	service := JourneysService{
+		Repository: &repository.JourneysRepository{
+			Journeys:        &repository.JourneysJourneyRepository{All: []*model.Journey{dayJourney, nightJourney, xmasJourney, noServiceJourney}},
+			ServiceCalendar: calendar,
+			Timezone:        helsinki,
+		},
+		Clock: &Clock{Location: helsinki, Now: func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, helsinki) }},
+	}
+
+	testCases := []struct {
+		id       string
+		params   map[string]string
+		expected []string
+	}{
+		// The night journey of Monday 23 December continues into 24 December.
+		{"christmas-eve", map[string]string{"date": "2024-12-24"}, []string{"night", "xmas"}},
+		{"weekday", map[string]string{"date": "2024-12-23"}, []string{"day", "night"}},
+		{"saturday-after-friday-night", map[string]string{"date": "2024-12-21"}, []string{"night", "no-service"}},
+		{"range", map[string]string{"startDate": "2024-12-21", "endDate": "2024-12-22"}, []string{"night", "no-service"}},
+		{"range-with-exception", map[string]string{"startDate": "2024-12-24", "endDate": "2024-12-25"}, []string{"day", "night", "xmas"}},
+		{"with-conditions", map[string]string{"date": "2024-12-24", "departureTime": "12:00"}, []string{"xmas"}},
+		{"outside-calendar", map[string]string{"date": "2025-01-15"}, []string{}},
+		{"invalid-date", map[string]string{"date": "24.12.2024"}, []string{}},
+		{"missing-end-date", map[string]string{"startDate": "2024-12-24"}, []string{}},
+		{"end-before-start", map[string]string{"startDate": "2024-12-24", "endDate": "2024-12-23"}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		ids := make([]string, 0)
+		for _, j := range service.Search(tc.params, true) {
+			ids = append(ids, j.Id)
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, ids, tc.id)
+	}
 }
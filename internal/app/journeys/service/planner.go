@@ -0,0 +1,612 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultMaxTransfers = 3
+const DefaultMaxWalkDistance = 1000.0
+const DefaultMinTransferTime = time.Minute
+
+// Walks are straight lines walked at walkingSpeed meters per second.
+const walkingSpeed = 1.2
+
+// transferWalkRadius is the longest walk between two stop points when changing journeys, in meters. Longer walks
+// are only taken when transfers.txt has a rule for them.
+const transferWalkRadius = 400.0
+
+// plannerCacheDays is the number of service days whose connections are kept in memory.
+const plannerCacheDays = 8
+
+// TripOptions are the options shared by the trip plans and the reachability queries.
+type TripOptions struct {
+	DepartAt time.Time
+	// MaxTransfers is the number of changes between journeys allowed in an itinerary.
+	MaxTransfers int
+	// MaxWalkDistance limits the walks from the origin and to the destination, in meters.
+	MaxWalkDistance float64
+	// MinTransferTime is the time reserved for changing journeys, also when the walk between the stop points
+	// is shorter.
+	MinTransferTime time.Duration
+	// WheelchairAccessible limits the itineraries to the journeys which are wheelchair accessible.
+	WheelchairAccessible bool
+}
+
+// PlanQuery describes a trip to plan.
+type PlanQuery struct {
+	From model.Place
+	To   model.Place
+	TripOptions
+}
+
+// PlannerService plans trips over the journeys of the repository with the Connection Scan Algorithm. The
+// connections between consecutive calls of the journeys are built per service day on first use and cached.
+type PlannerService struct {
+	Repository *repository.JourneysRepository
+	Journeys   *JourneysService
+
+	once       sync.Once
+	stopPoints []*model.StopPoint
+	stopIndex  map[*model.StopPoint]int
+	footpaths  [][]footpath
+	// footpathsTo holds the walks leading to each stop point. The to of these is the stop point the walk starts from.
+	footpathsTo [][]footpath
+	// stays holds the rules of transfers.txt for changing journeys at each stop point.
+	stays []transferRule
+
+	mu   sync.Mutex
+	days map[int]*plannerDay
+}
+
+// footpath is a walk from a stop point to another one nearby.
+type footpath struct {
+	to       int
+	distance float64
+	duration int64
+	rule     transferRule
+}
+
+// transferRule is a rule of transfers.txt for changing journeys between two stop points.
+type transferRule struct {
+	forbidden bool
+	// minTransfer is the minimum transfer time in seconds, or -1 when the minimum transfer time of the query
+	// applies.
+	minTransfer int64
+}
+
+// plannerDay holds the connections of the journeys running on a service day, sorted by departure time.
+type plannerDay struct {
+	serviceDate time.Time
+	journeys    []*model.Journey
+	connections []connection
+}
+
+// connection is the ride of a journey from a call to the next one. Times are unix seconds.
+type connection struct {
+	departure int64
+	arrival   int64
+	from      int
+	to        int
+	journey   int
+	// call is the index of the departure call in the calls of the journey.
+	call int
+}
+
+// Plan returns the itineraries from q.From to q.To departing at q.DepartAt or later. Every itinerary arrives
+// earlier than the ones with fewer transfers, so the result is the set of fastest itineraries for each number of
+// transfers, ordered by the number of transfers. A walk directly to the destination is included if it is not
+// longer than q.MaxWalkDistance.
+func (p *PlannerService) Plan(q PlanQuery) []*model.Itinerary {
+	p.once.Do(p.initialize)
+
+	result := make([]*model.Itinerary, 0)
+
+	distance := placeDistance(q.From, q.To)
+	if distance == 0 {
+		return result
+	}
+
+	clock := p.Journeys.clock()
+	departAt := q.DepartAt.Unix()
+	bestArrival := int64(math.MaxInt64)
+
+	if distance <= q.MaxWalkDistance {
+		arrival := departAt + walkingDuration(distance)
+		result = append(result, &model.Itinerary{Legs: []*model.ItineraryLeg{
+			walkLeg(q.From, q.To, departAt, arrival, distance, clock),
+		}})
+		bestArrival = arrival
+	}
+
+	origins := p.access(q.From, q.MaxWalkDistance, p.footpaths)
+	destinations := p.access(q.To, q.MaxWalkDistance, p.footpathsTo)
+	if len(origins) == 0 || len(destinations) == 0 {
+		return result
+	}
+
+	scan := p.newConnectionScan(q.DepartAt, q.MaxTransfers+1, q.MinTransferTime, q.WheelchairAccessible)
+	for _, o := range origins {
+		scan.start(o, departAt)
+	}
+
+	egress := make([]int64, len(p.stopPoints))
+	for i := range egress {
+		egress[i] = -1
+	}
+	for _, d := range destinations {
+		if egress[d.stop] < 0 || d.duration < egress[d.stop] {
+			egress[d.stop] = d.duration
+		}
+	}
+
+	scan.run(departAt, bestArrival, egress)
+
+	for trips := 1; trips <= scan.maxTrips; trips++ {
+		var best *stopAccess
+		for i, d := range destinations {
+			arrival := scan.arrival[trips][d.stop]
+			if arrival == math.MaxInt64 {
+				continue
+			}
+			if arrival+d.duration < bestArrival {
+				bestArrival = arrival + d.duration
+				best = &destinations[i]
+			}
+		}
+
+		if best != nil {
+			result = append(result, scan.itinerary(trips, *best, q))
+		}
+	}
+
+	return result
+}
+
+func (p *PlannerService) initialize() {
+	p.stopPoints = p.Repository.StopPoints.All
+	p.stopIndex = make(map[*model.StopPoint]int, len(p.stopPoints))
+	for i, sp := range p.stopPoints {
+		p.stopIndex[sp] = i
+	}
+
+	p.footpaths = make([][]footpath, len(p.stopPoints))
+	for i, sp := range p.stopPoints {
+		for _, nsp := range p.Repository.StopPoints.Index.Within(sp.Latitude, sp.Longitude, transferWalkRadius) {
+			if nsp.StopPoint == sp {
+				continue
+			}
+			p.footpaths[i] = append(p.footpaths[i], footpath{
+				to:       p.stopIndex[nsp.StopPoint],
+				distance: nsp.Distance,
+				duration: walkingDuration(nsp.Distance),
+				rule:     transferRule{minTransfer: -1},
+			})
+		}
+	}
+
+	p.stays = make([]transferRule, len(p.stopPoints))
+	for i := range p.stays {
+		p.stays[i].minTransfer = -1
+	}
+
+	if p.Repository.Transfers != nil {
+		for _, t := range p.Repository.Transfers.All {
+			p.addTransferRule(t)
+		}
+	}
+
+	p.footpathsTo = make([][]footpath, len(p.stopPoints))
+	for i, footpaths := range p.footpaths {
+		for _, fp := range footpaths {
+			p.footpathsTo[fp.to] = append(p.footpathsTo[fp.to], footpath{to: i, distance: fp.distance, duration: fp.duration, rule: fp.rule})
+		}
+	}
+}
+
+// addTransferRule applies a transfer rule to the stay at a stop point or to the walk between two stop points. A
+// walk longer than transferWalkRadius is added for a rule which allows it.
+func (p *PlannerService) addTransferRule(t *model.Transfer) {
+	from, okFrom := p.stopIndex[t.From]
+	to, okTo := p.stopIndex[t.To]
+	if !okFrom || !okTo {
+		return
+	}
+
+	rule := transferRule{forbidden: t.Forbidden, minTransfer: -1}
+	if t.MinTransferTime != nil {
+		rule.minTransfer = int64(*t.MinTransferTime / time.Second)
+	}
+
+	if from == to {
+		p.stays[from] = rule
+		return
+	}
+
+	for i, fp := range p.footpaths[from] {
+		if fp.to == to {
+			p.footpaths[from][i].rule = rule
+			return
+		}
+	}
+
+	if rule.forbidden {
+		return
+	}
+
+	distance := placeDistance(stopPlace(t.From), stopPlace(t.To))
+	p.footpaths[from] = append(p.footpaths[from], footpath{
+		to:       to,
+		distance: distance,
+		duration: walkingDuration(distance),
+		rule:     rule,
+	})
+}
+
+// stopAccess is a walk between a place and a stop point.
+type stopAccess struct {
+	stop     int
+	distance float64
+	duration int64
+}
+
+// access returns the stop points within maxWalkDistance meters of the place. A stop point place is accessed
+// directly, and its neighbours by the allowed walks of footpaths, which are the walks from it for an origin and the
+// walks to it for a destination.
+func (p *PlannerService) access(place model.Place, maxWalkDistance float64, footpaths [][]footpath) []stopAccess {
+	result := make([]stopAccess, 0)
+
+	if place.StopPoint != nil {
+		i, ok := p.stopIndex[place.StopPoint]
+		if !ok {
+			return result
+		}
+
+		result = append(result, stopAccess{stop: i})
+		for _, fp := range footpaths[i] {
+			if !fp.rule.forbidden && fp.distance <= maxWalkDistance {
+				result = append(result, stopAccess{stop: fp.to, distance: fp.distance, duration: fp.duration})
+			}
+		}
+
+		return result
+	}
+
+	for _, nsp := range p.Repository.StopPoints.Index.Within(place.Latitude, place.Longitude, maxWalkDistance) {
+		result = append(result, stopAccess{
+			stop:     p.stopIndex[nsp.StopPoint],
+			distance: nsp.Distance,
+			duration: walkingDuration(nsp.Distance),
+		})
+	}
+
+	return result
+}
+
+// day returns the connections of the service day of date.
+func (p *PlannerService) day(date time.Time) *plannerDay {
+	key := date.Year()*10000 + int(date.Month())*100 + date.Day()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if d, ok := p.days[key]; ok {
+		return d
+	}
+
+	if p.days == nil || len(p.days) >= plannerCacheDays {
+		p.days = make(map[int]*plannerDay)
+	}
+
+	d := p.newPlannerDay(date)
+	p.days[key] = d
+
+	return d
+}
+
+func (p *PlannerService) newPlannerDay(date time.Time) *plannerDay {
+	clock := p.Journeys.clock()
+	day := &plannerDay{serviceDate: date}
+
+	for _, journey := range p.Repository.Journeys.All {
+		if len(journey.Calls) < 2 || !p.Journeys.RunsOn(journey, date) {
+			continue
+		}
+
+		index := len(day.journeys)
+		day.journeys = append(day.journeys, journey)
+
+		for i := 0; i < len(journey.Calls)-1; i++ {
+			from, okFrom := p.stopIndex[journey.Calls[i].StopPoint]
+			to, okTo := p.stopIndex[journey.Calls[i+1].StopPoint]
+			if !okFrom || !okTo {
+				continue
+			}
+
+			departure, err := utils.ParseGtfsTime(journey.Calls[i].DepartureTime)
+			if err != nil {
+				continue
+			}
+			arrival, err := utils.ParseGtfsTime(journey.Calls[i+1].ArrivalTime)
+			if err != nil || arrival < departure {
+				continue
+			}
+
+			day.connections = append(day.connections, connection{
+				departure: clock.At(date, departure).Unix(),
+				arrival:   clock.At(date, arrival).Unix(),
+				from:      from,
+				to:        to,
+				journey:   index,
+				call:      i,
+			})
+		}
+	}
+
+	// The connections of a journey stay in the order of the calls, also when they depart at the same second.
+	sort.SliceStable(day.connections, func(x, y int) bool {
+		cx, cy := day.connections[x], day.connections[y]
+		if cx.departure != cy.departure {
+			return cx.departure < cy.departure
+		}
+		return cx.arrival < cy.arrival
+	})
+
+	return day
+}
+
+// connectionScan is the state of a scan over the connections of consecutive service days. The earliest times are
+// tracked separately for each number of journeys ridden, so that the scan finds the fastest itinerary for every
+// number of transfers.
+type connectionScan struct {
+	planner     *PlannerService
+	days        []*plannerDay
+	maxTrips    int
+	minTransfer int64
+	wheelchair  bool
+
+	// ready[k][s] is the earliest time a journey can be boarded at stop point s after riding k journeys, and
+	// readyVia[k][s] tells how the stop point was reached.
+	ready    [][]int64
+	readyVia [][]readyVia
+	// arrival[k][s] is the earliest arrival at stop point s on the k:th journey, ridden as rides[k][s].
+	arrival [][]int64
+	rides   [][]ride
+	// boarded[k][d][j] is the index of the connection plus one where journey j of day d was boarded as the k:th
+	// journey, or zero if it was not.
+	boarded [][][]int
+}
+
+type readyVia struct {
+	// walkFrom is the stop point the walk to this one started from, or -1 if there was no walk after the
+	// journey. Walks from the origin are not from a stop point, so their walkFrom is always -1.
+	walkFrom int
+	distance float64
+	duration int64
+}
+
+type ride struct {
+	day    int
+	board  int
+	alight int
+}
+
+// newConnectionScan prepares a scan over the service days around departAt. The journeys of the previous service
+// day may still run after midnight, and the journeys of the next one are needed for trips late in the evening.
+func (p *PlannerService) newConnectionScan(departAt time.Time, maxTrips int, minTransfer time.Duration, wheelchair bool) *connectionScan {
+	date := p.Journeys.clock().Date(departAt)
+
+	s := &connectionScan{
+		planner:     p,
+		days:        []*plannerDay{p.day(date.AddDate(0, 0, -1)), p.day(date), p.day(date.AddDate(0, 0, 1))},
+		maxTrips:    maxTrips,
+		minTransfer: int64(minTransfer / time.Second),
+		wheelchair:  wheelchair,
+	}
+
+	n := len(p.stopPoints)
+	for k := 0; k <= maxTrips; k++ {
+		ready := make([]int64, n)
+		arrival := make([]int64, n)
+		for i := 0; i < n; i++ {
+			ready[i] = math.MaxInt64
+			arrival[i] = math.MaxInt64
+		}
+		s.ready = append(s.ready, ready)
+		s.arrival = append(s.arrival, arrival)
+		s.readyVia = append(s.readyVia, make([]readyVia, n))
+		s.rides = append(s.rides, make([]ride, n))
+
+		boarded := make([][]int, len(s.days))
+		for d, day := range s.days {
+			boarded[d] = make([]int, len(day.journeys))
+		}
+		s.boarded = append(s.boarded, boarded)
+	}
+
+	return s
+}
+
+// start walks from the origin to a stop point, departing at departAt.
+func (s *connectionScan) start(a stopAccess, departAt int64) {
+	s.relax(0, a.stop, departAt+a.duration, readyVia{walkFrom: -1, distance: a.distance, duration: a.duration})
+}
+
+func (s *connectionScan) relax(trips int, stop int, ready int64, via readyVia) {
+	if ready < s.ready[trips][stop] {
+		s.ready[trips][stop] = ready
+		s.readyVia[trips][stop] = via
+	}
+}
+
+// run scans the connections departing between departAt and until in the order of departure. If egress is given, it
+// holds the walking time from each stop point to the destination, or -1, and the scan stops when no connection can
+// arrive at the destination earlier than with the same or a smaller number of journeys anymore.
+func (s *connectionScan) run(departAt int64, until int64, egress []int64) {
+	// best holds the earliest arrival at the destination with at most k journeys. It does not grow with k, so a
+	// connection departing after best[k] cannot improve the itineraries of k or more journeys.
+	best := make([]int64, s.maxTrips+1)
+	for k := range best {
+		best[k] = math.MaxInt64
+	}
+
+	next := make([]int, len(s.days))
+	for d, day := range s.days {
+		next[d] = sort.Search(len(day.connections), func(i int) bool {
+			return day.connections[i].departure >= departAt
+		})
+	}
+
+	for {
+		d := -1
+		for i, day := range s.days {
+			if next[i] < len(day.connections) && (d < 0 || day.connections[next[i]].departure < s.days[d].connections[next[d]].departure) {
+				d = i
+			}
+		}
+		if d < 0 {
+			return
+		}
+
+		index := next[d]
+		next[d]++
+		c := s.days[d].connections[index]
+
+		if c.departure >= best[1] || c.departure > until {
+			return
+		}
+
+		if s.wheelchair && !s.days[d].journeys[c.journey].WheelchairAccessible {
+			continue
+		}
+
+		for k := 1; k <= s.maxTrips && c.departure < best[k]; k++ {
+			boarded := s.boarded[k][d][c.journey]
+			if boarded == 0 {
+				if s.ready[k-1][c.from] > c.departure {
+					continue
+				}
+				boarded = index + 1
+				s.boarded[k][d][c.journey] = boarded
+			}
+
+			if c.arrival >= s.arrival[k][c.to] {
+				continue
+			}
+
+			s.arrival[k][c.to] = c.arrival
+			s.rides[k][c.to] = ride{day: d, board: boarded - 1, alight: index}
+
+			if stay := s.planner.stays[c.to]; !stay.forbidden {
+				s.relax(k, c.to, c.arrival+s.transferTime(stay, 0), readyVia{walkFrom: -1})
+			}
+			for _, fp := range s.planner.footpaths[c.to] {
+				if fp.rule.forbidden {
+					continue
+				}
+				s.relax(k, fp.to, c.arrival+s.transferTime(fp.rule, fp.duration), readyVia{walkFrom: c.to, distance: fp.distance, duration: fp.duration})
+			}
+
+			if egress != nil && egress[c.to] >= 0 {
+				for j := k; j <= s.maxTrips; j++ {
+					best[j] = min(best[j], c.arrival+egress[c.to])
+				}
+			}
+		}
+	}
+}
+
+// transferTime returns the time reserved for a transfer which walks for walk seconds. The minimum transfer time of
+// a transfer rule replaces the one of the query.
+func (s *connectionScan) transferTime(rule transferRule, walk int64) int64 {
+	if rule.minTransfer >= 0 {
+		return max(walk, rule.minTransfer)
+	}
+	return max(walk, s.minTransfer)
+}
+
+// itinerary traces back the itinerary which arrives at the destination on the trips:th journey, leaving the
+// journey at the stop point of the egress.
+func (s *connectionScan) itinerary(trips int, egress stopAccess, q PlanQuery) *model.Itinerary {
+	clock := s.planner.Journeys.clock()
+	stopPoints := s.planner.stopPoints
+
+	var legs []*model.ItineraryLeg
+
+	if arrival := s.arrival[trips][egress.stop]; stopPoints[egress.stop] != q.To.StopPoint {
+		legs = append(legs, walkLeg(stopPlace(stopPoints[egress.stop]), q.To, arrival, arrival+egress.duration, egress.distance, clock))
+	}
+
+	stop := egress.stop
+	for k := trips; k >= 1; k-- {
+		r := s.rides[k][stop]
+		day := s.days[r.day]
+		board, alight := day.connections[r.board], day.connections[r.alight]
+		journey := day.journeys[board.journey]
+
+		legs = append(legs, &model.ItineraryLeg{
+			Mode:        model.ItineraryLegTransit,
+			From:        stopPlace(stopPoints[board.from]),
+			To:          stopPlace(stopPoints[alight.to]),
+			Departure:   time.Unix(board.departure, 0).In(clock.location()),
+			Arrival:     time.Unix(alight.arrival, 0).In(clock.location()),
+			Journey:     journey,
+			ServiceDate: day.serviceDate,
+			Calls:       journey.Calls[board.call : alight.call+2],
+		})
+
+		via := s.readyVia[k-1][board.from]
+		if k == 1 {
+			if stopPoints[board.from] != q.From.StopPoint {
+				legs = append(legs, walkLeg(q.From, stopPlace(stopPoints[board.from]), board.departure-via.duration, board.departure, via.distance, clock))
+			}
+			break
+		}
+
+		stop = board.from
+		if via.walkFrom >= 0 {
+			departure := s.arrival[k-1][via.walkFrom]
+			legs = append(legs, walkLeg(stopPlace(stopPoints[via.walkFrom]), stopPlace(stopPoints[board.from]), departure, departure+via.duration, via.distance, clock))
+			stop = via.walkFrom
+		}
+	}
+
+	for i, j := 0, len(legs)-1; i < j; i, j = i+1, j-1 {
+		legs[i], legs[j] = legs[j], legs[i]
+	}
+
+	return &model.Itinerary{Legs: legs, Transfers: trips - 1}
+}
+
+func walkLeg(from model.Place, to model.Place, departure int64, arrival int64, distance float64, clock *Clock) *model.ItineraryLeg {
+	return &model.ItineraryLeg{
+		Mode:      model.ItineraryLegWalk,
+		From:      from,
+		To:        to,
+		Departure: time.Unix(departure, 0).In(clock.location()),
+		Arrival:   time.Unix(arrival, 0).In(clock.location()),
+		Distance:  distance,
+	}
+}
+
+func stopPlace(stopPoint *model.StopPoint) model.Place {
+	return model.Place{StopPoint: stopPoint, Latitude: stopPoint.Latitude, Longitude: stopPoint.Longitude}
+}
+
+func placeDistance(from model.Place, to model.Place) float64 {
+	if from.StopPoint != nil && from.StopPoint == to.StopPoint {
+		return 0
+	}
+	return ggtfs.HaversineDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
+
+// walkingDuration returns the time to walk distance meters in whole seconds.
+func walkingDuration(distance float64) int64 {
+	return int64(math.Ceil(distance / walkingSpeed))
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlannerService_Plan(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner, stops := newTestPlanner(helsinki)
+
+	at := func(hour int, minute int) time.Time {
+		// Tuesday 7 January 2025
+		return time.Date(2025, 1, 7, hour, minute, 0, 0, helsinki)
+	}
+
+	query := func(from model.Place, to model.Place, departAt time.Time) PlanQuery {
+		return PlanQuery{From: from, To: to, TripOptions: TripOptions{
+			DepartAt:        departAt,
+			MaxTransfers:    DefaultMaxTransfers,
+			MaxWalkDistance: DefaultMaxWalkDistance,
+			MinTransferTime: DefaultMinTransferTime,
+		}}
+	}
+
+	a, b, c, d := stopPlace(stops["A"]), stopPlace(stops["B"]), stopPlace(stops["C"]), stopPlace(stops["D"])
+	nearA := model.Place{Latitude: 61.5005, Longitude: 23.70}
+
+	noMinTransfer := query(a, d, at(7, 55))
+	noMinTransfer.MinTransferTime = 0
+
+	noTransfers := query(a, d, at(7, 55))
+	noTransfers.MaxTransfers = 0
+
+	wheelchair := query(a, d, at(7, 55))
+	wheelchair.WheelchairAccessible = true
+
+	testCases := []struct {
+		id       string
+		query    PlanQuery
+		expected []string
+	}{
+		{"transfer", query(a, d, at(7, 55)), []string{
+			"0: transit direct A-D 08:05-08:50",
+			"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit second C-D 08:15-08:30",
+		}},
+		// The walk from B to C takes 29 seconds, but a minute is reserved for the transfer.
+		{"min-transfer-time", noMinTransfer, []string{
+			"0: transit direct A-D 08:05-08:50",
+			"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit tight C-D 08:10-08:20",
+		}},
+		{"max-transfers", noTransfers, []string{"0: transit direct A-D 08:05-08:50"}},
+		{"wheelchair", wheelchair, []string{"0: transit direct A-D 08:05-08:50"}},
+		{"missed-first", query(a, d, at(8, 1)), []string{"0: transit direct A-D 08:05-08:50"}},
+		{"walk-from-location", query(nearA, d, at(7, 55)), []string{
+			"0: walk @-A 08:04-08:05, transit direct A-D 08:05-08:50",
+			"1: walk @-A 07:59-08:00, transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit second C-D 08:15-08:30",
+		}},
+		{"walk-between-stops", query(b, c, at(7, 55)), []string{"0: walk B-C 07:55-07:55"}},
+		{"from-the-stop-to-its-neighbour", query(a, c, at(7, 55)), []string{"0: transit first A-B 08:00-08:10, walk B-C 08:10-08:10"}},
+		{"same-place", query(a, a, at(7, 55)), []string{}},
+		{"next-day", query(a, d, at(9, 0)), []string{
+			"0: transit direct A-D 08:05-08:50",
+			"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit second C-D 08:15-08:30",
+		}},
+		// Friday 10 January, nothing runs on the weekend.
+		{"no-service", query(a, d, at(9, 0).AddDate(0, 0, 3)), []string{}},
+	}
+
+	for _, tc := range testCases {
+		itineraries := make([]string, 0)
+		for _, itinerary := range planner.Plan(tc.query) {
+			itineraries = append(itineraries, formatItinerary(itinerary))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, itineraries, tc.id)
+	}
+}
+
+func TestPlannerService_PlanTransfers(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minutes := func(m int) *time.Duration {
+		d := time.Duration(m) * time.Minute
+		return &d
+	}
+
+	testCases := []struct {
+		id       string
+		from     string
+		to       string
+		transfer func(stops map[string]*model.StopPoint) *model.Transfer
+		expected []string
+	}{
+		{"forbidden", "A", "D", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["B"], To: stops["C"], Forbidden: true}
+		}, []string{"0: transit direct A-D 08:05-08:50"}},
+		// The rule replaces the minute reserved for the transfer, so the tight journey is caught.
+		{"timed", "A", "D", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["B"], To: stops["C"], MinTransferTime: minutes(0)}
+		}, []string{
+			"0: transit direct A-D 08:05-08:50",
+			"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit tight C-D 08:10-08:20",
+		}},
+		{"min-transfer-time", "A", "D", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["B"], To: stops["C"], MinTransferTime: minutes(10)}
+		}, []string{"0: transit direct A-D 08:05-08:50"}},
+		// Staying at C is forbidden, which does not matter for walking from B.
+		{"other-stop-point", "A", "D", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["C"], To: stops["C"], Forbidden: true}
+		}, []string{
+			"0: transit direct A-D 08:05-08:50",
+			"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit second C-D 08:15-08:30",
+		}},
+		// The walk to the destination follows the rules of walking to it, not from it. The walks from the origin and to
+		// the destination follow the forbidden transfers too.
+		{"forbidden-egress", "A", "C", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["B"], To: stops["C"], Forbidden: true}
+		}, []string{}},
+		{"forbidden-reverse-egress", "A", "C", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["C"], To: stops["B"], Forbidden: true}
+		}, []string{"0: transit first A-B 08:00-08:10, walk B-C 08:10-08:10"}},
+		{"forbidden-access", "B", "D", func(stops map[string]*model.StopPoint) *model.Transfer {
+			return &model.Transfer{From: stops["B"], To: stops["C"], Forbidden: true}
+		}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		planner, stops := newTestPlanner(helsinki)
+		planner.Repository.Transfers = &repository.JourneysTransfersRepository{All: []*model.Transfer{tc.transfer(stops)}}
+
+		itineraries := make([]string, 0)
+		for _, itinerary := range planner.Plan(PlanQuery{From: stopPlace(stops[tc.from]), To: stopPlace(stops[tc.to]), TripOptions: TripOptions{
+			DepartAt:        time.Date(2025, 1, 7, 7, 55, 0, 0, helsinki),
+			MaxTransfers:    DefaultMaxTransfers,
+			MaxWalkDistance: DefaultMaxWalkDistance,
+			MinTransferTime: DefaultMinTransferTime,
+		}}) {
+			itineraries = append(itineraries, formatItinerary(itinerary))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, itineraries, tc.id)
+	}
+}
+
+// The express journey departs after the itinerary with a transfer has arrived, but it is still the fastest itinerary
+// without transfers.
+func TestPlannerService_PlanDirectAfterTransfer(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner, stops := newTestPlanner(helsinki)
+	planner.Repository.Journeys.All = append(planner.Repository.Journeys.All, &model.Journey{
+		Id:       "express",
+		GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"},
+		Calls: []*model.JourneyCall{
+			{DepartureTime: "08:31:00", ArrivalTime: "08:31:00", StopPoint: stops["A"]},
+			{DepartureTime: "08:45:00", ArrivalTime: "08:45:00", StopPoint: stops["D"]},
+		},
+	})
+
+	itineraries := make([]string, 0)
+	for _, itinerary := range planner.Plan(PlanQuery{From: stopPlace(stops["A"]), To: stopPlace(stops["D"]), TripOptions: TripOptions{
+		DepartAt:        time.Date(2025, 1, 7, 7, 55, 0, 0, helsinki),
+		MaxTransfers:    DefaultMaxTransfers,
+		MaxWalkDistance: DefaultMaxWalkDistance,
+		MinTransferTime: DefaultMinTransferTime,
+	}}) {
+		itineraries = append(itineraries, formatItinerary(itinerary))
+	}
+
+	testutil.CompareVariablesAndPrintResults(t, []string{
+		"0: transit express A-D 08:31-08:45",
+		"1: transit first A-B 08:00-08:10, walk B-C 08:10-08:10, transit second C-D 08:15-08:30",
+	}, itineraries, "direct-after-transfer")
+}
+
+func newTestPlanner(location *time.Location) (*PlannerService, map[string]*model.StopPoint) {
+	strPtr := func(s string) *string { return &s }
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20250101"), EndDate: strPtr("20251231"),
+		}},
+		nil,
+	)
+
+	// B and C are about 35 meters apart, the other stop points are kilometers from each other.
+	stops := map[string]*model.StopPoint{
+		"A": {ShortName: "A", Latitude: 61.50, Longitude: 23.70},
+		"B": {ShortName: "B", Latitude: 61.52, Longitude: 23.70},
+		"C": {ShortName: "C", Latitude: 61.5202, Longitude: 23.7005},
+		"D": {ShortName: "D", Latitude: 61.55, Longitude: 23.70},
+	}
+
+	journey := func(id string, wheelchairAccessible bool, calls ...string) *model.Journey {
+		j := &model.Journey{Id: id, WheelchairAccessible: wheelchairAccessible, GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}}
+		for i := 0; i < len(calls); i += 2 {
+			j.Calls = append(j.Calls, &model.JourneyCall{DepartureTime: calls[i+1], ArrivalTime: calls[i+1], StopPoint: stops[calls[i]]})
+		}
+		return j
+	}
+
+	repo := &repository.JourneysRepository{
+		StopPoints: &repository.JourneysStopPointsRepository{
+			All:   []*model.StopPoint{stops["A"], stops["B"], stops["C"], stops["D"]},
+			Index: repository.NewStopPointIndex([]*model.StopPoint{stops["A"], stops["B"], stops["C"], stops["D"]}),
+		},
+		Journeys: &repository.JourneysJourneyRepository{All: []*model.Journey{
+			journey("first", false, "A", "08:00:00", "B", "08:10:00"),
+			journey("second", false, "C", "08:15:00", "D", "08:30:00"),
+			journey("tight", false, "C", "08:10:30", "D", "08:20:00"),
+			journey("direct", true, "A", "08:05:00", "D", "08:50:00"),
+		}},
+		ServiceCalendar: calendar,
+	}
+
+	journeys := &JourneysService{Repository: repo, Clock: &Clock{Location: location}}
+
+	return &PlannerService{Repository: repo, Journeys: journeys}, stops
+}
+
+func formatItinerary(itinerary *model.Itinerary) string {
+	place := func(p model.Place) string {
+		if p.StopPoint == nil {
+			return "@"
+		}
+		return p.StopPoint.ShortName
+	}
+
+	var legs []string
+	for _, leg := range itinerary.Legs {
+		var journey string
+		if leg.Journey != nil {
+			journey = " " + leg.Journey.Id
+		}
+		legs = append(legs, fmt.Sprintf("%v%v %v-%v %v-%v", leg.Mode, journey, place(leg.From), place(leg.To),
+			leg.Departure.Format("15:04"), leg.Arrival.Format("15:04")))
+	}
+
+	return fmt.Sprintf("%v: %v", itinerary.Transfers, strings.Join(legs, ", "))
+}
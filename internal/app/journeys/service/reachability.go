@@ -0,0 +1,325 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"math"
+	"sort"
+	"time"
+)
+
+// isochroneCellSize is the resolution of the isochrone polygons in meters.
+const isochroneCellSize = 100.0
+
+const metersPerDegreeLatitude = 111320.0
+
+// ReachabilityQuery describes the stop points to reach from a place within a time budget.
+type ReachabilityQuery struct {
+	From        model.Place
+	MaxDuration time.Duration
+	TripOptions
+}
+
+// Reachable returns the stop points which can be reached from q.From within q.MaxDuration of q.DepartAt, with the
+// earliest arrival at each, sorted by the arrival time. The stop points are reached by walking from the origin, by
+// riding at most q.MaxTransfers+1 journeys, and by a transfer walk after the last journey.
+func (p *PlannerService) Reachable(q ReachabilityQuery) []model.ReachableStopPoint {
+	p.once.Do(p.initialize)
+
+	result := make([]model.ReachableStopPoint, 0)
+
+	origins := p.access(q.From, q.MaxWalkDistance, p.footpaths)
+	if len(origins) == 0 {
+		return result
+	}
+
+	departAt := q.DepartAt.Unix()
+	until := q.DepartAt.Add(q.MaxDuration).Unix()
+
+	scan := p.newConnectionScan(q.DepartAt, q.MaxTransfers+1, q.MinTransferTime, q.WheelchairAccessible)
+	for _, o := range origins {
+		scan.start(o, departAt)
+	}
+	scan.run(departAt, until, nil)
+
+	arrivals := make([]int64, len(p.stopPoints))
+	transfers := make([]int, len(p.stopPoints))
+	for i := range arrivals {
+		arrivals[i] = math.MaxInt64
+	}
+
+	reach := func(stop int, arrival int64, trips int) {
+		if arrival <= until && arrival < arrivals[stop] {
+			arrivals[stop] = arrival
+			transfers[stop] = max(trips-1, 0)
+		}
+	}
+
+	for _, o := range origins {
+		reach(o.stop, departAt+o.duration, 0)
+	}
+
+	for k := 1; k <= scan.maxTrips; k++ {
+		for stop, arrival := range scan.arrival[k] {
+			if arrival == math.MaxInt64 {
+				continue
+			}
+			reach(stop, arrival, k)
+			for _, fp := range p.footpaths[stop] {
+				reach(fp.to, arrival+fp.duration, k)
+			}
+		}
+	}
+
+	location := p.Journeys.clock().location()
+	for stop, arrival := range arrivals {
+		if arrival == math.MaxInt64 {
+			continue
+		}
+		result = append(result, model.ReachableStopPoint{
+			StopPoint: p.stopPoints[stop],
+			Arrival:   time.Unix(arrival, 0).In(location),
+			Transfers: transfers[stop],
+		})
+	}
+
+	sort.Slice(result, func(x, y int) bool {
+		if !result[x].Arrival.Equal(result[y].Arrival) {
+			return result[x].Arrival.Before(result[y].Arrival)
+		}
+		return result[x].StopPoint.ShortName < result[y].StopPoint.ShortName
+	})
+
+	return result
+}
+
+// Isochrone approximates the area reachable from q.From within duration of q.DepartAt, given the stop points
+// returned by Reachable. The area is made of the circles which can be walked from the origin and from every stop
+// point in the time left, each at most q.MaxWalkDistance in radius, rasterized to a grid of isochroneCellSize cells.
+// The result is a list of polygons covering the grid cells whose center is inside the area, as in the coordinates of
+// a GeoJSON MultiPolygon: each polygon is an exterior ring followed by its holes, and each ring is a closed list of
+// [longitude, latitude] pairs. Exterior rings are counterclockwise and holes clockwise. Cells touching only at a
+// corner are joined by filling one of the cells next to them, so that the polygons and their rings do not touch.
+func (p *PlannerService) Isochrone(q ReachabilityQuery, reachable []model.ReachableStopPoint, duration time.Duration) [][][][2]float64 {
+	type circle struct {
+		x, y   float64
+		radius float64
+	}
+
+	// The grid is laid on an equirectangular projection centered at the origin, in meters.
+	metersPerDegreeLongitude := metersPerDegreeLatitude * math.Cos(q.From.Latitude*math.Pi/180)
+	project := func(lat float64, lon float64) (float64, float64) {
+		return (lon - q.From.Longitude) * metersPerDegreeLongitude, (lat - q.From.Latitude) * metersPerDegreeLatitude
+	}
+	unproject := func(p gridPoint) [2]float64 {
+		return [2]float64{
+			roundCoordinate(q.From.Longitude + float64(p.col)*isochroneCellSize/metersPerDegreeLongitude),
+			roundCoordinate(q.From.Latitude + float64(p.row)*isochroneCellSize/metersPerDegreeLatitude),
+		}
+	}
+
+	until := q.DepartAt.Add(duration)
+	circles := []circle{{radius: math.Min(duration.Seconds()*walkingSpeed, q.MaxWalkDistance)}}
+	for _, r := range reachable {
+		left := until.Sub(r.Arrival)
+		if left < 0 {
+			continue
+		}
+		x, y := project(r.StopPoint.Latitude, r.StopPoint.Longitude)
+		circles = append(circles, circle{x: x, y: y, radius: math.Min(left.Seconds()*walkingSpeed, q.MaxWalkDistance)})
+	}
+
+	cells := make(map[gridPoint]bool)
+	for _, c := range circles {
+		minRow, maxRow := int(math.Floor((c.y-c.radius)/isochroneCellSize)), int(math.Floor((c.y+c.radius)/isochroneCellSize))
+		minCol, maxCol := int(math.Floor((c.x-c.radius)/isochroneCellSize)), int(math.Floor((c.x+c.radius)/isochroneCellSize))
+		for row := minRow; row <= maxRow; row++ {
+			for col := minCol; col <= maxCol; col++ {
+				cx, cy := (float64(col)+0.5)*isochroneCellSize, (float64(row)+0.5)*isochroneCellSize
+				if math.Hypot(cx-c.x, cy-c.y) <= c.radius {
+					cells[gridPoint{col: col, row: row}] = true
+				}
+			}
+		}
+	}
+
+	result := make([][][][2]float64, 0)
+	for _, polygon := range gridPolygons(cells) {
+		var rings [][][2]float64
+		for _, ring := range polygon {
+			coordinates := make([][2]float64, 0, len(ring))
+			for _, point := range ring {
+				coordinates = append(coordinates, unproject(point))
+			}
+			rings = append(rings, coordinates)
+		}
+		result = append(result, rings)
+	}
+
+	return result
+}
+
+// gridPoint is a corner of the isochrone grid, or the cell whose lower left corner it is. Columns grow to the east
+// and rows to the north.
+type gridPoint struct {
+	col, row int
+}
+
+func sortedGridPoints(points map[gridPoint]bool) []gridPoint {
+	result := make([]gridPoint, 0, len(points))
+	for p := range points {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(x, y int) bool {
+		if result[x].row != result[y].row {
+			return result[x].row < result[y].row
+		}
+		return result[x].col < result[y].col
+	})
+	return result
+}
+
+// gridPolygons traces the outlines of the cells. The result is a list of polygons, each an exterior ring followed by
+// its holes, and each ring a closed list of grid corners. Cells touching only at a corner are joined first.
+func gridPolygons(cells map[gridPoint]bool) [][][]gridPoint {
+	joinDiagonalCells(cells)
+
+	// Every side of a cell without a neighbour is an edge of the outline, directed so that the cell is on its
+	// left. Since no cells touch only at a corner, a corner starts at most one edge.
+	next := make(map[gridPoint]gridPoint)
+	corners := make(map[gridPoint]bool)
+	for c := range cells {
+		lowerLeft, lowerRight := c, gridPoint{c.col + 1, c.row}
+		upperRight, upperLeft := gridPoint{c.col + 1, c.row + 1}, gridPoint{c.col, c.row + 1}
+
+		if !cells[gridPoint{c.col, c.row - 1}] {
+			next[lowerLeft] = lowerRight
+		}
+		if !cells[gridPoint{c.col + 1, c.row}] {
+			next[lowerRight] = upperRight
+		}
+		if !cells[gridPoint{c.col, c.row + 1}] {
+			next[upperRight] = upperLeft
+		}
+		if !cells[gridPoint{c.col - 1, c.row}] {
+			next[upperLeft] = lowerLeft
+		}
+	}
+	for p := range next {
+		corners[p] = true
+	}
+
+	type hole struct {
+		ring []gridPoint
+		// x and y are the center of a cell inside the hole.
+		x, y float64
+	}
+
+	var exteriors [][]gridPoint
+	var holes []hole
+
+	for _, start := range sortedGridPoints(corners) {
+		if _, ok := next[start]; !ok {
+			continue
+		}
+
+		ring := []gridPoint{start}
+		for p := next[start]; p != start; p = next[ring[len(ring)-1]] {
+			delete(next, ring[len(ring)-1])
+			ring = append(ring, p)
+		}
+		delete(next, ring[len(ring)-1])
+
+		if ringArea(ring) > 0 {
+			exteriors = append(exteriors, simplifyRing(ring))
+			continue
+		}
+
+		// The cell on the right of the first edge is outside the cells.
+		dx, dy := ring[1].col-ring[0].col, ring[1].row-ring[0].row
+		holes = append(holes, hole{
+			ring: simplifyRing(ring),
+			x:    float64(ring[0].col+ring[1].col)/2 + float64(dy)/2,
+			y:    float64(ring[0].row+ring[1].row)/2 - float64(dx)/2,
+		})
+	}
+
+	polygons := make([][][]gridPoint, 0, len(exteriors))
+	for _, exterior := range exteriors {
+		polygons = append(polygons, [][]gridPoint{exterior})
+	}
+
+	// A hole belongs to the smallest exterior ring around it.
+	for _, h := range holes {
+		owner := -1
+		for i, exterior := range exteriors {
+			if ringContains(exterior, h.x, h.y) && (owner < 0 || ringArea(exterior) < ringArea(exteriors[owner])) {
+				owner = i
+			}
+		}
+		if owner >= 0 {
+			polygons[owner] = append(polygons[owner], h.ring)
+		}
+	}
+
+	return polygons
+}
+
+// joinDiagonalCells fills a cell next to every two cells which touch only at a corner.
+func joinDiagonalCells(cells map[gridPoint]bool) {
+	for changed := true; changed; {
+		changed = false
+		for _, c := range sortedGridPoints(cells) {
+			right := gridPoint{c.col + 1, c.row}
+			if cells[right] {
+				continue
+			}
+			if cells[gridPoint{c.col + 1, c.row + 1}] && !cells[gridPoint{c.col, c.row + 1}] ||
+				cells[gridPoint{c.col + 1, c.row - 1}] && !cells[gridPoint{c.col, c.row - 1}] {
+				cells[right] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// simplifyRing drops the corners in the middle of straight lines, and closes the ring.
+func simplifyRing(ring []gridPoint) []gridPoint {
+	result := make([]gridPoint, 0, len(ring)+1)
+	for i, p := range ring {
+		prev, next := ring[(i+len(ring)-1)%len(ring)], ring[(i+1)%len(ring)]
+		if (prev.col == p.col && p.col == next.col) || (prev.row == p.row && p.row == next.row) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return append(result, result[0])
+}
+
+// ringArea returns the signed area of a ring in cells, positive for counterclockwise rings.
+func ringArea(ring []gridPoint) float64 {
+	area := 0
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p.col*q.row - q.col*p.row
+	}
+	return float64(area) / 2
+}
+
+// ringContains tells whether the point is inside the ring. The point must not be on the ring.
+func ringContains(ring []gridPoint, x float64, y float64) bool {
+	inside := false
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		if (float64(p.row) > y) != (float64(q.row) > y) {
+			if x < float64(p.col)+(y-float64(p.row))*float64(q.col-p.col)/float64(q.row-p.row) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// roundCoordinate rounds a coordinate to six decimals, about ten centimeters.
+func roundCoordinate(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestPlannerService_Reachable(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner, stops := newTestPlanner(helsinki)
+
+	query := func(maxDuration time.Duration, maxTransfers int) ReachabilityQuery {
+		return ReachabilityQuery{From: stopPlace(stops["A"]), MaxDuration: maxDuration, TripOptions: TripOptions{
+			// Tuesday 7 January 2025
+			DepartAt:        time.Date(2025, 1, 7, 7, 55, 0, 0, helsinki),
+			MaxTransfers:    maxTransfers,
+			MaxWalkDistance: DefaultMaxWalkDistance,
+			MinTransferTime: DefaultMinTransferTime,
+		}}
+	}
+
+	testCases := []struct {
+		id       string
+		query    ReachabilityQuery
+		expected []string
+	}{
+		{"transfer", query(40*time.Minute, DefaultMaxTransfers), []string{"A 07:55:00 0", "B 08:10:00 0", "C 08:10:29 0", "D 08:30:00 1"}},
+		{"no-transfers", query(40*time.Minute, 0), []string{"A 07:55:00 0", "B 08:10:00 0", "C 08:10:29 0"}},
+		{"short", query(15*time.Minute, DefaultMaxTransfers), []string{"A 07:55:00 0", "B 08:10:00 0"}},
+		{"long", query(time.Hour, DefaultMaxTransfers), []string{"A 07:55:00 0", "B 08:10:00 0", "C 08:10:29 0", "D 08:30:00 1"}},
+	}
+
+	for _, tc := range testCases {
+		reachable := make([]string, 0)
+		for _, r := range planner.Reachable(tc.query) {
+			reachable = append(reachable, fmt.Sprintf("%v %v %v", r.StopPoint.ShortName, r.Arrival.Format("15:04:05"), r.Transfers))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, reachable, tc.id)
+	}
+}
+
+func TestPlannerService_Isochrone(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner, stops := newTestPlanner(helsinki)
+
+	q := ReachabilityQuery{From: stopPlace(stops["A"]), MaxDuration: time.Minute, TripOptions: TripOptions{
+		DepartAt:        time.Date(2025, 1, 7, 7, 55, 0, 0, helsinki),
+		MaxWalkDistance: DefaultMaxWalkDistance,
+	}}
+
+	// A minute of walking reaches 72 meters, which covers the centers of the four cells around the origin. The
+	// cells are merged into one square of 200 by 200 meters, counterclockwise from the south-west corner. The
+	// origin is at 61.5, 23.7.
+	polygons := planner.Isochrone(q, planner.Reachable(q), time.Minute)
+	expected := [][][][2]float64{{{{23.698117, 61.499102}, {23.701883, 61.499102}, {23.701883, 61.500898}, {23.698117, 61.500898}, {23.698117, 61.499102}}}}
+	testutil.CompareVariablesAndPrintResults(t, expected, polygons, "square")
+
+	// Walking further than MaxWalkDistance is not possible.
+	q.MaxWalkDistance = 10
+	if polygons := planner.Isochrone(q, nil, time.Minute); len(polygons) != 0 {
+		t.Errorf("expected no polygons, got %v", polygons)
+	}
+}
+
+func TestGridPolygons(t *testing.T) {
+	// parse reads the cells from rows of text, the last row being row 0.
+	parse := func(rows ...string) map[gridPoint]bool {
+		cells := make(map[gridPoint]bool)
+		for i, row := range rows {
+			for col, c := range row {
+				if c == '#' {
+					cells[gridPoint{col: col, row: len(rows) - 1 - i}] = true
+				}
+			}
+		}
+		return cells
+	}
+
+	testCases := []struct {
+		id       string
+		cells    map[gridPoint]bool
+		expected [][][]gridPoint
+	}{
+		{"separate", parse("#.#"), [][][]gridPoint{
+			{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}},
+			{{{2, 0}, {3, 0}, {3, 1}, {2, 1}, {2, 0}}},
+		}},
+		// The cell right of the lower one is filled to join the cells.
+		{"diagonal", parse(".#", "#."), [][][]gridPoint{
+			{{{0, 0}, {2, 0}, {2, 2}, {1, 2}, {1, 1}, {0, 1}, {0, 0}}},
+		}},
+		{"hole", parse("###", "#.#", "###"), [][][]gridPoint{
+			{{{0, 0}, {3, 0}, {3, 3}, {0, 3}, {0, 0}}, {{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}},
+		}},
+		{"island-in-hole", parse("#####", "#...#", "#.#.#", "#...#", "#####"), [][][]gridPoint{
+			{{{0, 0}, {5, 0}, {5, 5}, {0, 5}, {0, 0}}, {{1, 1}, {1, 4}, {4, 4}, {4, 1}, {1, 1}}},
+			{{{2, 2}, {3, 2}, {3, 3}, {2, 3}, {2, 2}}},
+		}},
+		{"empty", parse("..."), [][][]gridPoint{}},
+	}
+
+	for _, tc := range testCases {
+		testutil.CompareVariablesAndPrintResults(t, fmt.Sprint(tc.expected), fmt.Sprint(gridPolygons(tc.cells)), tc.id)
+	}
+}
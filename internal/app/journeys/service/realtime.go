@@ -0,0 +1,190 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/realtime"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"sort"
+	"time"
+)
+
+// departuresMargin is how much earlier or later than the window of a departures query a departure may be scheduled
+// and still be expected in the window.
+const departuresMargin = time.Hour
+
+// RealtimeService estimates the journeys from the GTFS-Realtime trip updates of the state. Without a state there are
+// no estimates, and the journeys are expected to run on schedule.
+type RealtimeService struct {
+	State    *realtime.State
+	Journeys *JourneysService
+	Clock    *Clock
+}
+
+// Journey returns the realtime estimate of the journey, or nil if there is no trip update for it. The delay of a stop
+// time update applies to the following calls until the next update, as defined by GTFS-Realtime, and a delay of the
+// whole trip applies to the calls before the first update.
+func (s *RealtimeService) Journey(journey *model.Journey) *model.JourneyEstimate {
+	if s == nil || s.State == nil || journey == nil {
+		return nil
+	}
+
+	update, ok := s.State.JourneyUpdate(journey.Id)
+	if !ok {
+		return nil
+	}
+
+	date, ok := s.serviceDate(journey, &update.TripUpdate.Trip)
+	if !ok {
+		return nil
+	}
+
+	estimate := &model.JourneyEstimate{ServiceDate: date, Calls: make([]model.CallEstimate, len(journey.Calls))}
+
+	switch update.TripUpdate.Trip.ScheduleRelationship {
+	case realtime.TripCanceled, realtime.TripDeleted:
+		estimate.Cancelled = true
+		return estimate
+	}
+
+	var delay time.Duration
+	if d := update.TripUpdate.Delay; d != nil {
+		delay = time.Duration(*d) * time.Second
+	}
+
+	next := 0
+	for i, c := range journey.Calls {
+		e := &estimate.Calls[i]
+
+		var u *realtime.StopTimeUpdate
+		if next < len(update.Calls) && update.Calls[next].Index == i {
+			u = update.Calls[next].StopTimeUpdate
+			next++
+		}
+
+		if u != nil {
+			switch u.ScheduleRelationship {
+			case realtime.StopSkipped:
+				e.Skipped = true
+			case realtime.StopNoData:
+				delay = 0
+			default:
+				if d, ok := s.eventDelay(u.Arrival, date, c.ArrivalTime); ok {
+					delay = d
+				}
+				e.ArrivalDelay = delay
+
+				if d, ok := s.eventDelay(u.Departure, date, c.DepartureTime); ok {
+					delay = d
+				}
+				e.DepartureDelay = delay
+				continue
+			}
+		}
+
+		e.ArrivalDelay, e.DepartureDelay = delay, delay
+	}
+
+	return estimate
+}
+
+// Departures returns the departures from the stop point expected at or after from and before until, with the
+// realtime estimates of their journeys, sorted by the expected time. At most limit departures are returned. Without a
+// state the departures are the scheduled ones.
+func (s *RealtimeService) Departures(stopPointId string, from time.Time, until time.Time, limit int) []*model.Departure {
+	if s.State == nil {
+		return s.Journeys.Departures(stopPointId, from, until, limit)
+	}
+
+	result := make([]*model.Departure, 0)
+	for _, d := range s.Journeys.Departures(stopPointId, from.Add(-departuresMargin), until.Add(departuresMargin), -1) {
+		if estimate := s.Journey(d.Journey); estimate != nil && estimate.ServiceDate.Equal(d.ServiceDate) {
+			d.Estimate = estimate
+			d.ExpectedTime = d.Time.Add(estimate.Calls[d.CallIndex].DepartureDelay)
+		}
+
+		if d.ExpectedTime.Before(from) || !d.ExpectedTime.Before(until) {
+			continue
+		}
+
+		result = append(result, d)
+	}
+
+	sort.SliceStable(result, func(x, y int) bool {
+		return result[x].ExpectedTime.Before(result[y].ExpectedTime)
+	})
+
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// serviceDate returns the service day the trip update is for. It is the start_date of the trip, or without one the
+// day on which the journey starts closest to the time of the update, today or yesterday.
+func (s *RealtimeService) serviceDate(journey *model.Journey, trip *realtime.TripDescriptor) (time.Time, bool) {
+	clock := s.clock()
+
+	if trip.StartDate != "" {
+		d, err := time.Parse("20060102", trip.StartDate)
+		if err != nil {
+			return time.Time{}, false
+		}
+		date, err := clock.ParseDate(d.Format("2006-01-02"))
+		return date, err == nil
+	}
+
+	start, err := utils.ParseGtfsTime(journey.DepartureTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	reference := s.State.Timestamp()
+	if reference.IsZero() {
+		reference = clock.Current()
+	}
+
+	var result time.Time
+	var nearest time.Duration
+	for _, date := range []time.Time{clock.Date(reference), clock.Date(reference).AddDate(0, 0, -1)} {
+		if s.Journeys != nil && !s.Journeys.RunsOn(journey, date) {
+			continue
+		}
+
+		d := clock.At(date, start).Sub(reference).Abs()
+		if result.IsZero() || d < nearest {
+			result, nearest = date, d
+		}
+	}
+
+	return result, !result.IsZero()
+}
+
+// eventDelay returns the delay of the realtime arrival or departure from the scheduled time, or false if the event
+// does not tell it.
+func (s *RealtimeService) eventDelay(event *realtime.StopTimeEvent, date time.Time, scheduled string) (time.Duration, bool) {
+	if event == nil {
+		return 0, false
+	}
+
+	if event.Time != nil {
+		offset, err := utils.ParseGtfsTime(scheduled)
+		if err != nil {
+			return 0, false
+		}
+		return time.Unix(*event.Time, 0).Sub(s.clock().At(date, offset)), true
+	}
+
+	if event.Delay != nil {
+		return time.Duration(*event.Delay) * time.Second, true
+	}
+
+	return 0, false
+}
+
+func (s *RealtimeService) clock() *Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return &Clock{Location: s.State.Repository.Timezone}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/realtime"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"testing"
+	"time"
+)
+
+func TestRealtimeService_Journey(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	int32Ptr := func(v int32) *int32 { return &v }
+	uint32Ptr := func(v uint32) *uint32 { return &v }
+
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20250101"), EndDate: strPtr("20251231"),
+		}},
+		nil,
+	)
+
+	newJourney := func(id string, times ...string) *model.Journey {
+		journey := &model.Journey{Id: id, GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: times[0]}
+		for i, tm := range times {
+			journey.Calls = append(journey.Calls, &model.JourneyCall{DepartureTime: tm, ArrivalTime: tm, StopSequence: i + 1})
+		}
+		return journey
+	}
+
+	delayed := newJourney("delayed", "08:00:00", "08:10:00", "08:20:00", "08:30:00")
+	night := newJourney("night", "24:30:00", "24:40:00")
+	cancelled := newJourney("cancelled", "09:00:00", "09:10:00")
+	scheduled := newJourney("scheduled", "10:00:00", "10:10:00")
+
+	repo := &repository.JourneysRepository{
+		Lines:      &repository.JourneysLinesRepository{},
+		StopPoints: &repository.JourneysStopPointsRepository{},
+		Journeys: &repository.JourneysJourneyRepository{
+			All:  []*model.Journey{delayed, night, cancelled, scheduled},
+			ById: map[string]*model.Journey{"delayed": delayed, "night": night, "cancelled": cancelled, "scheduled": scheduled},
+		},
+		ServiceCalendar: calendar,
+		Timezone:        helsinki,
+	}
+
+	clock := &Clock{Location: helsinki}
+	service := &RealtimeService{Journeys: &JourneysService{Repository: repo, Clock: clock}, Clock: clock}
+
+	if service.Journey(delayed) != nil {
+		t.Errorf("expected no estimate without a state")
+	}
+
+	timestamp := time.Date(2025, 1, 8, 8, 5, 0, 0, helsinki)
+	departure := time.Date(2025, 1, 8, 8, 13, 0, 0, helsinki).Unix()
+
+	service.State = realtime.NewState(repo)
+	service.State.Apply(&realtime.FeedMessage{
+		Header: realtime.FeedHeader{Version: "2.0", Timestamp: uint64(timestamp.Unix())},
+		Entities: []*realtime.FeedEntity{
+			{Id: "1", TripUpdate: &realtime.TripUpdate{
+				Trip:  realtime.TripDescriptor{TripId: "delayed"},
+				Delay: int32Ptr(60),
+				StopTimeUpdates: []*realtime.StopTimeUpdate{
+					{StopSequence: uint32Ptr(2), Departure: &realtime.StopTimeEvent{Time: &departure}},
+					{StopSequence: uint32Ptr(4), ScheduleRelationship: realtime.StopNoData},
+				},
+			}},
+			{Id: "2", TripUpdate: &realtime.TripUpdate{
+				Trip: realtime.TripDescriptor{TripId: "night"},
+				StopTimeUpdates: []*realtime.StopTimeUpdate{
+					{StopSequence: uint32Ptr(1), ScheduleRelationship: realtime.StopSkipped},
+					{StopSequence: uint32Ptr(2), Arrival: &realtime.StopTimeEvent{Delay: int32Ptr(-30)}},
+				},
+			}},
+			{Id: "3", TripUpdate: &realtime.TripUpdate{
+				Trip: realtime.TripDescriptor{TripId: "cancelled", StartDate: "20250108", ScheduleRelationship: realtime.TripCanceled},
+			}},
+		},
+	})
+
+	testCases := []struct {
+		journey     *model.Journey
+		serviceDate time.Time
+		cancelled   bool
+		calls       []model.CallEstimate
+	}{
+		// The delay of the trip applies before the first update, and the delay of an update until the next one.
+		{delayed, time.Date(2025, 1, 8, 12, 0, 0, 0, helsinki), false, []model.CallEstimate{
+			{ArrivalDelay: time.Minute, DepartureDelay: time.Minute},
+			{ArrivalDelay: time.Minute, DepartureDelay: 3 * time.Minute},
+			{ArrivalDelay: 3 * time.Minute, DepartureDelay: 3 * time.Minute},
+			{},
+		}},
+		// The journey of the previous service day starts closer to the time of the feed.
+		{night, time.Date(2025, 1, 7, 12, 0, 0, 0, helsinki), false, []model.CallEstimate{
+			{Skipped: true},
+			{ArrivalDelay: -30 * time.Second, DepartureDelay: -30 * time.Second},
+		}},
+		{cancelled, time.Date(2025, 1, 8, 12, 0, 0, 0, helsinki), true, []model.CallEstimate{{}, {}}},
+	}
+
+	for _, tc := range testCases {
+		estimate := service.Journey(tc.journey)
+		if estimate == nil {
+			t.Errorf("%v: expected an estimate", tc.journey.Id)
+			continue
+		}
+
+		if !estimate.ServiceDate.Equal(tc.serviceDate) {
+			t.Errorf("%v: expected service date %v, got %v", tc.journey.Id, tc.serviceDate, estimate.ServiceDate)
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.cancelled, estimate.Cancelled, tc.journey.Id)
+		testutil.CompareVariablesAndPrintResults(t, tc.calls, estimate.Calls, tc.journey.Id)
+	}
+
+	if service.Journey(scheduled) != nil {
+		t.Errorf("expected no estimate for a journey without a trip update")
+	}
+}
+
+func TestRealtimeService_Departures(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := repository.NewServiceCalendar(
+		[]*ggtfs.CalendarItem{{
+			ServiceId: strPtr("WD"), Monday: strPtr("1"), Tuesday: strPtr("1"), Wednesday: strPtr("1"), Thursday: strPtr("1"),
+			Friday: strPtr("1"), Saturday: strPtr("0"), Sunday: strPtr("0"), StartDate: strPtr("20250101"), EndDate: strPtr("20251231"),
+		}},
+		nil,
+	)
+
+	a := &model.StopPoint{ShortName: "A"}
+	b := &model.StopPoint{ShortName: "B"}
+
+	newJourney := func(id string, departure string, arrival string) *model.Journey {
+		return &model.Journey{Id: id, GtfsInfo: &model.JourneyGtfsInfo{ServiceId: "WD"}, DepartureTime: departure, Calls: []*model.JourneyCall{
+			{DepartureTime: departure, ArrivalTime: departure, StopPoint: a, StopSequence: 1},
+			{DepartureTime: arrival, ArrivalTime: arrival, StopPoint: b, StopSequence: 2},
+		}}
+	}
+
+	late := newJourney("late", "08:00:00", "08:10:00")
+	next := newJourney("next", "08:10:00", "08:20:00")
+	early := newJourney("early", "09:10:00", "09:20:00")
+
+	repo := &repository.JourneysRepository{
+		Lines:      &repository.JourneysLinesRepository{},
+		StopPoints: &repository.JourneysStopPointsRepository{},
+		Journeys: &repository.JourneysJourneyRepository{
+			All:         []*model.Journey{late, next, early},
+			ById:        map[string]*model.Journey{"late": late, "next": next, "early": early},
+			ByStopPoint: map[string][]*model.Journey{"A": {late, next, early}},
+		},
+		ServiceCalendar: calendar,
+		Timezone:        helsinki,
+	}
+
+	clock := &Clock{Location: helsinki}
+	service := &RealtimeService{Journeys: &JourneysService{Repository: repo, Clock: clock}, Clock: clock}
+
+	at := func(hour int, minute int) time.Time {
+		// Wednesday 8 January 2025
+		return time.Date(2025, 1, 8, hour, minute, 0, 0, helsinki)
+	}
+
+	format := func(departures []*model.Departure) []string {
+		result := make([]string, 0)
+		for _, d := range departures {
+			result = append(result, d.Journey.Id+" "+d.ExpectedTime.Format("15:04")+" "+fmt.Sprint(d.Estimate != nil))
+		}
+		return result
+	}
+
+	testutil.CompareVariablesAndPrintResults(t, []string{"late 08:00 false", "next 08:10 false"}, format(service.Departures("A", at(7, 0), at(9, 0), 10)), "no-state")
+
+	service.State = realtime.NewState(repo)
+	service.State.Apply(&realtime.FeedMessage{
+		Header: realtime.FeedHeader{Version: "2.0", Timestamp: uint64(at(7, 55).Unix())},
+		Entities: []*realtime.FeedEntity{
+			{Id: "1", TripUpdate: &realtime.TripUpdate{Trip: realtime.TripDescriptor{TripId: "late", StartDate: "20250108"}, Delay: int32Ptr(900)}},
+			{Id: "2", TripUpdate: &realtime.TripUpdate{Trip: realtime.TripDescriptor{TripId: "early", StartDate: "20250108"}, Delay: int32Ptr(-1200)}},
+		},
+	})
+
+	testCases := []struct {
+		id       string
+		from     time.Time
+		until    time.Time
+		limit    int
+		expected []string
+	}{
+		{"sorted-by-expected-time", at(7, 0), at(9, 0), 10, []string{"next 08:10 false", "late 08:15 true", "early 08:50 true"}},
+		{"limit", at(7, 0), at(9, 0), 2, []string{"next 08:10 false", "late 08:15 true"}},
+		// The late journey is scheduled before the window, and the early one after it.
+		{"expected-in-window", at(8, 12), at(8, 55), 10, []string{"late 08:15 true", "early 08:50 true"}},
+		{"scheduled-in-window", at(7, 59), at(8, 1), 10, []string{}},
+	}
+
+	for _, tc := range testCases {
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, format(service.Departures("A", tc.from, tc.until, tc.limit)), tc.id)
+	}
+}
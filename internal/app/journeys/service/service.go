@@ -3,22 +3,33 @@ package service
 import "github.com/jlundan/journeys-api/internal/app/journeys/repository"
 
 func NewJourneysDataService(journeysRepository *repository.JourneysRepository) *JourneysDataService {
+	clock := &Clock{Location: journeysRepository.Timezone}
+	journeys := &JourneysService{Repository: journeysRepository, Clock: clock}
+
 	return &JourneysDataService{
+		Clock:           clock,
 		JourneyPatterns: &JourneyPatternsService{Repository: journeysRepository},
-		Journeys:        &JourneysService{Repository: journeysRepository},
+		Journeys:        journeys,
 		Lines:           &LinesService{Repository: journeysRepository},
 		Municipalities:  &MunicipalitiesService{Repository: journeysRepository},
+		Planner:         &PlannerService{Repository: journeysRepository, Journeys: journeys},
+		Realtime:        &RealtimeService{Journeys: journeys, Clock: clock},
 		Routes:          &RoutesService{Repository: journeysRepository},
 		StopPoints:      &StopPointsService{Repository: journeysRepository},
+		Tiles:           &TilesService{Repository: journeysRepository},
 	}
 
 }
 
 type JourneysDataService struct {
+	Clock           *Clock
 	JourneyPatterns *JourneyPatternsService
 	Journeys        *JourneysService
 	Lines           *LinesService
 	Municipalities  *MunicipalitiesService
+	Planner         *PlannerService
+	Realtime        *RealtimeService
 	Routes          *RoutesService
 	StopPoints      *StopPointsService
+	Tiles           *TilesService
 }
@@ -25,6 +25,26 @@ func (s StopPointsService) Search(params map[string]string) []*model.StopPoint {
 	return result
 }
 
+// Nearby returns the stop points within radius meters of the location which match the conditions in params, nearest
+// first.
+func (s StopPointsService) Nearby(lat float64, lon float64, radius float64, params map[string]string) []model.NearbyStopPoint {
+	result := make([]model.NearbyStopPoint, 0)
+
+	for _, nsp := range s.Repository.StopPoints.Index.Within(lat, lon, radius) {
+		if stopPointMatchesConditions(nsp.StopPoint, params) {
+			result = append(result, nsp)
+		}
+	}
+
+	return result
+}
+
+// SearchByName returns the stop points whose name or short name matches the query, the most relevant first. At most
+// limit matches are returned.
+func (s StopPointsService) SearchByName(query string, limit int) []model.StopPointMatch {
+	return s.Repository.StopPoints.NameIndex.Search(query, limit)
+}
+
 func (s StopPointsService) GetOneById(id string) (*model.StopPoint, error) {
 	if sp, ok := s.Repository.StopPoints.ById[id]; ok {
 		return sp, nil
@@ -60,7 +80,9 @@ func stopPointMatchesConditions(stopPoint *model.StopPoint, conditions map[strin
 				return false
 			}
 		case "location":
-			return stopPointLocationMatches(stopPoint, v)
+			if !stopPointLocationMatches(stopPoint, v) {
+				return false
+			}
 		}
 	}
 
@@ -120,6 +120,7 @@ func TestStopPointMatchesConditions2(t *testing.T) {
 		{"18", &model.StopPoint{Latitude: 60.0, Longitude: 24.0}, map[string]string{"location": "61.0,25.0:foo,26.0"}, false},
 		{"19", &model.StopPoint{Latitude: 60.0, Longitude: 24.0}, map[string]string{"location": "61.0,25.0:62.0,foo"}, false},
 		{"20", &model.StopPoint{Latitude: 60.0, Longitude: 24.0}, map[string]string{"location": "61.0,25.0"}, false},
+		{"21", &model.StopPoint{Name: "StopPoint1", Latitude: 60.0, Longitude: 24.0}, map[string]string{"location": "59.0,23.0:61.0,25.0", "name": "StopPoint2"}, false},
 	}
 
 	for _, tc := range testCases {
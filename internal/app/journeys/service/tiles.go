@@ -0,0 +1,174 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"math"
+	"sync"
+)
+
+// TileExtent is the size of a tile in tile coordinates.
+const TileExtent = 4096
+
+// MaxTileZoom is the deepest zoom level of the tiles.
+const MaxTileZoom = 20
+
+// MinStopPointTileZoom is the zoom level from which the stop points are included in the tiles. On the lower levels
+// there are too many of them to tell apart.
+const MinStopPointTileZoom = 13
+
+// tileBuffer is the margin in tile coordinates around a tile within which the features are still included, so that
+// the lines and the symbols crossing the tile border are drawn seamlessly.
+const tileBuffer = 64
+
+const earthCircumference = 40075016.686
+
+// TileFeatures are the stop points and the route shapes on a tile, in tile coordinates.
+type TileFeatures struct {
+	StopPoints []TileStopPoint
+	Routes     []TileRoute
+}
+
+type TileStopPoint struct {
+	StopPoint *model.StopPoint
+	X, Y      int
+}
+
+// TileRoute is a route whose shape crosses a tile. Parts are the pieces of the shape inside the buffered tile, there
+// may be several if the shape leaves the tile and comes back.
+type TileRoute struct {
+	Route *model.Route
+	Parts [][][2]int
+}
+
+type TilesService struct {
+	Repository *repository.JourneysRepository
+
+	once sync.Once
+	// bounds are the bounding boxes of the shapes of the routes, minLat, minLon, maxLat, maxLon.
+	bounds map[*model.Route][4]float64
+	// tolerances are the simplification tolerances of the points of the shapes of the routes, so that the shapes are
+	// simplified to the resolution of any tile without running the simplification on every request.
+	tolerances map[*model.Route][]float64
+}
+
+// Tile returns the features on the tile x,y of zoom level z, in the Web Mercator tiling scheme. The shapes of the
+// routes are simplified to the resolution of the tile.
+func (s *TilesService) Tile(z int, x int, y int) TileFeatures {
+	s.once.Do(s.initialize)
+
+	project := func(lat float64, lon float64) [2]int {
+		tx, ty := tileCoordinates(z, lat, lon)
+		return [2]int{int(math.Round((tx - float64(x)) * TileExtent)), int(math.Round((ty - float64(y)) * TileExtent))}
+	}
+
+	buffer := float64(tileBuffer) / TileExtent
+	maxLat, minLon := tileLatLon(z, float64(x)-buffer, float64(y)-buffer)
+	minLat, maxLon := tileLatLon(z, float64(x+1)+buffer, float64(y+1)+buffer)
+
+	result := TileFeatures{StopPoints: make([]TileStopPoint, 0), Routes: make([]TileRoute, 0)}
+
+	if z >= MinStopPointTileZoom {
+		for _, sp := range s.Repository.StopPoints.All {
+			if sp.Latitude < minLat || sp.Latitude > maxLat || sp.Longitude < minLon || sp.Longitude > maxLon {
+				continue
+			}
+			p := project(sp.Latitude, sp.Longitude)
+			result.StopPoints = append(result.StopPoints, TileStopPoint{StopPoint: sp, X: p[0], Y: p[1]})
+		}
+	}
+
+	// One tile coordinate in meters at the latitude of the tile.
+	tolerance := earthCircumference * math.Cos((minLat+maxLat)/2*math.Pi/180) / (math.Exp2(float64(z)) * TileExtent)
+
+	for _, route := range s.Repository.Routes.All {
+		b, ok := s.bounds[route]
+		if !ok || b[0] > maxLat || b[2] < minLat || b[1] > maxLon || b[3] < minLon {
+			continue
+		}
+
+		var points [][2]int
+		for i, sp := range route.Shape {
+			if s.tolerances[route][i] <= tolerance {
+				continue
+			}
+			p := project(sp.Latitude, sp.Longitude)
+			if len(points) == 0 || points[len(points)-1] != p {
+				points = append(points, p)
+			}
+		}
+
+		if parts := clipTileLine(points); len(parts) > 0 {
+			result.Routes = append(result.Routes, TileRoute{Route: route, Parts: parts})
+		}
+	}
+
+	return result
+}
+
+func (s *TilesService) initialize() {
+	s.bounds = make(map[*model.Route][4]float64)
+	s.tolerances = make(map[*model.Route][]float64)
+
+	for _, route := range s.Repository.Routes.All {
+		if len(route.Shape) < 2 {
+			continue
+		}
+
+		b := [4]float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+		coords := make([][]float64, 0, len(route.Shape))
+		for _, p := range route.Shape {
+			b[0], b[1] = math.Min(b[0], p.Latitude), math.Min(b[1], p.Longitude)
+			b[2], b[3] = math.Max(b[2], p.Latitude), math.Max(b[3], p.Longitude)
+			coords = append(coords, []float64{p.Latitude, p.Longitude})
+		}
+		s.bounds[route] = b
+		s.tolerances[route] = utils.SimplificationTolerances(coords)
+	}
+}
+
+// clipTileLine splits a line in tile coordinates to the runs of its segments which touch the buffered tile. A segment
+// is kept if its bounding box overlaps the buffered tile, which keeps every segment crossing the tile and only a few
+// which pass by its corners.
+func clipTileLine(points [][2]int) [][][2]int {
+	var result [][][2]int
+	var part [][2]int
+
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		if min(a[0], b[0]) > TileExtent+tileBuffer || max(a[0], b[0]) < -tileBuffer ||
+			min(a[1], b[1]) > TileExtent+tileBuffer || max(a[1], b[1]) < -tileBuffer {
+			if len(part) > 0 {
+				result = append(result, part)
+				part = nil
+			}
+			continue
+		}
+
+		if len(part) == 0 {
+			part = append(part, a)
+		}
+		part = append(part, b)
+	}
+
+	if len(part) > 0 {
+		result = append(result, part)
+	}
+
+	return result
+}
+
+// tileCoordinates returns the position of the coordinate in the tile grid of zoom level z. The integer parts are the
+// x and y of the tile, and the fractions the position inside the tile.
+func tileCoordinates(z int, lat float64, lon float64) (float64, float64) {
+	n := math.Exp2(float64(z))
+	latRad := lat * math.Pi / 180
+	return (lon + 180) / 360 * n, (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+}
+
+// tileLatLon is the inverse of tileCoordinates.
+func tileLatLon(z int, x float64, y float64) (float64, float64) {
+	n := math.Exp2(float64(z))
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi, x/n*360 - 180
+}
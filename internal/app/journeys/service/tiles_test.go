@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"math"
+	"testing"
+)
+
+func TestTilesService_Tile(t *testing.T) {
+	central := &model.StopPoint{ShortName: "0001", Latitude: 61.4981, Longitude: 23.7610}
+	far := &model.StopPoint{ShortName: "0002", Latitude: 61.5200, Longitude: 23.9000}
+
+	line := &model.Line{Name: "1"}
+	crossing := &model.Route{Id: "crossing", Line: line, Shape: []model.ShapePoint{
+		{Latitude: 61.4981, Longitude: 23.7610},
+		{Latitude: 61.4990, Longitude: 23.7700},
+		{Latitude: 61.5100, Longitude: 23.8000},
+		{Latitude: 61.5200, Longitude: 23.9000},
+	}}
+	east := &model.Route{Id: "east", Line: line, Shape: []model.ShapePoint{
+		{Latitude: 61.5100, Longitude: 23.8000},
+		{Latitude: 61.5200, Longitude: 23.9000},
+	}}
+
+	service := &TilesService{Repository: &repository.JourneysRepository{
+		StopPoints: &repository.JourneysStopPointsRepository{All: []*model.StopPoint{central, far}},
+		Routes:     &repository.JourneysRoutesRepository{All: []*model.Route{crossing, east}},
+	}}
+
+	testCases := []struct {
+		id       string
+		z, x, y  int
+		expected []string
+	}{
+		{"stop-points-and-routes", 14, 9273, 4618, []string{"stop 0001 1595,1420", "route crossing [[[1595 1420] [3273 1068] [8866 -3230]]]"}},
+		{"stop-points-hidden", 12, 2318, 1154, []string{"route crossing [[[1423 2403] [1842 2315] [3240 1241] [7901 263]]]", "route east [[[3240 1241] [7901 263]]]"}},
+		{"empty", 14, 0, 0, []string{}},
+	}
+
+	for _, tc := range testCases {
+		features := service.Tile(tc.z, tc.x, tc.y)
+
+		got := make([]string, 0)
+		for _, sp := range features.StopPoints {
+			got = append(got, fmt.Sprintf("stop %v %v,%v", sp.StopPoint.ShortName, sp.X, sp.Y))
+		}
+		for _, r := range features.Routes {
+			got = append(got, fmt.Sprintf("route %v %v", r.Route.Id, r.Parts))
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, got, tc.id)
+	}
+}
+
+func TestClipTileLine(t *testing.T) {
+	testCases := []struct {
+		id       string
+		points   [][2]int
+		expected [][][2]int
+	}{
+		{"inside", [][2]int{{10, 10}, {20, 20}}, [][][2]int{{{10, 10}, {20, 20}}}},
+		{"outside", [][2]int{{-500, -500}, {-100, -500}}, nil},
+		{"crossing", [][2]int{{-500, 10}, {5000, 10}}, [][][2]int{{{-500, 10}, {5000, 10}}}},
+		{"leaving-and-returning", [][2]int{{10, 10}, {10, -500}, {-500, -500}, {-500, 10}, {10, 20}},
+			[][][2]int{{{10, 10}, {10, -500}}, {{-500, 10}, {10, 20}}}},
+	}
+
+	for _, tc := range testCases {
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, clipTileLine(tc.points), tc.id)
+	}
+}
+
+func TestTileCoordinates(t *testing.T) {
+	x, y := tileCoordinates(14, 61.4981, 23.7610)
+	if int(x) != 9273 || int(y) != 4618 {
+		t.Errorf("expected tile 9273,4618, got %v,%v", x, y)
+	}
+
+	lat, lon := tileLatLon(14, x, y)
+	if math.Abs(lat-61.4981) > 1e-9 || math.Abs(lon-23.7610) > 1e-9 {
+		t.Errorf("expected 61.4981,23.7610, got %v,%v", lat, lon)
+	}
+}
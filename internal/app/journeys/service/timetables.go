@@ -0,0 +1,160 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/utils"
+	"sort"
+	"time"
+)
+
+// Timetables returns the timetables of the line on the date, one per direction sorted by direction. If direction is
+// not empty, only the timetable of that direction is returned.
+func (s JourneysService) Timetables(lineName string, date time.Time, direction string) []*model.Timetable {
+	byDirection := make(map[string][]*model.Journey)
+	for _, journey := range s.Repository.Journeys.All {
+		if journey.Line == nil || journey.Line.Name != lineName || len(journey.Calls) == 0 {
+			continue
+		}
+		if direction != "" && journey.Direction != direction {
+			continue
+		}
+		if s.RunsOn(journey, date) {
+			byDirection[journey.Direction] = append(byDirection[journey.Direction], journey)
+		}
+	}
+
+	directions := make([]string, 0, len(byDirection))
+	for d := range byDirection {
+		directions = append(directions, d)
+	}
+	sort.Strings(directions)
+
+	result := make([]*model.Timetable, 0, len(directions))
+	for _, d := range directions {
+		result = append(result, newTimetable(byDirection[d], d, date))
+	}
+
+	return result
+}
+
+func newTimetable(journeys []*model.Journey, direction string, date time.Time) *model.Timetable {
+	sort.SliceStable(journeys, func(x, y int) bool {
+		dx, _ := utils.ParseGtfsTime(journeys[x].Calls[0].DepartureTime)
+		dy, _ := utils.ParseGtfsTime(journeys[y].Calls[0].DepartureTime)
+		if dx != dy {
+			return dx < dy
+		}
+		return journeys[x].Id < journeys[y].Id
+	})
+
+	stopPoints := mergeStopSequences(stopSequences(journeys))
+
+	calls := make([][]*model.JourneyCall, len(stopPoints))
+	for i := range calls {
+		calls[i] = make([]*model.JourneyCall, len(journeys))
+	}
+
+	for column, journey := range journeys {
+		row := -1
+		for _, c := range journey.Calls {
+			i := nextIndexOf(stopPoints, c.StopPoint, row)
+			if i == len(stopPoints) {
+				continue
+			}
+			row = i
+			calls[row][column] = c
+		}
+	}
+
+	return &model.Timetable{
+		Line:        journeys[0].Line,
+		Direction:   direction,
+		ServiceDate: date,
+		StopPoints:  stopPoints,
+		Journeys:    journeys,
+		Calls:       calls,
+	}
+}
+
+// stopSequences returns the distinct stop sequences of the journeys, the sequences used by the most journeys first.
+// Journeys without a journey pattern contribute the stop points of their calls.
+func stopSequences(journeys []*model.Journey) [][]*model.StopPoint {
+	var sequences [][]*model.StopPoint
+	counts := make(map[string]int)
+	keys := make(map[string]int)
+
+	for _, journey := range journeys {
+		key := journey.Id
+		var sequence []*model.StopPoint
+		if journey.JourneyPattern != nil {
+			key = journey.JourneyPattern.Id
+			sequence = journey.JourneyPattern.StopPoints
+		}
+		if len(sequence) != len(journey.Calls) {
+			sequence = make([]*model.StopPoint, 0, len(journey.Calls))
+			for _, c := range journey.Calls {
+				sequence = append(sequence, c.StopPoint)
+			}
+		}
+
+		if _, ok := keys[key]; !ok {
+			keys[key] = len(sequences)
+			sequences = append(sequences, sequence)
+		}
+		counts[key]++
+	}
+
+	order := make([]string, 0, len(keys))
+	for k := range keys {
+		order = append(order, k)
+	}
+	sort.Slice(order, func(x, y int) bool {
+		if counts[order[x]] != counts[order[y]] {
+			return counts[order[x]] > counts[order[y]]
+		}
+		return keys[order[x]] < keys[order[y]]
+	})
+
+	result := make([][]*model.StopPoint, 0, len(order))
+	for _, k := range order {
+		result = append(result, sequences[keys[k]])
+	}
+
+	return result
+}
+
+// mergeStopSequences merges the stop sequences into one sequence which contains every sequence in order. Stop
+// points missing from the merged sequence are inserted after the previous stop point of their own sequence, so
+// branches are placed next to the stop where they diverge. A stop point visited twice by a sequence, as on loop
+// routes, appears twice in the merged sequence.
+func mergeStopSequences(sequences [][]*model.StopPoint) []*model.StopPoint {
+	var merged []*model.StopPoint
+
+	for _, sequence := range sequences {
+		position := -1
+		for _, sp := range sequence {
+			if i := nextIndexOf(merged, sp, position); i < len(merged) {
+				position = i
+				continue
+			}
+
+			position++
+			merged = append(merged, nil)
+			copy(merged[position+1:], merged[position:])
+			merged[position] = sp
+		}
+	}
+
+	return merged
+}
+
+// nextIndexOf returns the index of the first occurrence of the stop point after the index after. If there is none,
+// it returns len(stopPoints).
+func nextIndexOf(stopPoints []*model.StopPoint, stopPoint *model.StopPoint, after int) int {
+	for i := after + 1; i < len(stopPoints); i++ {
+		if stopPoints[i] == stopPoint {
+			return i
+		}
+	}
+	return len(stopPoints)
+}
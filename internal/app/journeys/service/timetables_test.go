@@ -0,0 +1,106 @@
+package service
+
+import (
+	"github.com/jlundan/journeys-api/internal/app/journeys/model"
+	"github.com/jlundan/journeys-api/internal/app/journeys/repository"
+	"github.com/jlundan/journeys-api/internal/testutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeStopSequences(t *testing.T) {
+	stopPoints := make(map[string]*model.StopPoint)
+	sequence := func(ids string) []*model.StopPoint {
+		var result []*model.StopPoint
+		for _, id := range strings.Split(ids, " ") {
+			if _, ok := stopPoints[id]; !ok {
+				stopPoints[id] = &model.StopPoint{ShortName: id}
+			}
+			result = append(result, stopPoints[id])
+		}
+		return result
+	}
+
+	testCases := []struct {
+		id        string
+		sequences []string
+		expected  string
+	}{
+		{"single", []string{"A B C"}, "A B C"},
+		{"short-turn", []string{"A B C D", "B C"}, "A B C D"},
+		{"extension", []string{"A B C", "A B C D E"}, "A B C D E"},
+		{"branch", []string{"A B C D", "A B X Y"}, "A B X Y C D"},
+		{"detour", []string{"A B C D", "A B X C D"}, "A B X C D"},
+		{"loop", []string{"A B C A"}, "A B C A"},
+		{"reversed-order", []string{"A B C", "C B"}, "A B C B"},
+	}
+
+	for _, tc := range testCases {
+		var sequences [][]*model.StopPoint
+		for _, s := range tc.sequences {
+			sequences = append(sequences, sequence(s))
+		}
+
+		var ids []string
+		for _, sp := range mergeStopSequences(sequences) {
+			ids = append(ids, sp.ShortName)
+		}
+		testutil.CompareVariablesAndPrintResults(t, tc.expected, strings.Join(ids, " "), tc.id)
+	}
+}
+
+func TestJourneysService_Timetables(t *testing.T) {
+	line := &model.Line{Name: "1"}
+	a, b, c, x := &model.StopPoint{ShortName: "A"}, &model.StopPoint{ShortName: "B"}, &model.StopPoint{ShortName: "C"}, &model.StopPoint{ShortName: "X"}
+
+	full := &model.JourneyPattern{Id: "full", StopPoints: []*model.StopPoint{a, b, c}}
+	branch := &model.JourneyPattern{Id: "branch", StopPoints: []*model.StopPoint{a, x, c}}
+
+	journey := func(id string, direction string, jp *model.JourneyPattern, times ...string) *model.Journey {
+		j := &model.Journey{Id: id, Line: line, Direction: direction, JourneyPattern: jp, ValidFrom: "2025-01-01", ValidTo: "2025-12-31"}
+		for i, sp := range jp.StopPoints {
+			j.Calls = append(j.Calls, &model.JourneyCall{DepartureTime: times[i], ArrivalTime: times[i], StopPoint: sp})
+		}
+		return j
+	}
+
+	service := JourneysService{Repository: &repository.JourneysRepository{Journeys: &repository.JourneysJourneyRepository{All: []*model.Journey{
+		journey("late", "0", full, "09:00:00", "09:10:00", "09:20:00"),
+		journey("early", "0", full, "08:00:00", "08:10:00", "08:20:00"),
+		journey("branch", "0", branch, "08:30:00", "08:35:00", "08:40:00"),
+		journey("back", "1", &model.JourneyPattern{Id: "back", StopPoints: []*model.StopPoint{c, b, a}}, "10:00:00", "10:10:00", "10:20:00"),
+		{Id: "other-line", Line: &model.Line{Name: "2"}, Direction: "0", Calls: []*model.JourneyCall{{StopPoint: a}}, ValidFrom: "2025-01-01", ValidTo: "2025-12-31"},
+	}}}}
+
+	date := time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC)
+
+	timetables := service.Timetables("1", date, "")
+	testutil.CompareVariablesAndPrintResults(t, 2, len(timetables), "directions")
+
+	outbound := timetables[0]
+	testutil.CompareVariablesAndPrintResults(t, "0", outbound.Direction, "direction")
+
+	var rows []string
+	for i, sp := range outbound.StopPoints {
+		row := []string{sp.ShortName}
+		for _, call := range outbound.Calls[i] {
+			if call == nil {
+				row = append(row, "-")
+			} else {
+				row = append(row, call.DepartureTime)
+			}
+		}
+		rows = append(rows, strings.Join(row, " "))
+	}
+
+	testutil.CompareVariablesAndPrintResults(t, []string{
+		"A 08:00:00 08:30:00 09:00:00",
+		"X - 08:35:00 -",
+		"B 08:10:00 - 09:10:00",
+		"C 08:20:00 08:40:00 09:20:00",
+	}, rows, "matrix")
+
+	testutil.CompareVariablesAndPrintResults(t, 1, len(service.Timetables("1", date, "1")), "direction-filter")
+	testutil.CompareVariablesAndPrintResults(t, 0, len(service.Timetables("1", date.AddDate(1, 0, 0), "")), "outside-validity")
+}
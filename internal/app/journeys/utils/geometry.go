@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/jlundan/journeys-api/pkg/ggtfs"
+	"math"
+	"sort"
+	"strings"
+)
+
+const metersPerDegreeLatitude = 111320.0
+
+// EncodeCoordinateProjection encodes lat,lon coordinates in the delta format of the ITS Factory Journeys API. The
+// first coordinate is given in 1/100000 degrees, and every following one as its difference to the previous one,
+// previous minus current.
+func EncodeCoordinateProjection(coords [][]float64) string {
+	var projection strings.Builder
+	var lastLat int64
+	var lastLon int64
+
+	for k, v := range coords {
+		lat := int64(v[0] * 100000)
+		lon := int64(v[1] * 100000)
+		if k == 0 {
+			projection.WriteString(fmt.Sprintf("%v,%v", lat, lon))
+		} else {
+			projection.WriteString(fmt.Sprintf(":%v,%v", lastLat-lat, lastLon-lon))
+		}
+
+		lastLat = lat
+		lastLon = lon
+	}
+
+	return projection.String()
+}
+
+// EncodePolyline encodes lat,lon coordinates with the Encoded Polyline Algorithm Format of Google, rounding them to
+// precision decimals. The common precision is 5, some routing engines use 6.
+func EncodePolyline(coords [][]float64, precision int) string {
+	factor := math.Pow(10, float64(precision))
+
+	var polyline strings.Builder
+	var lastLat int64
+	var lastLon int64
+
+	for _, v := range coords {
+		lat := int64(math.Round(v[0] * factor))
+		lon := int64(math.Round(v[1] * factor))
+
+		encodePolylineValue(&polyline, lat-lastLat)
+		encodePolylineValue(&polyline, lon-lastLon)
+
+		lastLat = lat
+		lastLon = lon
+	}
+
+	return polyline.String()
+}
+
+func encodePolylineValue(polyline *strings.Builder, value int64) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+
+	for v >= 0x20 {
+		polyline.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	polyline.WriteByte(byte(v + 63))
+}
+
+// SimplifyCoordinates simplifies a line of lat,lon coordinates with the Douglas-Peucker algorithm. The points which
+// are at most tolerance meters from the simplified line are removed. The first and the last point are always kept.
+func SimplifyCoordinates(coords [][]float64, tolerance float64) [][]float64 {
+	if tolerance <= 0 || len(coords) < 3 {
+		return coords
+	}
+
+	keep := make([]bool, len(coords))
+	keep[0], keep[len(coords)-1] = true, true
+
+	type span struct{ first, last int }
+	stack := []span{{0, len(coords) - 1}}
+
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := -1, tolerance
+		for i := s.first + 1; i < s.last; i++ {
+			if d := distanceToSegment(coords[i], coords[s.first], coords[s.last]); d > distance {
+				farthest, distance = i, d
+			}
+		}
+
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, span{s.first, farthest}, span{farthest, s.last})
+		}
+	}
+
+	result := make([][]float64, 0)
+	for i, c := range coords {
+		if keep[i] {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// SimplificationTolerances returns for each point of a line of lat,lon coordinates the largest tolerance with which
+// SimplifyCoordinates removes it. SimplifyCoordinates keeps exactly the points whose tolerance is greater than its
+// tolerance, so the line can be simplified with any tolerance without running the algorithm again. The first and
+// the last point have an infinite tolerance.
+func SimplificationTolerances(coords [][]float64) []float64 {
+	result := make([]float64, len(coords))
+	if len(coords) == 0 {
+		return result
+	}
+	result[0], result[len(coords)-1] = math.Inf(1), math.Inf(1)
+
+	// A point split off a span is only kept while the point splitting the span itself is, so its tolerance is bound
+	// by the one of the span.
+	type span struct {
+		first, last int
+		tolerance   float64
+	}
+	stack := []span{{0, len(coords) - 1, math.Inf(1)}}
+
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := -1, 0.0
+		for i := s.first + 1; i < s.last; i++ {
+			if d := distanceToSegment(coords[i], coords[s.first], coords[s.last]); d > distance {
+				farthest, distance = i, d
+			}
+		}
+
+		if farthest >= 0 {
+			tolerance := math.Min(distance, s.tolerance)
+			result[farthest] = tolerance
+			stack = append(stack, span{s.first, farthest, tolerance}, span{farthest, s.last, tolerance})
+		}
+	}
+
+	return result
+}
+
+// LinePosition is a position on a line of lat,lon coordinates: the index of a segment of the line, and the fraction of
+// the segment from its first point.
+type LinePosition struct {
+	Segment  int
+	Fraction float64
+}
+
+// LocateOnLine places the points on the line, in order. Each point is placed on one of the segments of the line,
+// the positions never going backwards, so that the total distance from the points to their positions is the
+// smallest. Placing the points together rather than one by one keeps them in order on lines which pass a point more
+// than once, such as loops. The line must have at least two coordinates.
+func LocateOnLine(coords [][]float64, points [][]float64) []LinePosition {
+	segments := len(coords) - 1
+	if segments < 1 || len(points) == 0 {
+		return make([]LinePosition, len(points))
+	}
+
+	// fractions[k][i] is the position of the point k on the segment i, and cost[k][i] the smallest total distance
+	// of the points up to k with the point k on the segment i. from[k][i] is the segment of the point k-1 then.
+	fractions := make([][]float64, len(points))
+	cost := make([][]float64, len(points))
+	from := make([][]int, len(points))
+
+	for k, p := range points {
+		fractions[k] = make([]float64, segments)
+		cost[k] = make([]float64, segments)
+		from[k] = make([]int, segments)
+
+		best, bestSegment := math.MaxFloat64, 0
+		for i := 0; i < segments; i++ {
+			if k > 0 && cost[k-1][i] < best {
+				best, bestSegment = cost[k-1][i], i
+			}
+
+			fractions[k][i] = projectOntoSegment(p, coords[i], coords[i+1])
+			cost[k][i] = distanceToSegment(p, coords[i], coords[i+1])
+			if k > 0 {
+				cost[k][i] += best
+				from[k][i] = bestSegment
+			}
+		}
+	}
+
+	last := len(points) - 1
+	segment := 0
+	for i := 1; i < segments; i++ {
+		if cost[last][i] < cost[last][segment] {
+			segment = i
+		}
+	}
+
+	result := make([]LinePosition, len(points))
+	for k := last; k >= 0; k-- {
+		result[k] = LinePosition{Segment: segment, Fraction: fractions[k][segment]}
+		segment = from[k][segment]
+	}
+
+	// Two points placed on the same segment may still be in the wrong order within it.
+	for k := 1; k < len(result); k++ {
+		if result[k].Segment == result[k-1].Segment && result[k].Fraction < result[k-1].Fraction {
+			result[k].Fraction = result[k-1].Fraction
+		}
+	}
+
+	return result
+}
+
+// CutLine returns the part of the line from the position from to the position to.
+func CutLine(coords [][]float64, from LinePosition, to LinePosition) [][]float64 {
+	if len(coords) < 2 {
+		return coords
+	}
+
+	result := [][]float64{interpolate(coords[from.Segment], coords[from.Segment+1], from.Fraction)}
+	add := func(c []float64) {
+		if last := result[len(result)-1]; last[0] != c[0] || last[1] != c[1] {
+			result = append(result, c)
+		}
+	}
+
+	for i := from.Segment + 1; i <= to.Segment; i++ {
+		add(coords[i])
+	}
+	add(interpolate(coords[to.Segment], coords[to.Segment+1], to.Fraction))
+
+	return result
+}
+
+// LocateByDistance returns the position on a line at the distance d, given the distances of the coordinates of the
+// line from its start in non-decreasing order, such as the shape_dist_traveled values of a GTFS shape. Distances
+// outside the line are placed at its ends.
+func LocateByDistance(distances []float64, d float64) LinePosition {
+	if len(distances) < 2 || d <= distances[0] {
+		return LinePosition{}
+	}
+
+	i := sort.SearchFloat64s(distances, d)
+	if i >= len(distances) {
+		return LinePosition{Segment: len(distances) - 2, Fraction: 1}
+	}
+
+	// distances[i-1] < d <= distances[i]
+	return LinePosition{Segment: i - 1, Fraction: (d - distances[i-1]) / (distances[i] - distances[i-1])}
+}
+
+// CumulativeLengths returns the distance of each coordinate of the line from its start in meters.
+func CumulativeLengths(coords [][]float64) []float64 {
+	result := make([]float64, len(coords))
+	for i := 1; i < len(coords); i++ {
+		result[i] = result[i-1] + ggtfs.HaversineDistance(coords[i-1][0], coords[i-1][1], coords[i][0], coords[i][1])
+	}
+	return result
+}
+
+// LengthAt returns the distance of the position from the start of the line, given the cumulative lengths of the
+// line.
+func LengthAt(lengths []float64, p LinePosition) float64 {
+	if p.Segment+1 >= len(lengths) {
+		return lengths[len(lengths)-1]
+	}
+	return lengths[p.Segment] + (lengths[p.Segment+1]-lengths[p.Segment])*p.Fraction
+}
+
+// LineLength returns the length of the line in meters.
+func LineLength(coords [][]float64) float64 {
+	var length float64
+	for i := 0; i+1 < len(coords); i++ {
+		length += ggtfs.HaversineDistance(coords[i][0], coords[i][1], coords[i+1][0], coords[i+1][1])
+	}
+	return length
+}
+
+func interpolate(a []float64, b []float64, t float64) []float64 {
+	if t <= 0 {
+		return a
+	}
+	if t >= 1 {
+		return b
+	}
+	return []float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+}
+
+// projectOntoSegment returns the fraction of the segment from a to b at which the point of the segment nearest to p
+// is. The point is found on an equirectangular projection centered at p, which is accurate for the short segments of
+// a shape.
+func projectOntoSegment(p []float64, a []float64, b []float64) float64 {
+	ax, ay, bx, by := projectAround(p, a, b)
+	dx, dy := bx-ax, by-ay
+
+	if l := dx*dx + dy*dy; l > 0 {
+		return math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+	return 0
+}
+
+// distanceToSegment returns the distance in meters from p to the segment from a to b, on the projection of
+// projectOntoSegment.
+func distanceToSegment(p []float64, a []float64, b []float64) float64 {
+	ax, ay, bx, by := projectAround(p, a, b)
+	t := projectOntoSegment(p, a, b)
+
+	return math.Hypot(ax+t*(bx-ax), ay+t*(by-ay))
+}
+
+// projectAround returns a and b in meters on an equirectangular projection centered at p.
+func projectAround(p []float64, a []float64, b []float64) (float64, float64, float64, float64) {
+	metersPerDegreeLongitude := metersPerDegreeLatitude * math.Cos(p[0]*math.Pi/180)
+	project := func(c []float64) (float64, float64) {
+		return (c[1] - p[1]) * metersPerDegreeLongitude, (c[0] - p[0]) * metersPerDegreeLatitude
+	}
+
+	ax, ay := project(a)
+	bx, by := project(b)
+	return ax, ay, bx, by
+}
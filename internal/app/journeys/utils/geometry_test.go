@@ -0,0 +1,126 @@
+//go:build utils_tests || all_tests
+
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeCoordinateProjection(t *testing.T) {
+	coords := [][]float64{{61.46557, 23.64305}, {61.46576, 23.64453}, {61.46593, 23.64644}}
+
+	if got := EncodeCoordinateProjection(coords); got != "6146557,2364305:-19,-148:-17,-191" {
+		t.Errorf("unexpected projection %v", got)
+	}
+
+	if got := EncodeCoordinateProjection(nil); got != "" {
+		t.Errorf("expected an empty projection, got %v", got)
+	}
+}
+
+func TestEncodePolyline(t *testing.T) {
+	coords := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+
+	if got := EncodePolyline(coords, 5); got != "_p~iF~ps|U_ulLnnqC_mqNvxq`@" {
+		t.Errorf("unexpected polyline %v", got)
+	}
+
+	coords = [][]float64{{61.47590, 23.97764}, {61.47694, 23.97732}}
+
+	if got := EncodePolyline(coords, 6); got != "wbegtBoinvl@_`A~R" {
+		t.Errorf("unexpected polyline %v", got)
+	}
+}
+
+func TestSimplifyCoordinates(t *testing.T) {
+	coords := [][]float64{{61.0, 23.0}, {61.0001, 23.001}, {61.0, 23.002}, {61.0, 23.003}}
+
+	if got := SimplifyCoordinates(coords, 0); !reflect.DeepEqual(got, coords) {
+		t.Errorf("expected the coordinates unchanged, got %v", got)
+	}
+
+	// The second point is about 11 meters off the line from the first point to the last one, and the third one
+	// about 6 meters off the line from the second point to the last one.
+	expected := [][]float64{{61.0, 23.0}, {61.0001, 23.001}, {61.0, 23.003}}
+	if got := SimplifyCoordinates(coords, 8); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	expected = [][]float64{{61.0, 23.0}, {61.0, 23.003}}
+	if got := SimplifyCoordinates(coords, 20); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSimplificationTolerances(t *testing.T) {
+	coords := [][]float64{{61.0, 23.0}, {61.0001, 23.001}, {61.0, 23.002}, {61.00005, 23.003}, {61.0, 23.004}, {61.0, 23.005}}
+	tolerances := SimplificationTolerances(coords)
+
+	for _, tolerance := range []float64{1, 4, 8, 11, 20} {
+		var got [][]float64
+		for i, c := range coords {
+			if tolerances[i] > tolerance {
+				got = append(got, c)
+			}
+		}
+		if expected := SimplifyCoordinates(coords, tolerance); !reflect.DeepEqual(got, expected) {
+			t.Errorf("tolerance %v: expected %v, got %v", tolerance, expected, got)
+		}
+	}
+}
+
+func TestLocateOnLine(t *testing.T) {
+	// A line east and back west, 0.0002 degrees north.
+	line := [][]float64{{61.0, 23.0}, {61.0, 23.01}, {61.0002, 23.01}, {61.0002, 23.0}}
+
+	testCases := []struct {
+		id       string
+		points   [][]float64
+		expected []LinePosition
+	}{
+		{"in-order", [][]float64{{61.0, 23.0}, {61.0, 23.005}, {61.0002, 23.005}}, []LinePosition{{0, 0}, {0, 0.5}, {2, 0.5}}},
+		{"loop", [][]float64{{61.00009, 23.0}, {61.00011, 23.0}}, []LinePosition{{0, 0}, {2, 1}}},
+		{"same-segment", [][]float64{{61.0, 23.006}, {61.0, 23.004}}, []LinePosition{{0, 0.6}, {0, 0.6}}},
+	}
+
+	for _, tc := range testCases {
+		got := LocateOnLine(line, tc.points)
+		if len(got) != len(tc.expected) {
+			t.Fatalf("%v: expected %v, got %v", tc.id, tc.expected, got)
+		}
+		for i := range got {
+			if got[i].Segment != tc.expected[i].Segment || math.Abs(got[i].Fraction-tc.expected[i].Fraction) > 1e-6 {
+				t.Errorf("%v: expected %v, got %v", tc.id, tc.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestCutLine(t *testing.T) {
+	line := [][]float64{{61.0, 23.0}, {61.0, 23.01}, {61.0002, 23.01}, {61.0002, 23.0}}
+
+	expected := [][]float64{{61.0, 23.0025}, {61.0, 23.01}, {61.0002, 23.01}, {61.0002, 23.0025}}
+	if got := CutLine(line, LinePosition{0, 0.25}, LinePosition{2, 0.75}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	expected = [][]float64{{61.0, 23.01}, {61.0002, 23.01}}
+	if got := CutLine(line, LinePosition{0, 1}, LinePosition{1, 1}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestLineLength(t *testing.T) {
+	line := [][]float64{{61.0, 23.0}, {61.0, 23.01}, {61.0002, 23.01}}
+
+	if got := math.Round(LineLength(line)); got != 561 {
+		t.Errorf("expected 561, got %v", got)
+	}
+
+	if got := LineLength(line[:1]); got != 0 {
+		t.Errorf("expected 0, got %v", got)
+	}
+}
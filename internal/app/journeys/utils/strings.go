@@ -1,7 +1,55 @@
 package utils
 
-import "strings"
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
 
 func StrContains(str1 string, str2 string) bool {
 	return strings.Contains(strings.ToLower(str1), strings.ToLower(str2))
 }
+
+// FoldString lowercases the string and removes its diacritics, so that "Hämeenkatu" becomes "hameenkatu".
+func FoldString(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// EditDistance returns the number of single character insertions, deletions, substitutions and transpositions of
+// adjacent characters needed to turn a into b.
+func EditDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Three rows of the distance matrix are enough, the transpositions look two rows back.
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(rb)]
+}
@@ -15,3 +15,45 @@ func TestStrContains(t *testing.T) {
 		t.Error("expected false, got true")
 	}
 }
+
+func TestFoldString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Hämeenkatu", "hameenkatu"},
+		{"Näyttelijänkatu", "nayttelijankatu"},
+		{"Åbo Östra", "abo ostra"},
+		{"Café", "cafe"},
+		{"Keskustori H", "keskustori h"},
+	}
+
+	for _, tt := range tests {
+		if got := FoldString(tt.value); got != tt.expected {
+			t.Errorf("FoldString(%q): expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"hameenkatu", "hameenkatu", 0},
+		{"hamenkatu", "hameenkatu", 1},
+		{"hameenaktu", "hameenkatu", 1},
+		{"hamenaktu", "hameenkatu", 2},
+		{"kissa", "koira", 3},
+	}
+
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("EditDistance(%q, %q): expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
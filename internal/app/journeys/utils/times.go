@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidGtfsTime = errors.New("invalid GTFS time")
+
+// ParseGtfsTime parses a GTFS time of day, "HH:MM:SS" or "HH:MM", into the offset from the start of the service
+// day. GTFS times may exceed 24:00:00 for trips which continue past midnight.
+func ParseGtfsTime(value string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, ErrInvalidGtfsTime
+	}
+
+	var values [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 || (i > 0 && v > 59) {
+			return 0, ErrInvalidGtfsTime
+		}
+		values[i] = v
+	}
+
+	return time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute + time.Duration(values[2])*time.Second, nil
+}
+
+// TimeOfDay returns the time of day of an offset from the start of the service day, wrapping the offsets past
+// midnight to the next day.
+func TimeOfDay(offset time.Duration) time.Duration {
+	return offset % (24 * time.Hour)
+}
+
+// FormatGtfsTime formats an offset from the start of the service day as a GTFS time of day, "HH:MM:SS". The hours go
+// past 24 for the offsets past midnight, and the negative offsets are formatted as the start of the service day.
+func FormatGtfsTime(offset time.Duration) string {
+	seconds := int(max(offset, 0) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
@@ -0,0 +1,63 @@
+//go:build utils_tests || all_tests
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGtfsTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"07:05:30", 7*time.Hour + 5*time.Minute + 30*time.Second, true},
+		{"7:05:30", 7*time.Hour + 5*time.Minute + 30*time.Second, true},
+		{"25:10:00", 25*time.Hour + 10*time.Minute, true},
+		{"12:30", 12*time.Hour + 30*time.Minute, true},
+		{" 00:00:00 ", 0, true},
+		{"12:60:00", 0, false},
+		{"12", 0, false},
+		{"aa:bb:cc", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseGtfsTime(tt.value)
+		if tt.valid && (err != nil || d != tt.expected) {
+			t.Errorf("expected %q to parse as %v, got %v (%v)", tt.value, tt.expected, d, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("expected %q to be invalid", tt.value)
+		}
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	if got := TimeOfDay(7*time.Hour + 5*time.Minute); got != 7*time.Hour+5*time.Minute {
+		t.Errorf("expected 7h5m, got %v", got)
+	}
+	if got := TimeOfDay(25*time.Hour + 10*time.Minute); got != time.Hour+10*time.Minute {
+		t.Errorf("expected 1h10m, got %v", got)
+	}
+}
+
+func TestFormatGtfsTime(t *testing.T) {
+	tests := []struct {
+		offset   time.Duration
+		expected string
+	}{
+		{7*time.Hour + 5*time.Minute + 9*time.Second, "07:05:09"},
+		{25*time.Hour + 10*time.Minute, "25:10:00"},
+		{1500 * time.Millisecond, "00:00:01"},
+		{-time.Minute, "00:00:00"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatGtfsTime(tt.offset); got != tt.expected {
+			t.Errorf("expected %v to format as %v, got %v", tt.offset, tt.expected, got)
+		}
+	}
+}
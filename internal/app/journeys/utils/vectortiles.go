@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// VectorTileGeometryType is the type of the geometry of a vector tile feature.
+type VectorTileGeometryType uint64
+
+const (
+	VectorTilePoint      VectorTileGeometryType = 1
+	VectorTileLineString VectorTileGeometryType = 2
+)
+
+// VectorTileLayer is a named layer of a Mapbox Vector Tile. The coordinates of its features are tile coordinates,
+// from 0 to Extent, with the origin at the upper left corner of the tile.
+type VectorTileLayer struct {
+	Name     string
+	Extent   int
+	Features []VectorTileFeature
+}
+
+// VectorTileFeature is a feature of a vector tile layer. A point feature has a single part with one point per
+// point, a line string feature has one part per line. The property values are strings, ints, float64s or bools.
+type VectorTileFeature struct {
+	Id         uint64
+	Type       VectorTileGeometryType
+	Geometry   [][][2]int
+	Properties map[string]any
+}
+
+// Field numbers of the vector tile protobuf messages, https://github.com/mapbox/vector-tile-spec/tree/master/2.1
+const (
+	mvtTileLayers = 3
+
+	mvtLayerVersion  = 15
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+
+	mvtFeatureId       = 1
+	mvtFeatureTags     = 2
+	mvtFeatureType     = 3
+	mvtFeatureGeometry = 4
+
+	mvtValueString = 1
+	mvtValueDouble = 3
+	mvtValueSint   = 6
+	mvtValueBool   = 7
+)
+
+const (
+	mvtCommandMoveTo = 1
+	mvtCommandLineTo = 2
+)
+
+const (
+	wireVarint          = 0
+	wireFixed64         = 1
+	wireLengthDelimited = 2
+)
+
+// EncodeVectorTile encodes the layers as a Mapbox Vector Tile, version 2.1. Layers without features are left out,
+// and so are the features without geometry.
+func EncodeVectorTile(layers []VectorTileLayer) []byte {
+	var tile []byte
+	for _, layer := range layers {
+		if encoded := encodeVectorTileLayer(layer); encoded != nil {
+			tile = appendBytesField(tile, mvtTileLayers, encoded)
+		}
+	}
+	return tile
+}
+
+func encodeVectorTileLayer(layer VectorTileLayer) []byte {
+	var keys []string
+	keyIndex := make(map[string]int)
+	var values []any
+	valueIndex := make(map[any]int)
+
+	var features []byte
+	for _, feature := range layer.Features {
+		geometry := encodeVectorTileGeometry(feature.Type, feature.Geometry)
+		if geometry == nil {
+			continue
+		}
+
+		names := make([]string, 0, len(feature.Properties))
+		for name := range feature.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var tags []uint64
+		for _, name := range names {
+			value := vectorTileValue(feature.Properties[name])
+			if value == nil {
+				continue
+			}
+
+			k, ok := keyIndex[name]
+			if !ok {
+				k = len(keys)
+				keyIndex[name] = k
+				keys = append(keys, name)
+			}
+
+			v, ok := valueIndex[value]
+			if !ok {
+				v = len(values)
+				valueIndex[value] = v
+				values = append(values, value)
+			}
+
+			tags = append(tags, uint64(k), uint64(v))
+		}
+
+		var encoded []byte
+		if feature.Id != 0 {
+			encoded = appendVarintField(encoded, mvtFeatureId, feature.Id)
+		}
+		if len(tags) > 0 {
+			encoded = appendPackedField(encoded, mvtFeatureTags, tags)
+		}
+		encoded = appendVarintField(encoded, mvtFeatureType, uint64(feature.Type))
+		encoded = appendPackedField(encoded, mvtFeatureGeometry, geometry)
+
+		features = appendBytesField(features, mvtLayerFeatures, encoded)
+	}
+
+	if features == nil {
+		return nil
+	}
+
+	var encoded []byte
+	encoded = appendVarintField(encoded, mvtLayerVersion, 2)
+	encoded = appendBytesField(encoded, mvtLayerName, []byte(layer.Name))
+	encoded = append(encoded, features...)
+	for _, key := range keys {
+		encoded = appendBytesField(encoded, mvtLayerKeys, []byte(key))
+	}
+	for _, value := range values {
+		encoded = appendBytesField(encoded, mvtLayerValues, encodeVectorTileValue(value))
+	}
+	encoded = appendVarintField(encoded, mvtLayerExtent, uint64(layer.Extent))
+
+	return encoded
+}
+
+// vectorTileValue normalizes a property value to a string, an int64, a float64 or a bool, or nil if the value is not
+// supported.
+func vectorTileValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return v
+	case bool:
+		return v
+	}
+	return nil
+}
+
+func encodeVectorTileValue(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return appendBytesField(nil, mvtValueString, []byte(v))
+	case int64:
+		return appendVarintField(nil, mvtValueSint, zigzag(v))
+	case float64:
+		encoded := appendVarint(nil, mvtValueDouble<<3|wireFixed64)
+		bits := math.Float64bits(v)
+		for i := 0; i < 8; i++ {
+			encoded = append(encoded, byte(bits>>(8*i)))
+		}
+		return encoded
+	case bool:
+		b := uint64(0)
+		if v {
+			b = 1
+		}
+		return appendVarintField(nil, mvtValueBool, b)
+	}
+	return nil
+}
+
+// encodeVectorTileGeometry encodes the parts as geometry commands, the coordinates of each relative to the previous
+// one. Lines of less than two points are left out, and nil is returned if nothing is left.
+func encodeVectorTileGeometry(geometryType VectorTileGeometryType, parts [][][2]int) []uint64 {
+	var result []uint64
+	var x, y int
+
+	moveTo := func(p [2]int) {
+		result = append(result, zigzag(int64(p[0]-x)), zigzag(int64(p[1]-y)))
+		x, y = p[0], p[1]
+	}
+
+	for _, part := range parts {
+		switch geometryType {
+		case VectorTilePoint:
+			if len(part) == 0 {
+				continue
+			}
+			result = append(result, vectorTileCommand(mvtCommandMoveTo, len(part)))
+			for _, p := range part {
+				moveTo(p)
+			}
+		case VectorTileLineString:
+			if len(part) < 2 {
+				continue
+			}
+			result = append(result, vectorTileCommand(mvtCommandMoveTo, 1))
+			moveTo(part[0])
+			result = append(result, vectorTileCommand(mvtCommandLineTo, len(part)-1))
+			for _, p := range part[1:] {
+				moveTo(p)
+			}
+		}
+	}
+
+	return result
+}
+
+func vectorTileCommand(id int, count int) uint64 {
+	return uint64(id&0x7 | count<<3)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field<<3|wireVarint))
+	return appendVarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field<<3|wireLengthDelimited))
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendPackedField(b []byte, field int, values []uint64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = appendVarint(packed, v)
+	}
+	return appendBytesField(b, field, packed)
+}
@@ -0,0 +1,63 @@
+//go:build utils_tests || all_tests
+
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeVectorTile(t *testing.T) {
+	layers := []VectorTileLayer{
+		{
+			Name:   "points",
+			Extent: 4096,
+			Features: []VectorTileFeature{
+				{Id: 1, Type: VectorTilePoint, Geometry: [][][2]int{{{25, 17}}}, Properties: map[string]any{"name": "A"}},
+			},
+		},
+		{
+			Name:     "empty",
+			Extent:   4096,
+			Features: []VectorTileFeature{{Type: VectorTileLineString, Geometry: [][][2]int{{{1, 1}}}}},
+		},
+	}
+
+	expected := []byte{
+		0x1a, 0x27, // layer, 39 bytes
+		0x78, 0x02, // version 2
+		0x0a, 0x06, 'p', 'o', 'i', 'n', 't', 's', // name
+		0x12, 0x0d, // feature, 13 bytes
+		0x08, 0x01, // id 1
+		0x12, 0x02, 0x00, 0x00, // tags: key 0, value 0
+		0x18, 0x01, // type point
+		0x22, 0x03, 0x09, 0x32, 0x22, // geometry: MoveTo(1), 25, 17
+		0x1a, 0x04, 'n', 'a', 'm', 'e', // key
+		0x22, 0x03, 0x0a, 0x01, 'A', // value
+		0x28, 0x80, 0x20, // extent 4096
+	}
+
+	if got := EncodeVectorTile(layers); !bytes.Equal(got, expected) {
+		t.Errorf("expected % x, got % x", expected, got)
+	}
+
+	if got := EncodeVectorTile(nil); len(got) != 0 {
+		t.Errorf("expected an empty tile, got % x", got)
+	}
+}
+
+func TestEncodeVectorTileGeometry(t *testing.T) {
+	// The example of the vector tile specification, a line from 2,2 to 2,10 to 10,10 and a line from 1,1 to 3,5.
+	parts := [][][2]int{{{2, 2}, {2, 10}, {10, 10}}, {{1, 1}, {3, 5}}}
+	expected := []uint64{9, 4, 4, 18, 0, 16, 16, 0, 9, 17, 17, 10, 4, 8}
+
+	got := encodeVectorTileGeometry(VectorTileLineString, parts)
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
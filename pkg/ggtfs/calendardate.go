@@ -65,6 +65,9 @@ func ValidateCalendarDates(calendarDates []*CalendarDate, calendarItems []*Calen
 	return results
 }
 
+// validateCalendarDateReferences reports the exceptions of services which are defined nowhere. A service which is
+// not in calendar.txt is defined by the dates it adds in calendar_dates.txt, so only the services which have neither
+// a calendar row nor an added date are reported.
 func validateCalendarDateReferences(calendarDates []*CalendarDate, calendarItems []*CalendarItem, results *[]ValidationNotice) {
 	serviceIDMap := make(map[string]struct{})
 	for _, item := range calendarItems {
@@ -72,6 +75,12 @@ func validateCalendarDateReferences(calendarDates []*CalendarDate, calendarItems
 			serviceIDMap[*item.ServiceId] = struct{}{}
 		}
 	}
+	for _, calendarDate := range calendarDates {
+		if calendarDate != nil && !StringIsNilOrEmpty(calendarDate.ServiceId) && calendarDate.ExceptionType != nil &&
+			*calendarDate.ExceptionType == "1" {
+			serviceIDMap[*calendarDate.ServiceId] = struct{}{}
+		}
+	}
 
 	for _, calendarDate := range calendarDates {
 		if calendarDate == nil || StringIsNilOrEmpty(calendarDate.ServiceId) {
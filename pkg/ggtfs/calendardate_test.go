@@ -99,7 +99,7 @@ func TestValidateCalendarDates(t *testing.T) {
 				{
 					ServiceId:     stringPtr("111"), // avoid missing required field
 					Date:          stringPtr("20201201"),
-					ExceptionType: stringPtr("1"),
+					ExceptionType: stringPtr("2"),
 				},
 			},
 			calendarItems: []*CalendarItem{},
@@ -119,7 +119,7 @@ func TestValidateCalendarDates(t *testing.T) {
 				{
 					ServiceId:     stringPtr("111"), // avoid missing required field
 					Date:          stringPtr("20201201"),
-					ExceptionType: stringPtr("1"),
+					ExceptionType: stringPtr("2"),
 				},
 			},
 			calendarItems: []*CalendarItem{nil},
@@ -139,7 +139,7 @@ func TestValidateCalendarDates(t *testing.T) {
 				{
 					ServiceId:     stringPtr("111"), // avoid missing required field
 					Date:          stringPtr("20201201"),
-					ExceptionType: stringPtr("1"),
+					ExceptionType: stringPtr("2"),
 				},
 			},
 			calendarItems: []*CalendarItem{{ServiceId: stringPtr("112")}},
@@ -154,6 +154,22 @@ func TestValidateCalendarDates(t *testing.T) {
 				},
 			},
 		},
+		"calendar-dates-only-service": {
+			actualEntities: []*CalendarDate{
+				{
+					ServiceId:     stringPtr("111"),
+					Date:          stringPtr("20201201"),
+					ExceptionType: stringPtr("1"),
+				},
+				{
+					ServiceId:     stringPtr("111"),
+					Date:          stringPtr("20201202"),
+					ExceptionType: stringPtr("2"),
+				},
+			},
+			calendarItems:   []*CalendarItem{{ServiceId: stringPtr("112")}},
+			expectedResults: []ValidationNotice{},
+		},
 		"missing-calendar-date-with-calendar-items": {
 			actualEntities:  []*CalendarDate{nil},
 			calendarItems:   []*CalendarItem{{ServiceId: stringPtr("112")}},
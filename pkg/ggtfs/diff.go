@@ -0,0 +1,518 @@
+package ggtfs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DiffChangeType string
+
+const (
+	DiffAdded   DiffChangeType = "added"
+	DiffRemoved DiffChangeType = "removed"
+	DiffChanged DiffChangeType = "changed"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// EntityChange describes one added, removed or changed entity. Fields lists the changed values of changed entities.
+type EntityChange struct {
+	Id     string         `json:"id"`
+	Name   string         `json:"name,omitempty"`
+	Change DiffChangeType `json:"change"`
+	Fields []FieldChange  `json:"fields,omitempty"`
+}
+
+// LineScheduleChange describes how the departures of a line changed. A departure is identified by the departure
+// time and the stop_id of the first stop of a trip.
+type LineScheduleChange struct {
+	Line              string   `json:"line"`
+	OldTrips          int      `json:"oldTrips"`
+	NewTrips          int      `json:"newTrips"`
+	AddedDepartures   []string `json:"addedDepartures,omitempty"`
+	RemovedDepartures []string `json:"removedDepartures,omitempty"`
+}
+
+// FeedDiff is the difference between two versions of a feed. Lines are GTFS routes, routes are GTFS shapes and
+// journey patterns are the distinct stop sequences of the trips, identified by the same md5 hash of the stop ids
+// which the API uses.
+type FeedDiff struct {
+	Stops           []EntityChange       `json:"stops"`
+	Lines           []EntityChange       `json:"lines"`
+	Routes          []EntityChange       `json:"routes"`
+	JourneyPatterns []EntityChange       `json:"journeyPatterns"`
+	Trips           []EntityChange       `json:"trips"`
+	Schedules       []LineScheduleChange `json:"schedules"`
+	Calendars       []EntityChange       `json:"calendars"`
+}
+
+// DiffFeeds compares two versions of a feed. Entities are matched by their ids, and values are compared after
+// trimming. Coordinates are compared numerically, so that for example "61.4980" and "61.49800" are considered equal.
+func DiffFeeds(oldFeed *Feed, newFeed *Feed) *FeedDiff {
+	return &FeedDiff{
+		Stops: diffEntities(oldFeed.Stops, newFeed.Stops, defaultStopHeaders, "stop_id", stopFieldValue,
+			func(s *Stop) string { return idKey(s.Name) }),
+		Lines: diffEntities(oldFeed.Routes, newFeed.Routes, defaultRouteHeaders, "route_id", routeFieldValue,
+			func(r *Route) string { return idKey(r.ShortName) }),
+		Routes:          diffShapes(oldFeed, newFeed),
+		JourneyPatterns: diffJourneyPatterns(oldFeed, newFeed),
+		Trips:           diffTrips(oldFeed, newFeed),
+		Schedules:       diffSchedules(oldFeed, newFeed),
+		Calendars:       diffCalendars(oldFeed, newFeed),
+	}
+}
+
+// IsEmpty reports whether the feeds were found to be identical.
+func (d *FeedDiff) IsEmpty() bool {
+	return len(d.Stops) == 0 && len(d.Lines) == 0 && len(d.Routes) == 0 && len(d.JourneyPatterns) == 0 &&
+		len(d.Trips) == 0 && len(d.Schedules) == 0 && len(d.Calendars) == 0
+}
+
+// WriteText writes a human-readable report of the diff.
+func (d *FeedDiff) WriteText(w io.Writer) error {
+	var sb strings.Builder
+
+	sections := []struct {
+		title   string
+		changes []EntityChange
+	}{
+		{"Stops", d.Stops},
+		{"Lines", d.Lines},
+		{"Routes", d.Routes},
+		{"Journey patterns", d.JourneyPatterns},
+		{"Trips", d.Trips},
+	}
+
+	for _, section := range sections {
+		writeChangesText(&sb, section.title, section.changes)
+	}
+
+	fmt.Fprintf(&sb, "Schedules: %v lines changed\n", len(d.Schedules))
+	for _, s := range d.Schedules {
+		fmt.Fprintf(&sb, "  ~ %v: %v -> %v trips\n", s.Line, s.OldTrips, s.NewTrips)
+		for _, dep := range s.AddedDepartures {
+			fmt.Fprintf(&sb, "      + %v\n", dep)
+		}
+		for _, dep := range s.RemovedDepartures {
+			fmt.Fprintf(&sb, "      - %v\n", dep)
+		}
+	}
+
+	writeChangesText(&sb, "Calendars", d.Calendars)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeChangesText(sb *strings.Builder, title string, changes []EntityChange) {
+	counts := make(map[DiffChangeType]int)
+	for _, c := range changes {
+		counts[c.Change]++
+	}
+
+	fmt.Fprintf(sb, "%v: %v added, %v removed, %v changed\n", title, counts[DiffAdded], counts[DiffRemoved], counts[DiffChanged])
+
+	symbols := map[DiffChangeType]string{DiffAdded: "+", DiffRemoved: "-", DiffChanged: "~"}
+	for _, c := range changes {
+		if c.Name != "" {
+			fmt.Fprintf(sb, "  %v %v (%v)\n", symbols[c.Change], c.Id, c.Name)
+		} else {
+			fmt.Fprintf(sb, "  %v %v\n", symbols[c.Change], c.Id)
+		}
+		for _, f := range c.Fields {
+			fmt.Fprintf(sb, "      %v: %q -> %q\n", f.Field, f.Old, f.New)
+		}
+	}
+}
+
+func diffEntities[T comparable](oldEntities []T, newEntities []T, headers []string, idField string,
+	fieldValue func(T, string) *string, name func(T) string) []EntityChange {
+	var zero T
+
+	byId := func(entities []T) map[string]T {
+		m := make(map[string]T)
+		for _, e := range entities {
+			if e != zero && idKey(fieldValue(e, idField)) != "" {
+				m[idKey(fieldValue(e, idField))] = e
+			}
+		}
+		return m
+	}
+
+	oldById := byId(oldEntities)
+	newById := byId(newEntities)
+
+	var changes []EntityChange
+	for _, id := range sortedUnion(oldById, newById) {
+		o, inOld := oldById[id]
+		n, inNew := newById[id]
+
+		switch {
+		case !inOld:
+			changes = append(changes, EntityChange{Id: id, Name: name(n), Change: DiffAdded})
+		case !inNew:
+			changes = append(changes, EntityChange{Id: id, Name: name(o), Change: DiffRemoved})
+		default:
+			var fields []FieldChange
+			for _, h := range headers {
+				if h == idField {
+					continue
+				}
+				if ov, nv := idKey(fieldValue(o, h)), idKey(fieldValue(n, h)); !sameValue(h, ov, nv) {
+					fields = append(fields, FieldChange{Field: h, Old: ov, New: nv})
+				}
+			}
+			if len(fields) > 0 {
+				changes = append(changes, EntityChange{Id: id, Name: name(n), Change: DiffChanged, Fields: fields})
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffShapes(oldFeed *Feed, newFeed *Feed) []EntityChange {
+	shapeIds := func(f *Feed) map[string]bool {
+		ids := make(map[string]bool)
+		for _, s := range f.Shapes {
+			if s != nil && idKey(s.Id) != "" {
+				ids[idKey(s.Id)] = true
+			}
+		}
+		return ids
+	}
+
+	oldIds := shapeIds(oldFeed)
+	newIds := shapeIds(newFeed)
+
+	var changes []EntityChange
+	for _, id := range sortedUnion(oldIds, newIds) {
+		switch {
+		case !oldIds[id]:
+			changes = append(changes, EntityChange{Id: id, Change: DiffAdded})
+		case !newIds[id]:
+			changes = append(changes, EntityChange{Id: id, Change: DiffRemoved})
+		default:
+			oldPoints := oldFeed.ShapePoints(id)
+			newPoints := newFeed.ShapePoints(id)
+			if shapeSignature(oldPoints) == shapeSignature(newPoints) {
+				continue
+			}
+
+			var fields []FieldChange
+			if len(oldPoints) != len(newPoints) {
+				fields = append(fields, FieldChange{Field: "shape_pt_count", Old: strconv.Itoa(len(oldPoints)), New: strconv.Itoa(len(newPoints))})
+			}
+			if oldLength, newLength := shapeLength(oldPoints), shapeLength(newPoints); oldLength != newLength {
+				fields = append(fields, FieldChange{Field: "length_m", Old: strconv.Itoa(oldLength), New: strconv.Itoa(newLength)})
+			}
+			if len(fields) == 0 {
+				fields = append(fields, FieldChange{Field: "geometry", Old: "", New: "changed"})
+			}
+
+			changes = append(changes, EntityChange{Id: id, Change: DiffChanged, Fields: fields})
+		}
+	}
+
+	return changes
+}
+
+func diffJourneyPatterns(oldFeed *Feed, newFeed *Feed) []EntityChange {
+	oldPatterns := journeyPatterns(oldFeed)
+	newPatterns := journeyPatterns(newFeed)
+
+	var changes []EntityChange
+	for _, id := range sortedUnion(oldPatterns, newPatterns) {
+		if name, ok := oldPatterns[id]; ok {
+			if _, ok = newPatterns[id]; !ok {
+				changes = append(changes, EntityChange{Id: id, Name: name, Change: DiffRemoved})
+			}
+			continue
+		}
+		changes = append(changes, EntityChange{Id: id, Name: newPatterns[id], Change: DiffAdded})
+	}
+
+	return changes
+}
+
+// journeyPatterns returns the distinct stop sequences of the feed, mapped to a description of the sequence.
+func journeyPatterns(f *Feed) map[string]string {
+	patterns := make(map[string]string)
+
+	for _, t := range f.Trips {
+		if t == nil {
+			continue
+		}
+
+		stopTimes := f.StopTimesByTrip(idKey(t.Id))
+		if len(stopTimes) == 0 {
+			continue
+		}
+
+		bucket := md5.New()
+		for _, st := range stopTimes {
+			bucket.Write([]byte(idKey(st.StopId)))
+		}
+		hash := hex.EncodeToString(bucket.Sum(nil))
+
+		if _, ok := patterns[hash]; !ok {
+			patterns[hash] = fmt.Sprintf("%v stops, %v -> %v", len(stopTimes), idKey(stopTimes[0].StopId), idKey(stopTimes[len(stopTimes)-1].StopId))
+		}
+	}
+
+	return patterns
+}
+
+func diffTrips(oldFeed *Feed, newFeed *Feed) []EntityChange {
+	changes := diffEntities(oldFeed.Trips, newFeed.Trips, defaultTripHeaders, "trip_id", tripFieldValue,
+		func(t *Trip) string { return "" })
+
+	changed := make(map[string]int)
+	for i, c := range changes {
+		changed[c.Id] = i
+	}
+
+	for _, t := range oldFeed.Trips {
+		if t == nil {
+			continue
+		}
+
+		id := idKey(t.Id)
+		if _, ok := newFeed.TripById(id); !ok {
+			continue
+		}
+
+		oldStopTimes := oldFeed.StopTimesByTrip(id)
+		newStopTimes := newFeed.StopTimesByTrip(id)
+		if stopTimesSignature(oldStopTimes) == stopTimesSignature(newStopTimes) {
+			continue
+		}
+
+		field := FieldChange{Field: "stop_times", Old: stopTimesSummary(oldStopTimes), New: stopTimesSummary(newStopTimes)}
+		if i, ok := changed[id]; ok {
+			changes[i].Fields = append(changes[i].Fields, field)
+		} else {
+			changed[id] = len(changes)
+			changes = append(changes, EntityChange{Id: id, Change: DiffChanged, Fields: []FieldChange{field}})
+		}
+	}
+
+	sort.SliceStable(changes, func(x, y int) bool {
+		return changes[x].Id < changes[y].Id
+	})
+
+	return changes
+}
+
+func diffSchedules(oldFeed *Feed, newFeed *Feed) []LineScheduleChange {
+	oldDepartures := departuresByLine(oldFeed)
+	newDepartures := departuresByLine(newFeed)
+
+	var changes []LineScheduleChange
+	for _, line := range sortedUnion(oldDepartures, newDepartures) {
+		added, removed := diffMultiset(oldDepartures[line], newDepartures[line])
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		changes = append(changes, LineScheduleChange{
+			Line:              line,
+			OldTrips:          len(oldDepartures[line]),
+			NewTrips:          len(newDepartures[line]),
+			AddedDepartures:   added,
+			RemovedDepartures: removed,
+		})
+	}
+
+	return changes
+}
+
+// departuresByLine maps the route_short_name (or route_id, if the line has no short name) of every line to the
+// departures of its trips.
+func departuresByLine(f *Feed) map[string][]string {
+	departures := make(map[string][]string)
+
+	for _, t := range f.Trips {
+		if t == nil {
+			continue
+		}
+
+		line := idKey(t.RouteId)
+		if r, ok := f.RouteById(line); ok && idKey(r.ShortName) != "" {
+			line = idKey(r.ShortName)
+		}
+
+		stopTimes := f.StopTimesByTrip(idKey(t.Id))
+		if len(stopTimes) == 0 {
+			continue
+		}
+
+		departures[line] = append(departures[line], fmt.Sprintf("%v from %v", idKey(stopTimes[0].DepartureTime), idKey(stopTimes[0].StopId)))
+	}
+
+	return departures
+}
+
+func diffCalendars(oldFeed *Feed, newFeed *Feed) []EntityChange {
+	oldIds := make(map[string]bool)
+	for _, id := range oldFeed.ServiceIds() {
+		oldIds[id] = true
+	}
+	newIds := make(map[string]bool)
+	for _, id := range newFeed.ServiceIds() {
+		newIds[id] = true
+	}
+
+	var changes []EntityChange
+	for _, id := range sortedUnion(oldIds, newIds) {
+		switch {
+		case !oldIds[id]:
+			changes = append(changes, EntityChange{Id: id, Change: DiffAdded})
+		case !newIds[id]:
+			changes = append(changes, EntityChange{Id: id, Change: DiffRemoved})
+		default:
+			oldItem, _ := oldFeed.CalendarItemByService(id)
+			newItem, _ := newFeed.CalendarItemByService(id)
+
+			var fields []FieldChange
+			for _, h := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"} {
+				var ov, nv string
+				if oldItem != nil {
+					ov = idKey(calendarItemFieldValue(oldItem, h))
+				}
+				if newItem != nil {
+					nv = idKey(calendarItemFieldValue(newItem, h))
+				}
+				if ov != nv {
+					fields = append(fields, FieldChange{Field: h, Old: ov, New: nv})
+				}
+			}
+
+			oldDates := calendarExceptions(oldFeed.CalendarDatesByService(id))
+			newDates := calendarExceptions(newFeed.CalendarDatesByService(id))
+			for _, date := range sortedUnion(oldDates, newDates) {
+				if oldDates[date] != newDates[date] {
+					fields = append(fields, FieldChange{Field: "date " + date, Old: oldDates[date], New: newDates[date]})
+				}
+			}
+
+			if len(fields) > 0 {
+				changes = append(changes, EntityChange{Id: id, Change: DiffChanged, Fields: fields})
+			}
+		}
+	}
+
+	return changes
+}
+
+// calendarExceptions maps the dates of calendar_dates.txt to "added" or "removed", depending on the exception_type.
+func calendarExceptions(dates []*CalendarDate) map[string]string {
+	exceptions := make(map[string]string)
+	for _, cd := range dates {
+		switch idKey(cd.ExceptionType) {
+		case "1":
+			exceptions[idKey(cd.Date)] = "added"
+		case "2":
+			exceptions[idKey(cd.Date)] = "removed"
+		default:
+			exceptions[idKey(cd.Date)] = idKey(cd.ExceptionType)
+		}
+	}
+	return exceptions
+}
+
+func stopTimesSignature(stopTimes []*StopTime) string {
+	var parts []string
+	for _, st := range stopTimes {
+		parts = append(parts, joinValues(st.StopId, st.ArrivalTime, st.DepartureTime, st.PickupType, st.DropOffType))
+	}
+	return strings.Join(parts, "|")
+}
+
+func stopTimesSummary(stopTimes []*StopTime) string {
+	if len(stopTimes) == 0 {
+		return "no stops"
+	}
+	first, last := stopTimes[0], stopTimes[len(stopTimes)-1]
+	return fmt.Sprintf("%v stops, %v %v - %v %v", len(stopTimes), idKey(first.StopId), idKey(first.DepartureTime),
+		idKey(last.StopId), idKey(last.ArrivalTime))
+}
+
+// shapeLength returns the length of the shape in whole meters.
+func shapeLength(points []*Shape) int {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		lat1, lon1, ok1 := ParseCoordinates(points[i-1].PtLat, points[i-1].PtLon)
+		lat2, lon2, ok2 := ParseCoordinates(points[i].PtLat, points[i].PtLon)
+		if ok1 && ok2 {
+			length += HaversineDistance(lat1, lon1, lat2, lon2)
+		}
+	}
+	return int(math.Round(length))
+}
+
+// diffMultiset returns the values which occur more often in b than in a (added) and vice versa (removed), sorted.
+func diffMultiset(a []string, b []string) ([]string, []string) {
+	counts := make(map[string]int)
+	for _, v := range a {
+		counts[v]--
+	}
+	for _, v := range b {
+		counts[v]++
+	}
+
+	var added, removed []string
+	for v, c := range counts {
+		for ; c > 0; c-- {
+			added = append(added, v)
+		}
+		for ; c < 0; c++ {
+			removed = append(removed, v)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+}
+
+// sameValue compares two values of the field. Coordinates are compared numerically, everything else as text.
+func sameValue(field string, a string, b string) bool {
+	if a == b {
+		return true
+	}
+
+	if !strings.HasSuffix(field, "_lat") && !strings.HasSuffix(field, "_lon") {
+		return false
+	}
+
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+
+	return errA == nil && errB == nil && fa == fb
+}
+
+func sortedUnion[V any](a map[string]V, b map[string]V) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffFeeds(t *testing.T) {
+	oldFeed, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	files := validFeedFiles()
+	files[FileNameStops] = "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+		"S1,0001,Keskustori,61.4975400,23.76152\n" +
+		"S3,0003,Hervanta,61.45000,23.85000\n"
+	files[FileNameRoutes] = "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+		"R1,A1,1,Vatiala - Pirkkala - Lentoasema,3\n" +
+		"R2,A1,2,Pyynikintori - Rauhaniemi,3\n"
+	files[FileNameStopTimes] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"T1,10:05:00,10:05:00,S3,2\n" +
+		"T1,10:00:00,10:00:00,S1,1\n" +
+		"T2,11:30:00,11:30:00,S1,1\n"
+	files[FileNameCalendarDate] = "service_id,date,exception_type\n" +
+		"SAT,20250104,1\n"
+
+	newFeed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	diff := DiffFeeds(oldFeed, newFeed)
+
+	expectedStops := []EntityChange{
+		{Id: "S2", Name: "Hämeenkatu", Change: DiffRemoved},
+		{Id: "S3", Name: "Hervanta", Change: DiffAdded},
+	}
+	expectChanges(t, diff.Stops, expectedStops)
+
+	expectedLines := []EntityChange{
+		{Id: "R1", Name: "1", Change: DiffChanged, Fields: []FieldChange{
+			{Field: "route_long_name", Old: "Vatiala - Pirkkala", New: "Vatiala - Pirkkala - Lentoasema"},
+		}},
+	}
+	expectChanges(t, diff.Lines, expectedLines)
+
+	if len(diff.JourneyPatterns) != 2 || diff.JourneyPatterns[0].Change == diff.JourneyPatterns[1].Change {
+		t.Errorf("expected one added and one removed journey pattern, got %v", diff.JourneyPatterns)
+	}
+
+	expectedTrips := []EntityChange{
+		{Id: "T1", Change: DiffChanged, Fields: []FieldChange{{Field: "stop_times", Old: "2 stops, S1 10:00:00 - S2 10:05:00", New: "2 stops, S1 10:00:00 - S3 10:05:00"}}},
+		{Id: "T2", Change: DiffChanged, Fields: []FieldChange{{Field: "stop_times", Old: "1 stops, S1 11:00:00 - S1 11:00:00", New: "1 stops, S1 11:30:00 - S1 11:30:00"}}},
+	}
+	expectChanges(t, diff.Trips, expectedTrips)
+
+	expectedSchedules := []LineScheduleChange{
+		{Line: "1", OldTrips: 2, NewTrips: 2, AddedDepartures: []string{"11:30:00 from S1"}, RemovedDepartures: []string{"11:00:00 from S1"}},
+	}
+	expectChanges(t, diff.Schedules, expectedSchedules)
+
+	expectedCalendars := []EntityChange{
+		{Id: "WD", Change: DiffChanged, Fields: []FieldChange{{Field: "date 20250106", Old: "removed", New: ""}}},
+	}
+	expectChanges(t, diff.Calendars, expectedCalendars)
+
+	if len(diff.Routes) != 0 {
+		t.Errorf("expected no route changes, got %v", diff.Routes)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Stops: 1 added, 1 removed, 0 changed") {
+		t.Errorf("unexpected text output: %v", buf.String())
+	}
+}
+
+func TestDiffFeedsIdentical(t *testing.T) {
+	feed, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if diff := DiffFeeds(feed, feed); !diff.IsEmpty() {
+		t.Errorf("expected an empty diff, got %+v", diff)
+	}
+}
+
+func expectChanges(t *testing.T, actual interface{}, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,140 @@
+package ggtfs
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"unicode/utf8"
+)
+
+type CharacterEncoding string
+
+const (
+	EncodingAuto        CharacterEncoding = ""
+	EncodingUTF8        CharacterEncoding = "utf-8"
+	EncodingUTF16LE     CharacterEncoding = "utf-16le"
+	EncodingUTF16BE     CharacterEncoding = "utf-16be"
+	EncodingWindows1252 CharacterEncoding = "windows-1252"
+	EncodingISO88591    CharacterEncoding = "iso-8859-1"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// utf16SampleSize is the number of leading bytes inspected when looking for UTF-16 without a byte order mark.
+const utf16SampleSize = 1024
+
+func ParseCharacterEncoding(name string) (CharacterEncoding, error) {
+	switch CharacterEncoding(name) {
+	case EncodingAuto, EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingWindows1252, EncodingISO88591:
+		return CharacterEncoding(name), nil
+	case "auto":
+		return EncodingAuto, nil
+	case "latin1", "latin-1", "iso8859-1":
+		return EncodingISO88591, nil
+	case "cp1252":
+		return EncodingWindows1252, nil
+	}
+
+	return EncodingAuto, fmt.Errorf("unsupported character encoding: %v", name)
+}
+
+// DetectCharacterEncoding guesses the encoding of data. Byte order marks win, then a UTF-16 heuristic based on
+// the position of zero bytes, then UTF-8 validity. Anything else is treated as a single byte Latin encoding:
+// Windows-1252 if the data uses the 0x80-0x9F range (where Windows-1252 has printable characters and
+// ISO-8859-1 has control codes), ISO-8859-1 otherwise.
+func DetectCharacterEncoding(data []byte) CharacterEncoding {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+
+	if enc, ok := detectUTF16WithoutBOM(data); ok {
+		return enc
+	}
+
+	if utf8.Valid(data) {
+		return EncodingUTF8
+	}
+
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			return EncodingWindows1252
+		}
+	}
+
+	return EncodingISO88591
+}
+
+func detectUTF16WithoutBOM(data []byte) (CharacterEncoding, bool) {
+	sample := data
+	if len(sample) > utf16SampleSize {
+		sample = sample[:utf16SampleSize]
+	}
+	if len(sample) < 2 {
+		return EncodingAuto, false
+	}
+
+	var evenZeros, oddZeros int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+
+	// GTFS files are mostly ASCII, so in UTF-16 nearly every other byte is zero.
+	half := len(sample) / 2
+	if oddZeros > half*3/4 && evenZeros < half/4 {
+		return EncodingUTF16LE, true
+	}
+	if evenZeros > half*3/4 && oddZeros < half/4 {
+		return EncodingUTF16BE, true
+	}
+
+	return EncodingAuto, false
+}
+
+// DecodeToUTF8 converts data from the given encoding to UTF-8, detecting the encoding first if enc is EncodingAuto.
+// Any byte order mark is removed. The encoding that was used is returned along with the converted data.
+func DecodeToUTF8(data []byte, enc CharacterEncoding) ([]byte, CharacterEncoding, error) {
+	if enc == EncodingAuto {
+		enc = DetectCharacterEncoding(data)
+	}
+
+	var decoder *encoding.Decoder
+	switch enc {
+	case EncodingUTF8:
+		return bytes.TrimPrefix(data, bomUTF8), enc, nil
+	case EncodingUTF16LE:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()
+	case EncodingUTF16BE:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()
+	case EncodingWindows1252:
+		decoder = charmap.Windows1252.NewDecoder()
+	case EncodingISO88591:
+		decoder = charmap.ISO8859_1.NewDecoder()
+	default:
+		return nil, enc, fmt.Errorf("unsupported character encoding: %v", enc)
+	}
+
+	decoded, err := decoder.Bytes(data)
+	if err != nil {
+		return nil, enc, err
+	}
+
+	return bytes.TrimPrefix(decoded, bomUTF8), enc, nil
+}
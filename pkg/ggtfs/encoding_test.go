@@ -0,0 +1,136 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDetectCharacterEncoding(t *testing.T) {
+	tests := map[string]struct {
+		data     []byte
+		expected CharacterEncoding
+	}{
+		"empty":              {data: []byte{}, expected: EncodingUTF8},
+		"ascii":              {data: []byte("stop_id,stop_name\n1,Keskustori\n"), expected: EncodingUTF8},
+		"utf-8":              {data: []byte("stop_id,stop_name\n1,Hämeenkatu\n"), expected: EncodingUTF8},
+		"utf-8-bom":          {data: append([]byte{0xEF, 0xBB, 0xBF}, []byte("stop_id\n")...), expected: EncodingUTF8},
+		"utf-16le-bom":       {data: []byte{0xFF, 0xFE, 'a', 0, 'b', 0}, expected: EncodingUTF16LE},
+		"utf-16be-bom":       {data: []byte{0xFE, 0xFF, 0, 'a', 0, 'b'}, expected: EncodingUTF16BE},
+		"utf-16le-no-bom":    {data: []byte{'s', 0, 't', 0, 'o', 0, 'p', 0}, expected: EncodingUTF16LE},
+		"utf-16be-no-bom":    {data: []byte{0, 's', 0, 't', 0, 'o', 0, 'p'}, expected: EncodingUTF16BE},
+		"iso-8859-1":         {data: []byte("1,H\xe4meenkatu\n"), expected: EncodingISO88591},
+		"windows-1252":       {data: []byte("1,H\xe4meenkatu \x96 Keskustori\n"), expected: EncodingWindows1252},
+		"invalid-utf-8-tail": {data: []byte("1,Pyynikintori\xf6"), expected: EncodingISO88591},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			actual := DetectCharacterEncoding(tt.data)
+			if actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDecodeToUTF8(t *testing.T) {
+	tests := map[string]struct {
+		data             []byte
+		encoding         CharacterEncoding
+		expected         string
+		expectedEncoding CharacterEncoding
+		errorExpected    bool
+	}{
+		"utf-8-bom-is-removed": {
+			data:             append([]byte{0xEF, 0xBB, 0xBF}, []byte("Hämeenkatu")...),
+			encoding:         EncodingAuto,
+			expected:         "Hämeenkatu",
+			expectedEncoding: EncodingUTF8,
+		},
+		"utf-16le": {
+			data:             []byte{0xFF, 0xFE, 'H', 0, 0xE4, 0, 'm', 0},
+			encoding:         EncodingAuto,
+			expected:         "Häm",
+			expectedEncoding: EncodingUTF16LE,
+		},
+		"utf-16be": {
+			data:             []byte{0xFE, 0xFF, 0, 'H', 0, 0xE4, 0, 'm'},
+			encoding:         EncodingAuto,
+			expected:         "Häm",
+			expectedEncoding: EncodingUTF16BE,
+		},
+		"iso-8859-1": {
+			data:             []byte("H\xe4meenkatu, T\xf6\xf6l\xf6"),
+			encoding:         EncodingAuto,
+			expected:         "Hämeenkatu, Töölö",
+			expectedEncoding: EncodingISO88591,
+		},
+		"windows-1252": {
+			data:             []byte("H\xe4meenkatu \x96 Keskustori"),
+			encoding:         EncodingAuto,
+			expected:         "Hämeenkatu – Keskustori",
+			expectedEncoding: EncodingWindows1252,
+		},
+		"configured-encoding-wins": {
+			data:             []byte("H\xe4meenkatu"),
+			encoding:         EncodingWindows1252,
+			expected:         "Hämeenkatu",
+			expectedEncoding: EncodingWindows1252,
+		},
+		"unsupported-encoding": {
+			data:          []byte("foo"),
+			encoding:      CharacterEncoding("ebcdic"),
+			errorExpected: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			actual, actualEncoding, err := DecodeToUTF8(tt.data, tt.encoding)
+			if tt.errorExpected {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, string(actual))
+			}
+			if actualEncoding != tt.expectedEncoding {
+				t.Errorf("expected encoding %v, got %v", tt.expectedEncoding, actualEncoding)
+			}
+		})
+	}
+}
+
+func TestParseCharacterEncoding(t *testing.T) {
+	tests := map[string]struct {
+		name          string
+		expected      CharacterEncoding
+		errorExpected bool
+	}{
+		"empty":   {name: "", expected: EncodingAuto},
+		"auto":    {name: "auto", expected: EncodingAuto},
+		"latin1":  {name: "latin1", expected: EncodingISO88591},
+		"cp1252":  {name: "cp1252", expected: EncodingWindows1252},
+		"utf-16":  {name: "utf-16le", expected: EncodingUTF16LE},
+		"unknown": {name: "koi8-r", errorExpected: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			actual, err := ParseCharacterEncoding(tt.name)
+			if tt.errorExpected != (err != nil) {
+				t.Errorf("unexpected error state: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -1,12 +1,100 @@
 package ggtfs
 
 type FareAttributes struct {
-	Id               string
-	Price            float64
-	CurrencyType     string
-	PaymentMethod    int
-	Transfers        int
-	AgencyId         *string
-	TransferDuration *uint
+	Id               *string // fare_id 			(required)
+	Price            *string // price 				(required)
+	CurrencyType     *string // currency_type 		(required)
+	PaymentMethod    *string // payment_method 		(required)
+	Transfers        *string // transfers 			(required, empty means unlimited transfers)
+	AgencyId         *string // agency_id 			(conditionally required)
+	TransferDuration *string // transfer_duration 	(optional)
 	LineNumber       int
 }
+
+func CreateFareAttributes(row []string, headers map[string]int, lineNumber int) *FareAttributes {
+	fareAttributes := &FareAttributes{
+		LineNumber: lineNumber,
+	}
+
+	for hName := range headers {
+		v := getRowValueForHeaderName(row, headers, hName)
+		switch hName {
+		case "fare_id":
+			fareAttributes.Id = v
+		case "price":
+			fareAttributes.Price = v
+		case "currency_type":
+			fareAttributes.CurrencyType = v
+		case "payment_method":
+			fareAttributes.PaymentMethod = v
+		case "transfers":
+			fareAttributes.Transfers = v
+		case "agency_id":
+			fareAttributes.AgencyId = v
+		case "transfer_duration":
+			fareAttributes.TransferDuration = v
+		}
+	}
+
+	return fareAttributes
+}
+
+func ValidateFareAttribute(fa FareAttributes) []ValidationNotice {
+	var validationResults []ValidationNotice
+
+	fields := []struct {
+		fieldType FieldType
+		name      string
+		value     *string
+		required  bool
+	}{
+		{FieldTypeID, "fare_id", fa.Id, true},
+		{FieldTypeCurrencyAmount, "price", fa.Price, true},
+		{FieldTypeCurrencyCode, "currency_type", fa.CurrencyType, true},
+		{FieldTypePaymentMethod, "payment_method", fa.PaymentMethod, true},
+		{FieldTypeTransfers, "transfers", fa.Transfers, false},
+		{FieldTypeID, "agency_id", fa.AgencyId, false},
+		{FieldTypeInteger, "transfer_duration", fa.TransferDuration, false},
+	}
+
+	for _, field := range fields {
+		validationResults = append(validationResults, validateField(field.fieldType, field.name, field.value, field.required, FileNameFareAttributes, fa.LineNumber)...)
+	}
+
+	return validationResults
+}
+
+func ValidateFareAttributes(fareAttributes []*FareAttributes, agencies []*Agency) []ValidationNotice {
+	var results []ValidationNotice
+
+	agencyIds := make(map[string]struct{})
+	for _, agency := range agencies {
+		if agency != nil && !StringIsNilOrEmpty(agency.Id) {
+			agencyIds[*agency.Id] = struct{}{}
+		}
+	}
+
+	for _, fa := range fareAttributes {
+		if fa == nil {
+			continue
+		}
+
+		results = append(results, ValidateFareAttribute(*fa)...)
+
+		if agencies == nil || StringIsNilOrEmpty(fa.AgencyId) {
+			continue
+		}
+		if _, found := agencyIds[*fa.AgencyId]; !found {
+			results = append(results, ForeignKeyViolationNotice{
+				ReferencingFileName:  FileNameFareAttributes,
+				ReferencingFieldName: "agency_id",
+				ReferencedFileName:   FileNameAgency,
+				ReferencedFieldName:  "agency_id",
+				OffendingValue:       *fa.AgencyId,
+				ReferencedAtRow:      fa.LineNumber,
+			})
+		}
+	}
+
+	return results
+}
@@ -1,10 +1,112 @@
 package ggtfs
 
 type FareRule struct {
-	Id            string
-	RouteId       *string
-	OriginId      *string
-	DestinationId *string
-	ContainsId    *string
+	FareId        *string // fare_id 			(required)
+	RouteId       *string // route_id 		(optional)
+	OriginId      *string // origin_id 		(optional)
+	DestinationId *string // destination_id (optional)
+	ContainsId    *string // contains_id 	(optional)
 	LineNumber    int
 }
+
+func CreateFareRule(row []string, headers map[string]int, lineNumber int) *FareRule {
+	fareRule := &FareRule{
+		LineNumber: lineNumber,
+	}
+
+	for hName := range headers {
+		v := getRowValueForHeaderName(row, headers, hName)
+		switch hName {
+		case "fare_id":
+			fareRule.FareId = v
+		case "route_id":
+			fareRule.RouteId = v
+		case "origin_id":
+			fareRule.OriginId = v
+		case "destination_id":
+			fareRule.DestinationId = v
+		case "contains_id":
+			fareRule.ContainsId = v
+		}
+	}
+
+	return fareRule
+}
+
+func ValidateFareRule(fr FareRule) []ValidationNotice {
+	var validationResults []ValidationNotice
+
+	fields := []struct {
+		fieldType FieldType
+		name      string
+		value     *string
+		required  bool
+	}{
+		{FieldTypeID, "fare_id", fr.FareId, true},
+		{FieldTypeID, "route_id", fr.RouteId, false},
+		{FieldTypeID, "origin_id", fr.OriginId, false},
+		{FieldTypeID, "destination_id", fr.DestinationId, false},
+		{FieldTypeID, "contains_id", fr.ContainsId, false},
+	}
+
+	for _, field := range fields {
+		validationResults = append(validationResults, validateField(field.fieldType, field.name, field.value, field.required, FileNameFareRules, fr.LineNumber)...)
+	}
+
+	return validationResults
+}
+
+// ValidateFareRules checks the rules and their references to fare_attributes.txt and routes.txt. The zone ids are
+// not checked, stops.txt may define zones which no stop belongs to.
+func ValidateFareRules(fareRules []*FareRule, fareAttributes []*FareAttributes, routes []*Route) []ValidationNotice {
+	var results []ValidationNotice
+
+	fareIds := make(map[string]struct{})
+	for _, fa := range fareAttributes {
+		if fa != nil && !StringIsNilOrEmpty(fa.Id) {
+			fareIds[*fa.Id] = struct{}{}
+		}
+	}
+	routeIds := make(map[string]struct{})
+	for _, route := range routes {
+		if route != nil && !StringIsNilOrEmpty(route.Id) {
+			routeIds[*route.Id] = struct{}{}
+		}
+	}
+
+	for _, fr := range fareRules {
+		if fr == nil {
+			continue
+		}
+
+		results = append(results, ValidateFareRule(*fr)...)
+
+		if !StringIsNilOrEmpty(fr.FareId) {
+			if _, found := fareIds[*fr.FareId]; !found {
+				results = append(results, ForeignKeyViolationNotice{
+					ReferencingFileName:  FileNameFareRules,
+					ReferencingFieldName: "fare_id",
+					ReferencedFileName:   FileNameFareAttributes,
+					ReferencedFieldName:  "fare_id",
+					OffendingValue:       *fr.FareId,
+					ReferencedAtRow:      fr.LineNumber,
+				})
+			}
+		}
+
+		if routes != nil && !StringIsNilOrEmpty(fr.RouteId) {
+			if _, found := routeIds[*fr.RouteId]; !found {
+				results = append(results, ForeignKeyViolationNotice{
+					ReferencingFileName:  FileNameFareRules,
+					ReferencingFieldName: "route_id",
+					ReferencedFileName:   FileNameRoutes,
+					ReferencedFieldName:  "route_id",
+					OffendingValue:       *fr.RouteId,
+					ReferencedAtRow:      fr.LineNumber,
+				})
+			}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,103 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateFareAttributes(t *testing.T) {
+	tests := map[string]struct {
+		actualEntities  []*FareAttributes
+		agencies        []*Agency
+		expectedResults []ValidationNotice
+	}{
+		"unlimited-transfers": {
+			actualEntities: []*FareAttributes{{
+				Id:            stringPtr("F1"),
+				Price:         stringPtr("2.50"),
+				CurrencyType:  stringPtr("EUR"),
+				PaymentMethod: stringPtr("0"),
+				Transfers:     stringPtr(""),
+			}},
+			expectedResults: []ValidationNotice{},
+		},
+		"invalid-fields": {
+			actualEntities: []*FareAttributes{{
+				Id:            stringPtr("F1"),
+				Price:         stringPtr("2.50"),
+				CurrencyType:  stringPtr("EUR"),
+				PaymentMethod: stringPtr("2"),
+				Transfers:     stringPtr("3"),
+				AgencyId:      stringPtr("A2"),
+			}},
+			agencies: []*Agency{{Id: stringPtr("A1")}},
+			expectedResults: []ValidationNotice{
+				InvalidPaymentMethodNotice{SingleLineNotice{FileName: "fare_attributes.txt", FieldName: "payment_method"}},
+				InvalidTransfersNotice{SingleLineNotice{FileName: "fare_attributes.txt", FieldName: "transfers"}},
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "fare_attributes.txt",
+					ReferencingFieldName: "agency_id",
+					ReferencedFileName:   "agency.txt",
+					ReferencedFieldName:  "agency_id",
+					OffendingValue:       "A2",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			handleValidationResults(t, ValidateFareAttributes(tt.actualEntities, tt.agencies), tt.expectedResults)
+		})
+	}
+}
+
+func TestValidateFareRules(t *testing.T) {
+	tests := map[string]struct {
+		actualEntities  []*FareRule
+		fareAttributes  []*FareAttributes
+		routes          []*Route
+		expectedResults []ValidationNotice
+	}{
+		"nil-slice-items": {
+			actualEntities:  []*FareRule{nil},
+			expectedResults: []ValidationNotice{},
+		},
+		"missing-fare-id": {
+			actualEntities: []*FareRule{{RouteId: stringPtr("R1")}},
+			routes:         []*Route{{Id: stringPtr("R1")}},
+			expectedResults: []ValidationNotice{
+				MissingRequiredFieldNotice{SingleLineNotice{FileName: "fare_rules.txt", FieldName: "fare_id"}},
+			},
+		},
+		"missing-references": {
+			actualEntities: []*FareRule{{FareId: stringPtr("F2"), RouteId: stringPtr("R2"), OriginId: stringPtr("Z1")}},
+			fareAttributes: []*FareAttributes{{Id: stringPtr("F1")}},
+			routes:         []*Route{{Id: stringPtr("R1")}},
+			expectedResults: []ValidationNotice{
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "fare_rules.txt",
+					ReferencingFieldName: "fare_id",
+					ReferencedFileName:   "fare_attributes.txt",
+					ReferencedFieldName:  "fare_id",
+					OffendingValue:       "F2",
+				},
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "fare_rules.txt",
+					ReferencingFieldName: "route_id",
+					ReferencedFileName:   "routes.txt",
+					ReferencedFieldName:  "route_id",
+					OffendingValue:       "R2",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			handleValidationResults(t, ValidateFareRules(tt.actualEntities, tt.fareAttributes, tt.routes), tt.expectedResults)
+		})
+	}
+}
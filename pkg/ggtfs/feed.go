@@ -0,0 +1,447 @@
+package ggtfs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CsvDialect describes how the files of a feed are written. The zero value means strictly quoted files whose
+// delimiter and character encoding are detected automatically, separately for every file. Detection lets feeds
+// written in different dialects be loaded and merged with the same settings.
+type CsvDialect struct {
+	Delimiter  rune
+	LazyQuotes bool
+	Encoding   CharacterEncoding
+}
+
+// Feed is a complete GTFS feed. The entity slices are kept in file order, and the lookup methods are backed by
+// indexes which are built when the feed is loaded. If the slices are modified afterwards, call Reindex before
+// using the lookups again.
+type Feed struct {
+	Agencies      []*Agency
+	Routes        []*Route
+	Stops         []*Stop
+	Trips         []*Trip
+	StopTimes     []*StopTime
+	CalendarItems []*CalendarItem
+	CalendarDates []*CalendarDate
+	Shapes        []*Shape
+
+	Frequencies    []*Frequency
+	Transfers      []*Transfer
+	FareAttributes []*FareAttributes
+	FareRules      []*FareRule
+	// FeedInfo has a single row in a valid feed.
+	FeedInfo []*FeedInfo
+
+	// LoadNotices contains the notices emitted while reading the files, such as character encoding conversions.
+	LoadNotices []ValidationNotice
+
+	agenciesById           map[string]*Agency
+	routesById             map[string]*Route
+	stopsById              map[string]*Stop
+	tripsById              map[string]*Trip
+	tripsByRoute           map[string][]*Trip
+	tripsByService         map[string][]*Trip
+	stopTimesByTrip        map[string][]*StopTime
+	calendarItemsByService map[string]*CalendarItem
+	calendarDatesByService map[string][]*CalendarDate
+	shapePoints            map[string][]*Shape
+	frequenciesByTrip      map[string][]*Frequency
+	fareAttributesById     map[string]*FareAttributes
+}
+
+type ValidationOptions struct {
+	// MinimumSeverity drops notices which are less severe than the given severity. The zero value keeps all notices.
+	MinimumSeverity ValidationNoticeSeverity
+}
+
+// LoadFeed reads every supported GTFS file from the directory at feedPath. Missing optional files are skipped,
+// missing required files and unparseable rows are reported as errors. Calendar files are treated as optional
+// individually, since a feed may define its services through either one of them. Other files in the directory,
+// such as levels.txt or translations.txt, are not loaded, and a FileNotLoadedNotice is added to LoadNotices for
+// each of them.
+func LoadFeed(feedPath string, dialect CsvDialect) (*Feed, []error) {
+	feed := &Feed{}
+	var errs []error
+
+	files := []string{FileNameAgency, FileNameRoutes, FileNameStops, FileNameTrips, FileNameStopTimes,
+		FileNameCalendar, FileNameCalendarDate, FileNameShapes, FileNameFrequencies, FileNameTransfers,
+		FileNameFareAttributes, FileNameFareRules, FileNameFeedInfo}
+	optionalFiles := map[string]bool{FileNameCalendar: true, FileNameCalendarDate: true, FileNameShapes: true,
+		FileNameFrequencies: true, FileNameTransfers: true, FileNameFareAttributes: true, FileNameFareRules: true,
+		FileNameFeedInfo: true}
+
+	for _, file := range files {
+		csvReader, notices, err := NewCsvReaderForFile(path.Join(feedPath, file), dialect)
+		if err != nil {
+			if os.IsNotExist(err) && optionalFiles[file] {
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		feed.LoadNotices = append(feed.LoadNotices, notices...)
+
+		reader := NewReader(csvReader)
+		var loadErrors []error
+
+		switch file {
+		case FileNameAgency:
+			feed.Agencies, loadErrors = LoadAgencies(reader)
+		case FileNameRoutes:
+			feed.Routes, loadErrors = LoadRoutes(reader)
+		case FileNameStops:
+			feed.Stops, loadErrors = LoadStops(reader)
+		case FileNameTrips:
+			feed.Trips, loadErrors = LoadTrips(reader)
+		case FileNameStopTimes:
+			feed.StopTimes, loadErrors = LoadStopTimes(reader)
+		case FileNameCalendar:
+			feed.CalendarItems, loadErrors = LoadCalendar(reader)
+		case FileNameCalendarDate:
+			feed.CalendarDates, loadErrors = LoadCalendarDates(reader)
+		case FileNameShapes:
+			feed.Shapes, loadErrors = LoadShapes(reader)
+		case FileNameFrequencies:
+			feed.Frequencies, loadErrors = LoadFrequencies(reader)
+		case FileNameTransfers:
+			feed.Transfers, loadErrors = LoadTransfers(reader)
+		case FileNameFareAttributes:
+			feed.FareAttributes, loadErrors = LoadFareAttributes(reader)
+		case FileNameFareRules:
+			feed.FareRules, loadErrors = LoadFareRules(reader)
+		case FileNameFeedInfo:
+			feed.FeedInfo, loadErrors = LoadFeedInfo(reader)
+		}
+
+		for _, loadErr := range loadErrors {
+			errs = append(errs, fmt.Errorf("%v: %v", file, loadErr.Error()))
+		}
+	}
+
+	if len(feed.CalendarItems) == 0 && len(feed.CalendarDates) == 0 {
+		errs = append(errs, fmt.Errorf("%v or %v is required", FileNameCalendar, FileNameCalendarDate))
+	}
+
+	feed.LoadNotices = append(feed.LoadNotices, notLoadedFileNotices(feedPath, files)...)
+
+	feed.Reindex()
+
+	return feed, errs
+}
+
+// extensionFiles are the files which are not part of GTFS, but are read from the feed directory by the users of
+// this package.
+var extensionFiles = map[string]bool{"municipalities.txt": true}
+
+func notLoadedFileNotices(feedPath string, loadedFiles []string) []ValidationNotice {
+	entries, err := os.ReadDir(feedPath)
+	if err != nil {
+		return nil
+	}
+
+	loaded := make(map[string]bool)
+	for _, file := range loadedFiles {
+		loaded[file] = true
+	}
+
+	var notices []ValidationNotice
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".txt") || loaded[name] || extensionFiles[name] {
+			continue
+		}
+		notices = append(notices, FileNotLoadedNotice{FileName: name})
+	}
+	return notices
+}
+
+// NewCsvReaderForFile opens a GTFS file, converts it to UTF-8 and skips blank lines. A notice is returned
+// if the encoding had to be detected and was something else than UTF-8.
+func NewCsvReaderForFile(filePath string, dialect CsvDialect) (*csv.Reader, []ValidationNotice, error) {
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, usedEncoding, err := DecodeToUTF8(raw, dialect.Encoding)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", filePath, err.Error())
+	}
+
+	var notices []ValidationNotice
+	if dialect.Encoding == EncodingAuto && usedEncoding != EncodingUTF8 {
+		notices = append(notices, CharacterEncodingConvertedNotice{
+			FileName: path.Base(filePath),
+			Encoding: usedEncoding,
+		})
+	}
+
+	if dialect.Delimiter == 0 {
+		dialect.Delimiter = DetectDelimiter(content)
+	}
+
+	r := csv.NewReader(newSkippingReader(bytes.NewReader(content)))
+	dialect.Apply(r)
+
+	return r, notices, nil
+}
+
+// DetectDelimiter guesses the field delimiter of CSV content from its first line, the header line of a GTFS file.
+// The field names of GTFS never contain the candidates, so the most frequent one of comma, semicolon and tab is the
+// delimiter. Falls back to a comma.
+func DetectDelimiter(content []byte) rune {
+	header, _, _ := bytes.Cut(content, []byte("\n"))
+
+	delimiter, most := ',', 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(header, []byte(string(candidate))); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
+
+// Apply configures r to read files written in the dialect.
+func (d CsvDialect) Apply(r *csv.Reader) {
+	if d.Delimiter != 0 {
+		r.Comma = d.Delimiter
+	}
+	r.LazyQuotes = d.LazyQuotes
+}
+
+func newSkippingReader(r io.Reader) io.Reader {
+	var buf bytes.Buffer
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Skip empty lines and lines that contain only whitespace.
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		buf.WriteString(line + "\n")
+	}
+
+	return &buf
+}
+
+// Reindex rebuilds the lookup indexes from the entity slices.
+func (f *Feed) Reindex() {
+	f.agenciesById = make(map[string]*Agency)
+	f.routesById = make(map[string]*Route)
+	f.stopsById = make(map[string]*Stop)
+	f.tripsById = make(map[string]*Trip)
+	f.tripsByRoute = make(map[string][]*Trip)
+	f.tripsByService = make(map[string][]*Trip)
+	f.stopTimesByTrip = make(map[string][]*StopTime)
+	f.calendarItemsByService = make(map[string]*CalendarItem)
+	f.calendarDatesByService = make(map[string][]*CalendarDate)
+	f.shapePoints = make(map[string][]*Shape)
+	f.frequenciesByTrip = make(map[string][]*Frequency)
+	f.fareAttributesById = make(map[string]*FareAttributes)
+
+	for _, a := range f.Agencies {
+		if a != nil {
+			f.agenciesById[idKey(a.Id)] = a
+		}
+	}
+
+	for _, r := range f.Routes {
+		if r != nil && !StringIsNilOrEmpty(r.Id) {
+			f.routesById[idKey(r.Id)] = r
+		}
+	}
+
+	for _, s := range f.Stops {
+		if s != nil && !StringIsNilOrEmpty(s.Id) {
+			f.stopsById[idKey(s.Id)] = s
+		}
+	}
+
+	for _, t := range f.Trips {
+		if t == nil || StringIsNilOrEmpty(t.Id) {
+			continue
+		}
+		f.tripsById[idKey(t.Id)] = t
+		if !StringIsNilOrEmpty(t.RouteId) {
+			f.tripsByRoute[idKey(t.RouteId)] = append(f.tripsByRoute[idKey(t.RouteId)], t)
+		}
+		if !StringIsNilOrEmpty(t.ServiceId) {
+			f.tripsByService[idKey(t.ServiceId)] = append(f.tripsByService[idKey(t.ServiceId)], t)
+		}
+	}
+
+	for _, st := range f.StopTimes {
+		if st != nil && !StringIsNilOrEmpty(st.TripId) {
+			f.stopTimesByTrip[idKey(st.TripId)] = append(f.stopTimesByTrip[idKey(st.TripId)], st)
+		}
+	}
+	for _, stopTimes := range f.stopTimesByTrip {
+		sortBySequence(stopTimes, func(st *StopTime) *string { return st.StopSequence })
+	}
+
+	for _, ci := range f.CalendarItems {
+		if ci != nil && !StringIsNilOrEmpty(ci.ServiceId) {
+			f.calendarItemsByService[idKey(ci.ServiceId)] = ci
+		}
+	}
+
+	for _, cd := range f.CalendarDates {
+		if cd != nil && !StringIsNilOrEmpty(cd.ServiceId) {
+			f.calendarDatesByService[idKey(cd.ServiceId)] = append(f.calendarDatesByService[idKey(cd.ServiceId)], cd)
+		}
+	}
+
+	for _, s := range f.Shapes {
+		if s != nil && !StringIsNilOrEmpty(s.Id) {
+			f.shapePoints[idKey(s.Id)] = append(f.shapePoints[idKey(s.Id)], s)
+		}
+	}
+	for _, points := range f.shapePoints {
+		sortBySequence(points, func(s *Shape) *string { return s.PtSequence })
+	}
+
+	for _, fr := range f.Frequencies {
+		if fr != nil && !StringIsNilOrEmpty(fr.TripId) {
+			f.frequenciesByTrip[idKey(fr.TripId)] = append(f.frequenciesByTrip[idKey(fr.TripId)], fr)
+		}
+	}
+
+	for _, fa := range f.FareAttributes {
+		if fa != nil && !StringIsNilOrEmpty(fa.Id) {
+			f.fareAttributesById[idKey(fa.Id)] = fa
+		}
+	}
+}
+
+func (f *Feed) AgencyById(id string) (*Agency, bool) {
+	a, ok := f.agenciesById[id]
+	return a, ok
+}
+
+func (f *Feed) RouteById(id string) (*Route, bool) {
+	r, ok := f.routesById[id]
+	return r, ok
+}
+
+func (f *Feed) StopById(id string) (*Stop, bool) {
+	s, ok := f.stopsById[id]
+	return s, ok
+}
+
+func (f *Feed) TripById(id string) (*Trip, bool) {
+	t, ok := f.tripsById[id]
+	return t, ok
+}
+
+func (f *Feed) TripsByRoute(routeId string) []*Trip {
+	return f.tripsByRoute[routeId]
+}
+
+func (f *Feed) TripsByService(serviceId string) []*Trip {
+	return f.tripsByService[serviceId]
+}
+
+// StopTimesByTrip returns the stop times of the trip ordered by stop_sequence.
+func (f *Feed) StopTimesByTrip(tripId string) []*StopTime {
+	return f.stopTimesByTrip[tripId]
+}
+
+func (f *Feed) CalendarItemByService(serviceId string) (*CalendarItem, bool) {
+	ci, ok := f.calendarItemsByService[serviceId]
+	return ci, ok
+}
+
+func (f *Feed) CalendarDatesByService(serviceId string) []*CalendarDate {
+	return f.calendarDatesByService[serviceId]
+}
+
+// ShapePoints returns the points of the shape ordered by shape_pt_sequence.
+func (f *Feed) ShapePoints(shapeId string) []*Shape {
+	return f.shapePoints[shapeId]
+}
+
+func (f *Feed) FrequenciesByTrip(tripId string) []*Frequency {
+	return f.frequenciesByTrip[tripId]
+}
+
+func (f *Feed) FareAttributesById(fareId string) (*FareAttributes, bool) {
+	fa, ok := f.fareAttributesById[fareId]
+	return fa, ok
+}
+
+// ServiceIds returns every service_id defined in calendar.txt or calendar_dates.txt, sorted.
+func (f *Feed) ServiceIds() []string {
+	var ids []string
+	for id := range f.calendarItemsByService {
+		ids = append(ids, id)
+	}
+	for id := range f.calendarDatesByService {
+		if _, ok := f.calendarItemsByService[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Validate runs every file level and cross-file rule against the feed.
+func (f *Feed) Validate(opts ValidationOptions) []ValidationNotice {
+	var notices []ValidationNotice
+
+	notices = append(notices, ValidateAgencies(f.Agencies)...)
+	notices = append(notices, ValidateRoutes(f.Routes, f.Agencies)...)
+	notices = append(notices, ValidateStops(f.Stops)...)
+	notices = append(notices, ValidateTrips(f.Trips, f.Routes, f.CalendarItems, f.CalendarDates, f.Shapes)...)
+	notices = append(notices, ValidateStopTimes(f.StopTimes, f.Stops)...)
+	notices = append(notices, ValidateCalendarItems(f.CalendarItems)...)
+	notices = append(notices, ValidateCalendarDates(f.CalendarDates, f.CalendarItems)...)
+	notices = append(notices, ValidateShapes(f.Shapes)...)
+	notices = append(notices, ValidateFrequencies(f.Frequencies, f.Trips)...)
+	notices = append(notices, ValidateTransfers(f.Transfers, f.Stops, f.Routes, f.Trips)...)
+	notices = append(notices, ValidateFareAttributes(f.FareAttributes, f.Agencies)...)
+	notices = append(notices, ValidateFareRules(f.FareRules, f.FareAttributes, f.Routes)...)
+	notices = append(notices, ValidateFeedInfos(f.FeedInfo)...)
+
+	var filtered []ValidationNotice
+	for _, n := range notices {
+		if n.Severity() >= opts.MinimumSeverity {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+func idKey(id *string) string {
+	if id == nil {
+		return ""
+	}
+	return strings.TrimSpace(*id)
+}
+
+// sortBySequence orders items by the integer sequence returned by seq. Items whose sequence cannot be parsed
+// come last, in their relative order.
+func sortBySequence[T any](items []T, seq func(T) *string) {
+	sort.SliceStable(items, func(x, y int) bool {
+		sx, errX := parseSequence(seq(items[x]))
+		sy, errY := parseSequence(seq(items[y]))
+		return errX == nil && (errY != nil || sx < sy)
+	})
+}
+
+func parseSequence(s *string) (int, error) {
+	if s == nil {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(strings.TrimSpace(*s))
+}
@@ -0,0 +1,330 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func writeFeedFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func validFeedFiles() map[string]string {
+	return map[string]string{
+		FileNameAgency: "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"A1,Nysse,http://nysse.fi,Europe/Helsinki\n",
+		FileNameRoutes: "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"R1,A1,1,Vatiala - Pirkkala,3\n" +
+			"R2,A1,2,Pyynikintori - Rauhaniemi,3\n",
+		FileNameStops: "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"S1,0001,Keskustori,61.49754,23.76152\n" +
+			"S2,0002,Hämeenkatu,61.49800,23.76000\n",
+		FileNameTrips: "route_id,service_id,trip_id,shape_id\n" +
+			"R1,WD,T1,SH1\n" +
+			"R1,WD,T2,SH1\n" +
+			"R2,SAT,T3,SH1\n",
+		FileNameStopTimes: "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,10:05:00,10:05:00,S2,2\n" +
+			"T1,10:00:00,10:00:00,S1,1\n" +
+			"T2,11:00:00,11:00:00,S1,1\n",
+		FileNameCalendar: "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WD,1,1,1,1,1,0,0,20250101,20251231\n",
+		FileNameCalendarDate: "service_id,date,exception_type\n" +
+			"SAT,20250104,1\n" +
+			"WD,20250106,2\n",
+		FileNameShapes: "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n" +
+			"SH1,61.49800,23.76000,2\n" +
+			"SH1,61.49754,23.76152,1\n",
+	}
+}
+
+// optionalFeedFiles returns the optional files which reference the entities of validFeedFiles.
+func optionalFeedFiles() map[string]string {
+	return map[string]string{
+		FileNameFrequencies: "trip_id,start_time,end_time,headway_secs\n" +
+			"T1,06:00:00,09:00:00,600\n",
+		FileNameTransfers: "from_stop_id,to_stop_id,transfer_type,min_transfer_time\n" +
+			"S1,S2,2,180\n",
+		FileNameFareAttributes: "fare_id,price,currency_type,payment_method,transfers\n" +
+			"F1,2.50,EUR,0,\n",
+		FileNameFareRules: "fare_id,route_id\n" +
+			"F1,R1\n",
+		FileNameFeedInfo: "feed_publisher_name,feed_publisher_url,feed_lang,feed_start_date,feed_end_date\n" +
+			"Nysse,http://nysse.fi,fi,20250101,20251231\n",
+	}
+}
+
+func TestLoadFeed(t *testing.T) {
+	feed, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if len(feed.Agencies) != 1 || len(feed.Routes) != 2 || len(feed.Stops) != 2 || len(feed.Trips) != 3 ||
+		len(feed.StopTimes) != 3 || len(feed.CalendarItems) != 1 || len(feed.CalendarDates) != 2 || len(feed.Shapes) != 2 {
+		t.Errorf("unexpected entity counts: %+v", feed)
+	}
+
+	if s, ok := feed.StopById("S2"); !ok || *s.Name != "Hämeenkatu" {
+		t.Errorf("expected stop S2, got %v", s)
+	}
+
+	if _, ok := feed.StopById("S3"); ok {
+		t.Error("expected S3 to be missing")
+	}
+
+	if r, ok := feed.RouteById("R2"); !ok || *r.ShortName != "2" {
+		t.Errorf("expected route R2, got %v", r)
+	}
+
+	if a, ok := feed.AgencyById("A1"); !ok || *a.Name != "Nysse" {
+		t.Errorf("expected agency A1, got %v", a)
+	}
+
+	if trips := feed.TripsByRoute("R1"); len(trips) != 2 || *trips[0].Id != "T1" || *trips[1].Id != "T2" {
+		t.Errorf("expected trips T1 and T2 for route R1, got %v", trips)
+	}
+
+	if trips := feed.TripsByService("SAT"); len(trips) != 1 || *trips[0].Id != "T3" {
+		t.Errorf("expected trip T3 for service SAT, got %v", trips)
+	}
+
+	stopTimes := feed.StopTimesByTrip("T1")
+	if len(stopTimes) != 2 || *stopTimes[0].StopId != "S1" || *stopTimes[1].StopId != "S2" {
+		t.Errorf("expected stop times of T1 ordered by stop_sequence, got %v", stopTimes)
+	}
+
+	points := feed.ShapePoints("SH1")
+	if len(points) != 2 || *points[0].PtSequence != "1" || *points[1].PtSequence != "2" {
+		t.Errorf("expected shape points ordered by shape_pt_sequence, got %v", points)
+	}
+
+	if ci, ok := feed.CalendarItemByService("WD"); !ok || *ci.StartDate != "20250101" {
+		t.Errorf("expected calendar item WD, got %v", ci)
+	}
+
+	if cds := feed.CalendarDatesByService("SAT"); len(cds) != 1 {
+		t.Errorf("expected one calendar date for SAT, got %v", cds)
+	}
+
+	if ids := strings.Join(feed.ServiceIds(), ","); ids != "SAT,WD" {
+		t.Errorf("expected service ids SAT,WD, got %v", ids)
+	}
+}
+
+func TestLoadFeedOptionalFiles(t *testing.T) {
+	files := validFeedFiles()
+	for name, content := range optionalFeedFiles() {
+		files[name] = content
+	}
+	files["levels.txt"] = "level_id,level_index\nL1,0\n"
+	files["municipalities.txt"] = "municipality_id,municipality_name\n837,Tampere\n"
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if len(feed.Frequencies) != 1 || len(feed.Transfers) != 1 || len(feed.FareAttributes) != 1 ||
+		len(feed.FareRules) != 1 || len(feed.FeedInfo) != 1 {
+		t.Errorf("unexpected entity counts: %+v", feed)
+	}
+
+	if frequencies := feed.FrequenciesByTrip("T1"); len(frequencies) != 1 || *frequencies[0].HeadwaySecs != "600" {
+		t.Errorf("expected a frequency for trip T1, got %v", frequencies)
+	}
+
+	if fa, ok := feed.FareAttributesById("F1"); !ok || *fa.Price != "2.50" {
+		t.Errorf("expected fare F1, got %v", fa)
+	}
+
+	if len(feed.LoadNotices) != 1 || feed.LoadNotices[0] != (FileNotLoadedNotice{FileName: "levels.txt"}) {
+		t.Errorf("expected a notice for levels.txt only, got %v", feed.LoadNotices)
+	}
+
+	if notices := feed.Validate(ValidationOptions{}); len(notices) > 0 {
+		t.Errorf("expected no validation notices, got %v", notices)
+	}
+}
+
+func TestLoadFeedMissingFiles(t *testing.T) {
+	tests := map[string]struct {
+		remove         []string
+		expectedErrors int
+	}{
+		"optional-shapes":         {remove: []string{FileNameShapes}, expectedErrors: 0},
+		"calendar-dates-only":     {remove: []string{FileNameCalendar}, expectedErrors: 0},
+		"calendar-only":           {remove: []string{FileNameCalendarDate}, expectedErrors: 0},
+		"no-calendars":            {remove: []string{FileNameCalendar, FileNameCalendarDate}, expectedErrors: 1},
+		"required-stops":          {remove: []string{FileNameStops}, expectedErrors: 1},
+		"required-stops-and-trip": {remove: []string{FileNameStops, FileNameTrips}, expectedErrors: 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			files := validFeedFiles()
+			for _, f := range tt.remove {
+				delete(files, f)
+			}
+
+			_, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+			if len(errs) != tt.expectedErrors {
+				t.Errorf("expected %v errors, got %v", tt.expectedErrors, errs)
+			}
+		})
+	}
+}
+
+func TestLoadFeedWithDialect(t *testing.T) {
+	files := validFeedFiles()
+	for name, content := range files {
+		files[name] = strings.ReplaceAll(content, ",", ";")
+	}
+	files[FileNameStops] = "stop_id;stop_code;stop_name;stop_lat;stop_lon\n" +
+		"S1;0001;Keskustori \"H\";61.49754;23.76152\n" +
+		"S2;0002;H\xe4meenkatu;61.49800;23.76000\n"
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{Delimiter: ';', LazyQuotes: true})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if s, ok := feed.StopById("S2"); !ok || *s.Name != "Hämeenkatu" {
+		t.Errorf("expected converted stop name, got %v", s)
+	}
+
+	if s, ok := feed.StopById("S1"); !ok || *s.Name != "Keskustori \"H\"" {
+		t.Errorf("expected lazily quoted stop name, got %v", s)
+	}
+
+	handleValidationResults(t, feed.LoadNotices, []ValidationNotice{
+		CharacterEncodingConvertedNotice{FileName: FileNameStops, Encoding: EncodingISO88591},
+	})
+}
+
+func TestLoadFeedDetectsDelimiter(t *testing.T) {
+	// Every file is read in its own dialect, so a feed exported partly from a spreadsheet loads without settings.
+	files := validFeedFiles()
+	files[FileNameStops] = strings.ReplaceAll(files[FileNameStops], ",", ";")
+	files[FileNameTrips] = strings.ReplaceAll(files[FileNameTrips], ",", "\t")
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if _, ok := feed.StopById("S1"); !ok {
+		t.Errorf("expected the semicolon separated stops to load")
+	}
+	if _, ok := feed.TripById("T1"); !ok {
+		t.Errorf("expected the tab separated trips to load")
+	}
+
+	testCases := []struct {
+		content  string
+		expected rune
+	}{
+		{"stop_id,stop_name\nS1,A;B\n", ','},
+		{"stop_id;stop_name\nS1;A,B\n", ';'},
+		{"stop_id\tstop_name\n", '\t'},
+		{"stop_id\nS1\n", ','},
+		{"", ','},
+	}
+	for _, tc := range testCases {
+		if got := DetectDelimiter([]byte(tc.content)); got != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.content, tc.expected, got)
+		}
+	}
+}
+
+func TestFeedValidate(t *testing.T) {
+	files := validFeedFiles()
+	files[FileNameStopTimes] += "T2,11:05:00,11:05:00,S9,2\n"
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	foreignKeyViolation := ForeignKeyViolationNotice{
+		ReferencingFileName:  FileNameStopTimes,
+		ReferencingFieldName: "stop_id",
+		ReferencedFieldName:  FileNameStops,
+		ReferencedFileName:   "stop_id",
+		OffendingValue:       "S9",
+		ReferencedAtRow:      5,
+	}
+
+	found := false
+	for _, n := range feed.Validate(ValidationOptions{MinimumSeverity: SeverityViolation}) {
+		if n.Severity() < SeverityViolation {
+			t.Errorf("expected only violations, got %v", n.AsText())
+		}
+		if n == foreignKeyViolation {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Error("expected a foreign key violation for stop S9")
+	}
+}
+
+func TestFeedReindex(t *testing.T) {
+	feed := &Feed{}
+	feed.Stops = append(feed.Stops, &Stop{Id: stringPtr(" S1 ")})
+	feed.Reindex()
+
+	if _, ok := feed.StopById("S1"); !ok {
+		t.Error("expected S1 to be found after reindexing")
+	}
+}
+
+func TestSortBySequence(t *testing.T) {
+	values := []string{"3", "x", "1", "", "2", "y"}
+	items := make([]*string, len(values))
+	for i := range values {
+		items[i] = &values[i]
+	}
+	items = append(items, nil)
+
+	sortBySequence(items, func(s *string) *string { return s })
+
+	var got []string
+	for _, s := range items {
+		if s == nil {
+			got = append(got, "nil")
+			continue
+		}
+		got = append(got, *s)
+	}
+
+	// The rows whose sequence cannot be parsed come last, in their original order.
+	if expected := "1 2 3 x  y nil"; strings.Join(got, " ") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestFeedValidateCalendarDatesOnlyService(t *testing.T) {
+	feed, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for _, n := range feed.Validate(ValidationOptions{MinimumSeverity: SeverityViolation}) {
+		if fk, ok := n.(ForeignKeyViolationNotice); ok && fk.ReferencingFieldName == "service_id" {
+			t.Errorf("expected service SAT from calendar_dates.txt to be accepted, got %v", n.AsText())
+		}
+	}
+}
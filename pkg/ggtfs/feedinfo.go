@@ -0,0 +1,86 @@
+package ggtfs
+
+type FeedInfo struct {
+	PublisherName *string // feed_publisher_name 	(required)
+	PublisherURL  *string // feed_publisher_url 	(required)
+	Lang          *string // feed_lang 				(required)
+	DefaultLang   *string // default_lang 			(optional)
+	StartDate     *string // feed_start_date 		(recommended)
+	EndDate       *string // feed_end_date 			(recommended)
+	Version       *string // feed_version 			(recommended)
+	ContactEmail  *string // feed_contact_email 	(optional)
+	ContactURL    *string // feed_contact_url 		(optional)
+	LineNumber    int
+}
+
+func CreateFeedInfo(row []string, headers map[string]int, lineNumber int) *FeedInfo {
+	feedInfo := &FeedInfo{
+		LineNumber: lineNumber,
+	}
+
+	for hName := range headers {
+		v := getRowValueForHeaderName(row, headers, hName)
+		switch hName {
+		case "feed_publisher_name":
+			feedInfo.PublisherName = v
+		case "feed_publisher_url":
+			feedInfo.PublisherURL = v
+		case "feed_lang":
+			feedInfo.Lang = v
+		case "default_lang":
+			feedInfo.DefaultLang = v
+		case "feed_start_date":
+			feedInfo.StartDate = v
+		case "feed_end_date":
+			feedInfo.EndDate = v
+		case "feed_version":
+			feedInfo.Version = v
+		case "feed_contact_email":
+			feedInfo.ContactEmail = v
+		case "feed_contact_url":
+			feedInfo.ContactURL = v
+		}
+	}
+
+	return feedInfo
+}
+
+func ValidateFeedInfo(fi FeedInfo) []ValidationNotice {
+	var validationResults []ValidationNotice
+
+	fields := []struct {
+		fieldType FieldType
+		name      string
+		value     *string
+		required  bool
+	}{
+		{FieldTypeText, "feed_publisher_name", fi.PublisherName, true},
+		{FieldTypeURL, "feed_publisher_url", fi.PublisherURL, true},
+		{FieldTypeLanguageCode, "feed_lang", fi.Lang, true},
+		{FieldTypeLanguageCode, "default_lang", fi.DefaultLang, false},
+		{FieldTypeDate, "feed_start_date", fi.StartDate, false},
+		{FieldTypeDate, "feed_end_date", fi.EndDate, false},
+		{FieldTypeText, "feed_version", fi.Version, false},
+		{FieldTypeEmail, "feed_contact_email", fi.ContactEmail, false},
+		{FieldTypeURL, "feed_contact_url", fi.ContactURL, false},
+	}
+
+	for _, field := range fields {
+		validationResults = append(validationResults, validateField(field.fieldType, field.name, field.value, field.required, FileNameFeedInfo, fi.LineNumber)...)
+	}
+
+	return validationResults
+}
+
+func ValidateFeedInfos(feedInfos []*FeedInfo) []ValidationNotice {
+	var results []ValidationNotice
+
+	for _, fi := range feedInfos {
+		if fi == nil {
+			continue
+		}
+		results = append(results, ValidateFeedInfo(*fi)...)
+	}
+
+	return results
+}
@@ -32,4 +32,8 @@ const (
 	FieldTypeDirectionId          FieldType = "DirectionId"
 	FieldTypeWheelchairAccessible FieldType = "WheelchairAccessible"
 	FieldTypeBikesAllowed         FieldType = "BikesAllowed"
+	FieldTypeExactTimes           FieldType = "ExactTimes"
+	FieldTypeTransferType         FieldType = "TransferType"
+	FieldTypePaymentMethod        FieldType = "PaymentMethod"
+	FieldTypeTransfers            FieldType = "Transfers"
 )
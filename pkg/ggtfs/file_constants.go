@@ -1,12 +1,17 @@
 package ggtfs
 
 const (
-	FileNameAgency       = "agency.txt"
-	FileNameCalendar     = "calendar.txt"
-	FileNameCalendarDate = "calendar_dates.txt"
-	FileNameRoutes       = "routes.txt"
-	FileNameShapes       = "shapes.txt"
-	FileNameStops        = "stops.txt"
-	FileNameStopTimes    = "stop_times.txt"
-	FileNameTrips        = "trips.txt"
+	FileNameAgency         = "agency.txt"
+	FileNameCalendar       = "calendar.txt"
+	FileNameCalendarDate   = "calendar_dates.txt"
+	FileNameFareAttributes = "fare_attributes.txt"
+	FileNameFareRules      = "fare_rules.txt"
+	FileNameFeedInfo       = "feed_info.txt"
+	FileNameFrequencies    = "frequencies.txt"
+	FileNameRoutes         = "routes.txt"
+	FileNameShapes         = "shapes.txt"
+	FileNameStops          = "stops.txt"
+	FileNameStopTimes      = "stop_times.txt"
+	FileNameTransfers      = "transfers.txt"
+	FileNameTrips          = "trips.txt"
 )
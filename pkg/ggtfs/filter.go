@@ -0,0 +1,331 @@
+package ggtfs
+
+import (
+	"time"
+)
+
+const gtfsDateLayout = "20060102"
+
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Contains reports whether the coordinate is inside the box, edges included.
+func (b BoundingBox) Contains(lat float64, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// FilterOptions selects the trips to keep. Every criterion which is set has to match, criteria which are not set
+// match every trip.
+type FilterOptions struct {
+	// StartDate and EndDate limit the feed to the services which run at least once in the window, both ends
+	// included. A zero value leaves that end of the window open.
+	StartDate time.Time
+	EndDate   time.Time
+	// RouteIds and LineNames keep the trips whose route_id or route_short_name is listed. If both are set, a trip
+	// matching either one is kept.
+	RouteIds  []string
+	LineNames []string
+	// BoundingBox keeps the trips which call at least at one stop inside the box.
+	BoundingBox *BoundingBox
+}
+
+// FilterFeed returns a subset of the feed containing the trips selected by opts together with everything they
+// reference. Trips are kept whole, so stops outside the bounding box are kept if a kept trip calls at them.
+// Entities which are not referenced by the kept trips are pruned, as are the frequencies, transfers and fare rules
+// which reference pruned entities. When a date window is given, the calendars and feed_info.txt are also trimmed
+// to the window. The input feed is not modified.
+func FilterFeed(feed *Feed, opts FilterOptions) *Feed {
+	result := &Feed{}
+
+	start, end := "", ""
+	if !opts.StartDate.IsZero() {
+		start = opts.StartDate.Format(gtfsDateLayout)
+	}
+	if !opts.EndDate.IsZero() {
+		end = opts.EndDate.Format(gtfsDateLayout)
+	}
+
+	activeServices := make(map[string]bool)
+	for _, id := range feed.ServiceIds() {
+		if start == "" && end == "" || serviceRunsBetween(feed, id, start, end) {
+			activeServices[id] = true
+		}
+	}
+
+	routeIds := toSet(opts.RouteIds)
+	lineNames := toSet(opts.LineNames)
+
+	stopsInBox := make(map[string]bool)
+	if opts.BoundingBox != nil {
+		for _, s := range feed.Stops {
+			if s == nil {
+				continue
+			}
+			if lat, lon, ok := ParseCoordinates(s.Lat, s.Lon); ok && opts.BoundingBox.Contains(lat, lon) {
+				stopsInBox[idKey(s.Id)] = true
+			}
+		}
+	}
+
+	keptRoutes := make(map[string]bool)
+	keptServices := make(map[string]bool)
+	keptShapes := make(map[string]bool)
+	keptStops := make(map[string]bool)
+	keptTrips := make(map[string]bool)
+
+	for _, t := range feed.Trips {
+		if t == nil || StringIsNilOrEmpty(t.Id) {
+			continue
+		}
+
+		if !activeServices[idKey(t.ServiceId)] {
+			continue
+		}
+
+		if len(routeIds) > 0 || len(lineNames) > 0 {
+			_, routeMatches := routeIds[idKey(t.RouteId)]
+			lineMatches := false
+			if r, ok := feed.RouteById(idKey(t.RouteId)); ok {
+				_, lineMatches = lineNames[idKey(r.ShortName)]
+			}
+			if !routeMatches && !lineMatches {
+				continue
+			}
+		}
+
+		stopTimes := feed.StopTimesByTrip(idKey(t.Id))
+
+		if opts.BoundingBox != nil && !callsAtAny(stopTimes, stopsInBox) {
+			continue
+		}
+
+		result.Trips = append(result.Trips, t)
+		result.StopTimes = append(result.StopTimes, stopTimes...)
+		result.Frequencies = append(result.Frequencies, feed.FrequenciesByTrip(idKey(t.Id))...)
+
+		keptTrips[idKey(t.Id)] = true
+		keptRoutes[idKey(t.RouteId)] = true
+		keptServices[idKey(t.ServiceId)] = true
+		if !StringIsNilOrEmpty(t.ShapeId) {
+			keptShapes[idKey(t.ShapeId)] = true
+		}
+		for _, st := range stopTimes {
+			keptStops[idKey(st.StopId)] = true
+		}
+	}
+
+	keptAgencies := make(map[string]bool)
+	for _, r := range feed.Routes {
+		if r != nil && keptRoutes[idKey(r.Id)] {
+			result.Routes = append(result.Routes, r)
+			keptAgencies[idKey(r.AgencyId)] = true
+		}
+	}
+
+	for _, a := range feed.Agencies {
+		// Routes may omit agency_id in a feed with a single agency.
+		if a != nil && (keptAgencies[idKey(a.Id)] || len(feed.Agencies) == 1 && len(result.Routes) > 0) {
+			result.Agencies = append(result.Agencies, a)
+		}
+	}
+
+	// Parent stations are kept along with their platforms.
+	for _, s := range feed.Stops {
+		if s != nil && keptStops[idKey(s.Id)] && !StringIsNilOrEmpty(s.ParentStation) {
+			keptStops[idKey(s.ParentStation)] = true
+		}
+	}
+	for _, s := range feed.Stops {
+		if s != nil && keptStops[idKey(s.Id)] {
+			result.Stops = append(result.Stops, s)
+		}
+	}
+
+	for _, ci := range feed.CalendarItems {
+		if ci == nil || !keptServices[idKey(ci.ServiceId)] {
+			continue
+		}
+
+		trimmed, ok := trimCalendarItem(ci, start, end)
+		if ok {
+			result.CalendarItems = append(result.CalendarItems, trimmed)
+		}
+	}
+
+	for _, cd := range feed.CalendarDates {
+		if cd != nil && keptServices[idKey(cd.ServiceId)] && dateInWindow(idKey(cd.Date), start, end) {
+			result.CalendarDates = append(result.CalendarDates, cd)
+		}
+	}
+
+	for _, s := range feed.Shapes {
+		if s != nil && keptShapes[idKey(s.Id)] {
+			result.Shapes = append(result.Shapes, s)
+		}
+	}
+
+	for _, t := range feed.Transfers {
+		if t != nil && keptOrUnset(t.FromStopId, keptStops) && keptOrUnset(t.ToStopId, keptStops) &&
+			keptOrUnset(t.FromRouteId, keptRoutes) && keptOrUnset(t.ToRouteId, keptRoutes) &&
+			keptOrUnset(t.FromTripId, keptTrips) && keptOrUnset(t.ToTripId, keptTrips) {
+			result.Transfers = append(result.Transfers, t)
+		}
+	}
+
+	filterFares(feed, result, keptRoutes)
+
+	for _, fi := range feed.FeedInfo {
+		if fi != nil {
+			result.FeedInfo = append(result.FeedInfo, trimFeedInfo(fi, start, end))
+		}
+	}
+
+	result.Reindex()
+
+	return result
+}
+
+// filterFares keeps the fare rules whose route and zones are still in the result, and the fares which have a kept
+// rule left. Fares without any rules apply to the whole feed and are kept as long as their agency is.
+func filterFares(feed *Feed, result *Feed, keptRoutes map[string]bool) {
+	keptAgencies := make(map[string]bool)
+	for _, a := range result.Agencies {
+		keptAgencies[idKey(a.Id)] = true
+	}
+
+	keptZones := make(map[string]bool)
+	for _, s := range result.Stops {
+		if !StringIsNilOrEmpty(s.ZoneId) {
+			keptZones[idKey(s.ZoneId)] = true
+		}
+	}
+
+	faresWithRules := make(map[string]bool)
+	var rules []*FareRule
+	for _, r := range feed.FareRules {
+		if r == nil {
+			continue
+		}
+		faresWithRules[idKey(r.FareId)] = true
+
+		if keptOrUnset(r.RouteId, keptRoutes) && keptOrUnset(r.OriginId, keptZones) &&
+			keptOrUnset(r.DestinationId, keptZones) && keptOrUnset(r.ContainsId, keptZones) {
+			rules = append(rules, r)
+		}
+	}
+
+	faresWithKeptRules := make(map[string]bool)
+	for _, r := range rules {
+		faresWithKeptRules[idKey(r.FareId)] = true
+	}
+
+	keptFares := make(map[string]bool)
+	for _, fa := range feed.FareAttributes {
+		if fa == nil || len(result.Agencies) == 0 || !keptOrUnset(fa.AgencyId, keptAgencies) {
+			continue
+		}
+		if faresWithRules[idKey(fa.Id)] && !faresWithKeptRules[idKey(fa.Id)] {
+			continue
+		}
+		result.FareAttributes = append(result.FareAttributes, fa)
+		keptFares[idKey(fa.Id)] = true
+	}
+
+	for _, r := range rules {
+		if keptFares[idKey(r.FareId)] {
+			result.FareRules = append(result.FareRules, r)
+		}
+	}
+}
+
+// trimFeedInfo limits the validity of the feed to the window, in the same way as trimCalendarItem.
+func trimFeedInfo(fi *FeedInfo, start string, end string) *FeedInfo {
+	c := *fi
+
+	if start != "" && !StringIsNilOrEmpty(c.StartDate) && start > idKey(c.StartDate) {
+		c.StartDate = strPtr(start)
+	}
+	if end != "" && !StringIsNilOrEmpty(c.EndDate) && end < idKey(c.EndDate) {
+		c.EndDate = strPtr(end)
+	}
+
+	return &c
+}
+
+// serviceRunsBetween reports whether the service runs on at least one date between start and end, which are
+// GTFS dates. An empty start or end leaves that end of the window open.
+func serviceRunsBetween(feed *Feed, serviceId string, start string, end string) bool {
+	exceptions := calendarExceptions(feed.CalendarDatesByService(serviceId))
+	for date, exception := range exceptions {
+		if exception == "added" && dateInWindow(date, start, end) {
+			return true
+		}
+	}
+
+	ci, ok := feed.CalendarItemByService(serviceId)
+	if !ok {
+		return false
+	}
+
+	from, to := idKey(ci.StartDate), idKey(ci.EndDate)
+	if start != "" && start > from {
+		from = start
+	}
+	if end != "" && end < to {
+		to = end
+	}
+
+	fromDate, err := time.Parse(gtfsDateLayout, from)
+	if err != nil {
+		return false
+	}
+	toDate, err := time.Parse(gtfsDateLayout, to)
+	if err != nil {
+		return false
+	}
+
+	weekdays := []*string{ci.Sunday, ci.Monday, ci.Tuesday, ci.Wednesday, ci.Thursday, ci.Friday, ci.Saturday}
+	for d := fromDate; !d.After(toDate); d = d.AddDate(0, 0, 1) {
+		if idKey(weekdays[d.Weekday()]) == "1" && exceptions[d.Format(gtfsDateLayout)] != "removed" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimCalendarItem limits the date range of the calendar item to the window. It returns false if the ranges
+// do not overlap.
+func trimCalendarItem(ci *CalendarItem, start string, end string) (*CalendarItem, bool) {
+	c := *ci
+
+	if start != "" && start > idKey(c.StartDate) {
+		c.StartDate = strPtr(start)
+	}
+	if end != "" && end < idKey(c.EndDate) {
+		c.EndDate = strPtr(end)
+	}
+
+	return &c, idKey(c.StartDate) <= idKey(c.EndDate)
+}
+
+func dateInWindow(date string, start string, end string) bool {
+	return (start == "" || date >= start) && (end == "" || date <= end)
+}
+
+func keptOrUnset(id *string, kept map[string]bool) bool {
+	return StringIsNilOrEmpty(id) || kept[idKey(id)]
+}
+
+func callsAtAny(stopTimes []*StopTime, stops map[string]bool) bool {
+	for _, st := range stopTimes {
+		if stops[idKey(st.StopId)] {
+			return true
+		}
+	}
+	return false
+}
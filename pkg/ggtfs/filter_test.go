@@ -0,0 +1,210 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterFeed(t *testing.T) {
+	tests := map[string]struct {
+		opts              FilterOptions
+		expectedTrips     string
+		expectedRoutes    string
+		expectedStops     string
+		expectedServices  string
+		expectedShapes    int
+		expectedStartDate string
+	}{
+		"no-criteria": {
+			expectedTrips:     "T1,T2,T3",
+			expectedRoutes:    "R1,R2",
+			expectedStops:     "S1,S2",
+			expectedServices:  "SAT,WD",
+			expectedShapes:    2,
+			expectedStartDate: "20250101",
+		},
+		"line-name": {
+			opts:              FilterOptions{LineNames: []string{"2"}},
+			expectedTrips:     "T3",
+			expectedRoutes:    "R2",
+			expectedStops:     "",
+			expectedServices:  "SAT",
+			expectedShapes:    2,
+			expectedStartDate: "",
+		},
+		"route-id": {
+			opts:              FilterOptions{RouteIds: []string{"R1"}},
+			expectedTrips:     "T1,T2",
+			expectedRoutes:    "R1",
+			expectedStops:     "S1,S2",
+			expectedServices:  "WD",
+			expectedShapes:    2,
+			expectedStartDate: "20250101",
+		},
+		"date-window-saturday": {
+			opts:              FilterOptions{StartDate: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+			expectedTrips:     "T3",
+			expectedRoutes:    "R2",
+			expectedStops:     "",
+			expectedServices:  "SAT",
+			expectedShapes:    2,
+			expectedStartDate: "",
+		},
+		"date-window-removed-monday": {
+			opts:              FilterOptions{StartDate: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+			expectedTrips:     "",
+			expectedRoutes:    "",
+			expectedStops:     "",
+			expectedServices:  "",
+			expectedShapes:    0,
+			expectedStartDate: "",
+		},
+		"date-window-trims-calendar": {
+			opts:              FilterOptions{StartDate: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+			expectedTrips:     "T1,T2",
+			expectedRoutes:    "R1",
+			expectedStops:     "S1,S2",
+			expectedServices:  "WD",
+			expectedShapes:    2,
+			expectedStartDate: "20250301",
+		},
+		"bounding-box": {
+			opts:              FilterOptions{BoundingBox: &BoundingBox{MinLat: 61.4979, MinLon: 23.759, MaxLat: 61.4981, MaxLon: 23.761}},
+			expectedTrips:     "T1",
+			expectedRoutes:    "R1",
+			expectedStops:     "S1,S2",
+			expectedServices:  "WD",
+			expectedShapes:    2,
+			expectedStartDate: "20250101",
+		},
+	}
+
+	feed, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			filtered := FilterFeed(feed, tt.opts)
+
+			var trips, routes, stops []string
+			for _, trip := range filtered.Trips {
+				trips = append(trips, *trip.Id)
+			}
+			for _, r := range filtered.Routes {
+				routes = append(routes, *r.Id)
+			}
+			for _, s := range filtered.Stops {
+				stops = append(stops, *s.Id)
+			}
+
+			if v := strings.Join(trips, ","); v != tt.expectedTrips {
+				t.Errorf("expected trips %v, got %v", tt.expectedTrips, v)
+			}
+			if v := strings.Join(routes, ","); v != tt.expectedRoutes {
+				t.Errorf("expected routes %v, got %v", tt.expectedRoutes, v)
+			}
+			if v := strings.Join(stops, ","); v != tt.expectedStops {
+				t.Errorf("expected stops %v, got %v", tt.expectedStops, v)
+			}
+			if v := strings.Join(filtered.ServiceIds(), ","); v != tt.expectedServices {
+				t.Errorf("expected services %v, got %v", tt.expectedServices, v)
+			}
+			if len(filtered.Shapes) != tt.expectedShapes {
+				t.Errorf("expected %v shape points, got %v", tt.expectedShapes, len(filtered.Shapes))
+			}
+
+			startDate := ""
+			if ci, ok := filtered.CalendarItemByService("WD"); ok {
+				startDate = *ci.StartDate
+			}
+			if startDate != tt.expectedStartDate {
+				t.Errorf("expected calendar to start on %v, got %v", tt.expectedStartDate, startDate)
+			}
+
+			if tt.expectedTrips != "" && len(filtered.Agencies) != 1 {
+				t.Errorf("expected the agency to be kept, got %v", len(filtered.Agencies))
+			}
+		})
+	}
+}
+
+func TestFilterFeedOptionalFiles(t *testing.T) {
+	files := validFeedFiles()
+	for name, content := range optionalFeedFiles() {
+		files[name] = content
+	}
+	files[FileNameFareAttributes] += "F2,3.00,EUR,0,\n"
+	files[FileNameFareRules] += "F2,R2\n"
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	tests := map[string]struct {
+		opts                FilterOptions
+		expectedFrequencies int
+		expectedTransfers   int
+		expectedFares       string
+		expectedStartDate   string
+	}{
+		"route-id": {
+			opts:                FilterOptions{RouteIds: []string{"R1"}},
+			expectedFrequencies: 1,
+			expectedTransfers:   1,
+			expectedFares:       "F1",
+			expectedStartDate:   "20250101",
+		},
+		"line-name": {
+			opts:                FilterOptions{LineNames: []string{"2"}},
+			expectedFrequencies: 0,
+			expectedTransfers:   0,
+			expectedFares:       "F2",
+			expectedStartDate:   "20250101",
+		},
+		"date-window": {
+			opts:                FilterOptions{StartDate: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+			expectedFrequencies: 1,
+			expectedTransfers:   1,
+			expectedFares:       "F1",
+			expectedStartDate:   "20250301",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			filtered := FilterFeed(feed, tt.opts)
+
+			if len(filtered.Frequencies) != tt.expectedFrequencies {
+				t.Errorf("expected %v frequencies, got %v", tt.expectedFrequencies, len(filtered.Frequencies))
+			}
+			if len(filtered.Transfers) != tt.expectedTransfers {
+				t.Errorf("expected %v transfers, got %v", tt.expectedTransfers, len(filtered.Transfers))
+			}
+
+			var fares, rules []string
+			for _, fa := range filtered.FareAttributes {
+				fares = append(fares, *fa.Id)
+			}
+			for _, r := range filtered.FareRules {
+				rules = append(rules, *r.FareId)
+			}
+			if v := strings.Join(fares, ","); v != tt.expectedFares {
+				t.Errorf("expected fares %v, got %v", tt.expectedFares, v)
+			}
+			if v := strings.Join(rules, ","); v != tt.expectedFares {
+				t.Errorf("expected fare rules of %v, got %v", tt.expectedFares, v)
+			}
+
+			if len(filtered.FeedInfo) != 1 || *filtered.FeedInfo[0].StartDate != tt.expectedStartDate {
+				t.Errorf("expected feed info starting on %v, got %v", tt.expectedStartDate, filtered.FeedInfo)
+			}
+		})
+	}
+}
@@ -1,10 +1,92 @@
 package ggtfs
 
 type Frequency struct {
-	TripId      string
-	StartTime   string
-	EndTime     string
-	HeadwaySecs uint
-	ExactTimes  *int
+	TripId      *string // trip_id 		(required)
+	StartTime   *string // start_time 	(required)
+	EndTime     *string // end_time 	(required)
+	HeadwaySecs *string // headway_secs (required)
+	ExactTimes  *string // exact_times 	(optional)
 	LineNumber  int
 }
+
+func CreateFrequency(row []string, headers map[string]int, lineNumber int) *Frequency {
+	frequency := &Frequency{
+		LineNumber: lineNumber,
+	}
+
+	for hName := range headers {
+		v := getRowValueForHeaderName(row, headers, hName)
+		switch hName {
+		case "trip_id":
+			frequency.TripId = v
+		case "start_time":
+			frequency.StartTime = v
+		case "end_time":
+			frequency.EndTime = v
+		case "headway_secs":
+			frequency.HeadwaySecs = v
+		case "exact_times":
+			frequency.ExactTimes = v
+		}
+	}
+
+	return frequency
+}
+
+func ValidateFrequency(f Frequency) []ValidationNotice {
+	var validationResults []ValidationNotice
+
+	fields := []struct {
+		fieldType FieldType
+		name      string
+		value     *string
+		required  bool
+	}{
+		{FieldTypeID, "trip_id", f.TripId, true},
+		{FieldTypeTime, "start_time", f.StartTime, true},
+		{FieldTypeTime, "end_time", f.EndTime, true},
+		{FieldTypeInteger, "headway_secs", f.HeadwaySecs, true},
+		{FieldTypeExactTimes, "exact_times", f.ExactTimes, false},
+	}
+
+	for _, field := range fields {
+		validationResults = append(validationResults, validateField(field.fieldType, field.name, field.value, field.required, FileNameFrequencies, f.LineNumber)...)
+	}
+
+	return validationResults
+}
+
+func ValidateFrequencies(frequencies []*Frequency, trips []*Trip) []ValidationNotice {
+	var results []ValidationNotice
+
+	tripIds := make(map[string]struct{})
+	for _, trip := range trips {
+		if trip != nil && !StringIsNilOrEmpty(trip.Id) {
+			tripIds[*trip.Id] = struct{}{}
+		}
+	}
+
+	for _, frequency := range frequencies {
+		if frequency == nil {
+			continue
+		}
+
+		results = append(results, ValidateFrequency(*frequency)...)
+
+		if trips == nil || StringIsNilOrEmpty(frequency.TripId) {
+			continue
+		}
+		if _, found := tripIds[*frequency.TripId]; !found {
+			results = append(results, ForeignKeyViolationNotice{
+				ReferencingFileName:  FileNameFrequencies,
+				ReferencingFieldName: "trip_id",
+				ReferencedFileName:   FileNameTrips,
+				ReferencedFieldName:  "trip_id",
+				OffendingValue:       *frequency.TripId,
+				ReferencedAtRow:      frequency.LineNumber,
+			})
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,96 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCreateFrequency(t *testing.T) {
+	headerMap := map[string]int{"trip_id": 0, "start_time": 1, "end_time": 2, "headway_secs": 3, "exact_times": 4}
+
+	tests := map[string]struct {
+		headers  map[string]int
+		rows     [][]string
+		expected []*Frequency
+	}{
+		"nil-values": {
+			headers:  headerMap,
+			rows:     [][]string{nil},
+			expected: []*Frequency{{}},
+		},
+		"OK": {
+			headers: headerMap,
+			rows:    [][]string{{"T1", "06:00:00", "09:00:00", "600", "1"}},
+			expected: []*Frequency{{
+				TripId:      stringPtr("T1"),
+				StartTime:   stringPtr("06:00:00"),
+				EndTime:     stringPtr("09:00:00"),
+				HeadwaySecs: stringPtr("600"),
+				ExactTimes:  stringPtr("1"),
+			}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			var actual []*Frequency
+			for i, row := range tt.rows {
+				actual = append(actual, CreateFrequency(row, tt.headers, i))
+			}
+			handleEntityCreateResults(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestValidateFrequencies(t *testing.T) {
+	tests := map[string]struct {
+		actualEntities  []*Frequency
+		trips           []*Trip
+		expectedResults []ValidationNotice
+	}{
+		"nil-slice-items": {
+			actualEntities:  []*Frequency{nil},
+			expectedResults: []ValidationNotice{},
+		},
+		"invalid-fields": {
+			actualEntities: []*Frequency{{
+				TripId:      stringPtr("T1"),
+				StartTime:   stringPtr("6 am"),
+				EndTime:     stringPtr("09:00:00"),
+				HeadwaySecs: stringPtr("ten minutes"),
+				ExactTimes:  stringPtr("2"),
+			}},
+			expectedResults: []ValidationNotice{
+				InvalidTimeNotice{SingleLineNotice{FileName: "frequencies.txt", FieldName: "start_time"}},
+				InvalidIntegerNotice{SingleLineNotice{FileName: "frequencies.txt", FieldName: "headway_secs"}},
+				InvalidExactTimesNotice{SingleLineNotice{FileName: "frequencies.txt", FieldName: "exact_times"}},
+			},
+		},
+		"missing-trip": {
+			actualEntities: []*Frequency{{
+				TripId:      stringPtr("T2"),
+				StartTime:   stringPtr("06:00:00"),
+				EndTime:     stringPtr("09:00:00"),
+				HeadwaySecs: stringPtr("600"),
+			}},
+			trips: []*Trip{{Id: stringPtr("T1")}},
+			expectedResults: []ValidationNotice{
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "frequencies.txt",
+					ReferencingFieldName: "trip_id",
+					ReferencedFileName:   "trips.txt",
+					ReferencedFieldName:  "trip_id",
+					OffendingValue:       "T2",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			handleValidationResults(t, ValidateFrequencies(tt.actualEntities, tt.trips), tt.expectedResults)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package ggtfs
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusMeters = 6371008.8
+
+// HaversineDistance returns the great-circle distance between two WGS84 coordinates in meters.
+func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// ParseCoordinates parses a latitude and longitude pair, returning false if either one is missing or malformed.
+func ParseCoordinates(lat *string, lon *string) (float64, float64, bool) {
+	if StringIsNilOrEmpty(lat) || StringIsNilOrEmpty(lon) {
+		return 0, 0, false
+	}
+
+	parsedLat, err := strconv.ParseFloat(strings.TrimSpace(*lat), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	parsedLon, err := strconv.ParseFloat(strings.TrimSpace(*lon), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return parsedLat, parsedLon, true
+}
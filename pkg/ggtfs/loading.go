@@ -46,6 +46,26 @@ func LoadShapes(reader *GtfsCsvReader) ([]*Shape, []error) {
 	return loadCsvEntities[*Shape](defaultShapeHeaders, reader, CreateShape)
 }
 
+func LoadFrequencies(reader *GtfsCsvReader) ([]*Frequency, []error) {
+	return loadCsvEntities[*Frequency](defaultFrequencyHeaders, reader, CreateFrequency)
+}
+
+func LoadTransfers(reader *GtfsCsvReader) ([]*Transfer, []error) {
+	return loadCsvEntities[*Transfer](defaultTransferHeaders, reader, CreateTransfer)
+}
+
+func LoadFareAttributes(reader *GtfsCsvReader) ([]*FareAttributes, []error) {
+	return loadCsvEntities[*FareAttributes](defaultFareAttributesHeaders, reader, CreateFareAttributes)
+}
+
+func LoadFareRules(reader *GtfsCsvReader) ([]*FareRule, []error) {
+	return loadCsvEntities[*FareRule](defaultFareRuleHeaders, reader, CreateFareRule)
+}
+
+func LoadFeedInfo(reader *GtfsCsvReader) ([]*FeedInfo, []error) {
+	return loadCsvEntities[*FeedInfo](defaultFeedInfoHeaders, reader, CreateFeedInfo)
+}
+
 func loadCsvEntities[T CsvEntity](headerNames []string, reader *GtfsCsvReader, entityCreator csvEntityCreator[T]) ([]T, []error) {
 	var errs []error
 
@@ -113,6 +133,14 @@ var defaultStopTimeHeaders = []string{"trip_id", "arrival_time", "departure_time
 	"shape_dist_traveled", "timepoint"}
 var defaultTripHeaders = []string{"route_id", "service_id", "trip_id", "trip_headsign", "trip_short_name",
 	"direction_id", "block_id", "shape_id", "wheelchair_accessible", "bikes_allowed"}
+var defaultFrequencyHeaders = []string{"trip_id", "start_time", "end_time", "headway_secs", "exact_times"}
+var defaultTransferHeaders = []string{"from_stop_id", "to_stop_id", "from_route_id", "to_route_id", "from_trip_id",
+	"to_trip_id", "transfer_type", "min_transfer_time"}
+var defaultFareAttributesHeaders = []string{"fare_id", "price", "currency_type", "payment_method", "transfers",
+	"agency_id", "transfer_duration"}
+var defaultFareRuleHeaders = []string{"fare_id", "route_id", "origin_id", "destination_id", "contains_id"}
+var defaultFeedInfoHeaders = []string{"feed_publisher_name", "feed_publisher_url", "feed_lang", "default_lang",
+	"feed_start_date", "feed_end_date", "feed_version", "feed_contact_email", "feed_contact_url"}
 
 type csvEntityCreator[T CsvEntity] func(row []string, headers map[string]int, lineNumber int) T
 
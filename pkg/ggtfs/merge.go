@@ -0,0 +1,578 @@
+package ggtfs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultStopMatchRadius is the distance in meters within which two stops sharing a stop_code are considered
+// to be the same stop when merging feeds.
+const DefaultStopMatchRadius = 50.0
+
+type MergeOptions struct {
+	// Namespaces are used to prefix colliding ids of the respective feed, as in "<namespace>:<id>". Feeds
+	// without a namespace use their 1-based position in the feed list.
+	Namespaces []string
+	// StopMatchRadius is the maximum distance in meters between two stops with the same stop_code for them to
+	// be unified into one stop. Zero means DefaultStopMatchRadius, a negative value disables the matching.
+	StopMatchRadius float64
+}
+
+// MergeFeeds combines the feeds into a single feed. The first feed keeps its ids. Entities of the later feeds
+// whose ids are already taken are either deduplicated, when they are identical to the existing entity, or
+// renamed into the feed's namespace. Agencies with the same name and URL are merged, and stops with the same
+// stop_code within StopMatchRadius of a stop from an earlier feed are unified. Frequencies, transfers and fares
+// follow the ids of the entities they reference, and feed_info.txt keeps the row of the first feed with the dates
+// widened to cover every feed. The input feeds are not modified. The returned notices describe every rename and
+// unification.
+func MergeFeeds(feeds []*Feed, opts MergeOptions) (*Feed, []ValidationNotice) {
+	m := feedMerger{
+		result:      &Feed{},
+		radius:      opts.StopMatchRadius,
+		agencies:    make(map[string]*Agency),
+		routes:      make(map[string]*Route),
+		stops:       make(map[string]*Stop),
+		stopsByCode: make(map[string][]mergedStop),
+		services:    make(map[string]string),
+		shapes:      make(map[string]string),
+		trips:       make(map[string]string),
+		fares:       make(map[string]string),
+		transfers:   make(map[string]bool),
+	}
+
+	if m.radius == 0 {
+		m.radius = DefaultStopMatchRadius
+	}
+
+	for i, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+
+		namespace := fmt.Sprintf("%v", i+1)
+		if i < len(opts.Namespaces) && opts.Namespaces[i] != "" {
+			namespace = opts.Namespaces[i]
+		}
+
+		m.add(feed, namespace, i)
+	}
+
+	m.result.Reindex()
+
+	return m.result, m.notices
+}
+
+type mergedStop struct {
+	id        string
+	lat, lon  float64
+	located   bool
+	feedIndex int
+}
+
+type feedMerger struct {
+	result  *Feed
+	notices []ValidationNotice
+	radius  float64
+
+	agencies    map[string]*Agency
+	routes      map[string]*Route
+	stops       map[string]*Stop
+	stopsByCode map[string][]mergedStop
+	// services, shapes, trips and fares map the merged id to a signature which is used to detect identical entities
+	services  map[string]string
+	shapes    map[string]string
+	trips     map[string]string
+	fares     map[string]string
+	transfers map[string]bool
+}
+
+func (m *feedMerger) add(feed *Feed, namespace string, feedIndex int) {
+	agencyIds := m.addAgencies(feed, namespace)
+	stopIds := m.addStops(feed, namespace, feedIndex)
+	routeIds := m.addRoutes(feed, namespace, agencyIds)
+	serviceIds := m.addServices(feed, namespace)
+	shapeIds := m.addShapes(feed, namespace)
+	tripIds := m.addTrips(feed, namespace, stopIds, routeIds, serviceIds, shapeIds)
+	m.addTransfers(feed, stopIds, routeIds, tripIds)
+	m.addFares(feed, namespace, agencyIds, routeIds)
+	m.addFeedInfo(feed)
+}
+
+func (m *feedMerger) addAgencies(feed *Feed, namespace string) map[string]string {
+	ids := make(map[string]string)
+
+	for _, a := range feed.Agencies {
+		if a == nil {
+			continue
+		}
+
+		id := idKey(a.Id)
+		if id == "" {
+			// A feed with a single agency may omit agency_id, but the merged feed will have several agencies.
+			id = namespace
+		}
+
+		if existing, ok := m.agencies[id]; ok && sameAgency(existing, a) {
+			ids[idKey(a.Id)] = id
+			continue
+		}
+
+		if mergedId, ok := m.findAgency(a); ok {
+			ids[idKey(a.Id)] = mergedId
+			m.notices = append(m.notices, EntityMergedNotice{FileName: FileNameAgency, FieldName: "agency_id", Id: id, MergedInto: mergedId})
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameAgency, "agency_id", func(id string) bool { _, taken := m.agencies[id]; return taken })
+
+		c := *a
+		c.Id = strPtr(newId)
+		m.agencies[newId] = &c
+		m.result.Agencies = append(m.result.Agencies, &c)
+		ids[idKey(a.Id)] = newId
+	}
+
+	return ids
+}
+
+func (m *feedMerger) findAgency(a *Agency) (string, bool) {
+	for _, existing := range m.result.Agencies {
+		if sameAgency(existing, a) {
+			return idKey(existing.Id), true
+		}
+	}
+	return "", false
+}
+
+func (m *feedMerger) addStops(feed *Feed, namespace string, feedIndex int) map[string]string {
+	ids := make(map[string]string)
+	var added []*Stop
+
+	for _, s := range feed.Stops {
+		if s == nil || StringIsNilOrEmpty(s.Id) {
+			continue
+		}
+
+		id := idKey(s.Id)
+		lat, lon, located := ParseCoordinates(s.Lat, s.Lon)
+
+		if existing, ok := m.stops[id]; ok && m.sameStop(existing, s) {
+			ids[id] = id
+			continue
+		}
+
+		if mergedId, ok := m.findStopByCode(idKey(s.Code), lat, lon, located, feedIndex); ok {
+			ids[id] = mergedId
+			m.notices = append(m.notices, EntityMergedNotice{FileName: FileNameStops, FieldName: "stop_id", Id: id, MergedInto: mergedId})
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameStops, "stop_id", func(id string) bool { _, taken := m.stops[id]; return taken })
+
+		c := *s
+		c.Id = strPtr(newId)
+		m.stops[newId] = &c
+		m.result.Stops = append(m.result.Stops, &c)
+		added = append(added, &c)
+		ids[id] = newId
+
+		if code := idKey(s.Code); code != "" {
+			m.stopsByCode[code] = append(m.stopsByCode[code], mergedStop{id: newId, lat: lat, lon: lon, located: located, feedIndex: feedIndex})
+		}
+	}
+
+	for _, s := range added {
+		if parent, ok := ids[idKey(s.ParentStation)]; ok && !StringIsNilOrEmpty(s.ParentStation) {
+			s.ParentStation = strPtr(parent)
+		}
+	}
+
+	return ids
+}
+
+// findStopByCode looks for a stop with the given code from an earlier feed. Stops within the same feed are never
+// unified, even if they share a code.
+func (m *feedMerger) findStopByCode(code string, lat, lon float64, located bool, feedIndex int) (string, bool) {
+	if code == "" || m.radius < 0 || !located {
+		return "", false
+	}
+
+	for _, candidate := range m.stopsByCode[code] {
+		if candidate.feedIndex == feedIndex || !candidate.located {
+			continue
+		}
+		if HaversineDistance(lat, lon, candidate.lat, candidate.lon) <= m.radius {
+			return candidate.id, true
+		}
+	}
+
+	return "", false
+}
+
+func (m *feedMerger) sameStop(a *Stop, b *Stop) bool {
+	if !strings.EqualFold(idKey(a.Name), idKey(b.Name)) {
+		return false
+	}
+
+	aLat, aLon, aOk := ParseCoordinates(a.Lat, a.Lon)
+	bLat, bLon, bOk := ParseCoordinates(b.Lat, b.Lon)
+	if !aOk || !bOk {
+		return aOk == bOk
+	}
+
+	radius := m.radius
+	if radius < 0 {
+		radius = 0
+	}
+
+	return HaversineDistance(aLat, aLon, bLat, bLon) <= radius
+}
+
+func (m *feedMerger) addRoutes(feed *Feed, namespace string, agencyIds map[string]string) map[string]string {
+	ids := make(map[string]string)
+
+	var defaultAgencyId *string
+	if len(feed.Agencies) == 1 && feed.Agencies[0] != nil {
+		defaultAgencyId = strPtr(agencyIds[idKey(feed.Agencies[0].Id)])
+	}
+
+	for _, r := range feed.Routes {
+		if r == nil || StringIsNilOrEmpty(r.Id) {
+			continue
+		}
+
+		id := idKey(r.Id)
+
+		c := *r
+		if agencyId, ok := agencyIds[idKey(r.AgencyId)]; ok && !StringIsNilOrEmpty(r.AgencyId) {
+			c.AgencyId = strPtr(agencyId)
+		} else if StringIsNilOrEmpty(r.AgencyId) && defaultAgencyId != nil {
+			c.AgencyId = defaultAgencyId
+		}
+
+		if existing, ok := m.routes[id]; ok && routeSignature(existing) == routeSignature(&c) {
+			ids[id] = id
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameRoutes, "route_id", func(id string) bool { _, taken := m.routes[id]; return taken })
+
+		c.Id = strPtr(newId)
+		m.routes[newId] = &c
+		m.result.Routes = append(m.result.Routes, &c)
+		ids[id] = newId
+	}
+
+	return ids
+}
+
+func (m *feedMerger) addServices(feed *Feed, namespace string) map[string]string {
+	ids := make(map[string]string)
+
+	for _, id := range feed.ServiceIds() {
+		item, hasItem := feed.CalendarItemByService(id)
+		dates := feed.CalendarDatesByService(id)
+		signature := serviceSignature(item, dates)
+
+		if existing, ok := m.services[id]; ok && existing == signature {
+			ids[id] = id
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameCalendar, "service_id", func(id string) bool { _, taken := m.services[id]; return taken })
+		m.services[newId] = signature
+		ids[id] = newId
+
+		if hasItem {
+			c := *item
+			c.ServiceId = strPtr(newId)
+			m.result.CalendarItems = append(m.result.CalendarItems, &c)
+		}
+
+		for _, cd := range dates {
+			c := *cd
+			c.ServiceId = strPtr(newId)
+			m.result.CalendarDates = append(m.result.CalendarDates, &c)
+		}
+	}
+
+	return ids
+}
+
+func (m *feedMerger) addShapes(feed *Feed, namespace string) map[string]string {
+	ids := make(map[string]string)
+
+	for _, s := range feed.Shapes {
+		if s == nil || StringIsNilOrEmpty(s.Id) {
+			continue
+		}
+
+		id := idKey(s.Id)
+		if _, done := ids[id]; done {
+			continue
+		}
+
+		points := feed.ShapePoints(id)
+		signature := shapeSignature(points)
+
+		if existing, ok := m.shapes[id]; ok && existing == signature {
+			ids[id] = id
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameShapes, "shape_id", func(id string) bool { _, taken := m.shapes[id]; return taken })
+		m.shapes[newId] = signature
+		ids[id] = newId
+
+		for _, p := range points {
+			c := *p
+			c.Id = strPtr(newId)
+			m.result.Shapes = append(m.result.Shapes, &c)
+		}
+	}
+
+	return ids
+}
+
+func (m *feedMerger) addTrips(feed *Feed, namespace string, stopIds, routeIds, serviceIds, shapeIds map[string]string) map[string]string {
+	ids := make(map[string]string)
+
+	for _, t := range feed.Trips {
+		if t == nil || StringIsNilOrEmpty(t.Id) {
+			continue
+		}
+
+		id := idKey(t.Id)
+
+		c := *t
+		c.RouteId = remapId(t.RouteId, routeIds)
+		c.ServiceId = remapId(t.ServiceId, serviceIds)
+		c.ShapeId = remapId(t.ShapeId, shapeIds)
+
+		var stopTimes []*StopTime
+		for _, st := range feed.StopTimesByTrip(id) {
+			stc := *st
+			stc.StopId = remapId(st.StopId, stopIds)
+			stopTimes = append(stopTimes, &stc)
+		}
+
+		frequencies := feed.FrequenciesByTrip(id)
+
+		signature := tripSignature(&c, stopTimes, frequencies)
+		if existing, ok := m.trips[id]; ok && existing == signature {
+			ids[id] = id
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameTrips, "trip_id", func(id string) bool { _, taken := m.trips[id]; return taken })
+		m.trips[newId] = signature
+		ids[id] = newId
+
+		c.Id = strPtr(newId)
+		m.result.Trips = append(m.result.Trips, &c)
+
+		for _, st := range stopTimes {
+			st.TripId = strPtr(newId)
+			m.result.StopTimes = append(m.result.StopTimes, st)
+		}
+
+		for _, f := range frequencies {
+			fc := *f
+			fc.TripId = strPtr(newId)
+			m.result.Frequencies = append(m.result.Frequencies, &fc)
+		}
+	}
+
+	return ids
+}
+
+// addTransfers copies the transfers with their references remapped. A transfer identical to one of an earlier
+// feed is added only once.
+func (m *feedMerger) addTransfers(feed *Feed, stopIds, routeIds, tripIds map[string]string) {
+	for _, t := range feed.Transfers {
+		if t == nil {
+			continue
+		}
+
+		c := *t
+		c.FromStopId = remapId(t.FromStopId, stopIds)
+		c.ToStopId = remapId(t.ToStopId, stopIds)
+		c.FromRouteId = remapId(t.FromRouteId, routeIds)
+		c.ToRouteId = remapId(t.ToRouteId, routeIds)
+		c.FromTripId = remapId(t.FromTripId, tripIds)
+		c.ToTripId = remapId(t.ToTripId, tripIds)
+
+		signature := joinValues(c.FromStopId, c.ToStopId, c.FromRouteId, c.ToRouteId, c.FromTripId, c.ToTripId,
+			c.TransferType, c.MinTransferTime)
+		if m.transfers[signature] {
+			continue
+		}
+		m.transfers[signature] = true
+
+		m.result.Transfers = append(m.result.Transfers, &c)
+	}
+}
+
+// addFares copies the fare attributes together with their rules. The zone ids of the rules are kept as they are,
+// since the zone_id of the stops is not namespaced either.
+func (m *feedMerger) addFares(feed *Feed, namespace string, agencyIds, routeIds map[string]string) {
+	rulesByFare := make(map[string][]*FareRule)
+	for _, r := range feed.FareRules {
+		if r != nil && !StringIsNilOrEmpty(r.FareId) {
+			rulesByFare[idKey(r.FareId)] = append(rulesByFare[idKey(r.FareId)], r)
+		}
+	}
+
+	for _, fa := range feed.FareAttributes {
+		if fa == nil || StringIsNilOrEmpty(fa.Id) {
+			continue
+		}
+
+		id := idKey(fa.Id)
+
+		c := *fa
+		c.AgencyId = remapId(fa.AgencyId, agencyIds)
+
+		var rules []*FareRule
+		for _, r := range rulesByFare[id] {
+			rc := *r
+			rc.RouteId = remapId(r.RouteId, routeIds)
+			rules = append(rules, &rc)
+		}
+
+		signature := fareSignature(&c, rules)
+		if existing, ok := m.fares[id]; ok && existing == signature {
+			continue
+		}
+
+		newId := m.uniqueId(id, namespace, FileNameFareAttributes, "fare_id", func(id string) bool { _, taken := m.fares[id]; return taken })
+		m.fares[newId] = signature
+
+		c.Id = strPtr(newId)
+		m.result.FareAttributes = append(m.result.FareAttributes, &c)
+
+		for _, r := range rules {
+			r.FareId = strPtr(newId)
+			m.result.FareRules = append(m.result.FareRules, r)
+		}
+	}
+}
+
+// addFeedInfo keeps the feed_info.txt row of the first feed which has one, and widens its validity to cover the
+// later feeds. GTFS allows only a single row in the file.
+func (m *feedMerger) addFeedInfo(feed *Feed) {
+	if len(feed.FeedInfo) == 0 || feed.FeedInfo[0] == nil {
+		return
+	}
+	fi := feed.FeedInfo[0]
+
+	if len(m.result.FeedInfo) == 0 {
+		c := *fi
+		m.result.FeedInfo = append(m.result.FeedInfo, &c)
+		return
+	}
+
+	merged := m.result.FeedInfo[0]
+	// The dates are in the YYYYMMDD format, so they can be compared as strings.
+	if !StringIsNilOrEmpty(merged.StartDate) && !StringIsNilOrEmpty(fi.StartDate) && idKey(fi.StartDate) < idKey(merged.StartDate) {
+		merged.StartDate = fi.StartDate
+	}
+	if !StringIsNilOrEmpty(merged.EndDate) && !StringIsNilOrEmpty(fi.EndDate) && idKey(fi.EndDate) > idKey(merged.EndDate) {
+		merged.EndDate = fi.EndDate
+	}
+}
+
+// uniqueId returns id if it is free, otherwise the id prefixed with the namespace (and a counter, should even
+// that be taken).
+func (m *feedMerger) uniqueId(id string, namespace string, fileName string, fieldName string, taken func(string) bool) string {
+	if !taken(id) {
+		return id
+	}
+
+	newId := fmt.Sprintf("%v:%v", namespace, id)
+	for i := 2; taken(newId); i++ {
+		newId = fmt.Sprintf("%v:%v:%v", namespace, id, i)
+	}
+
+	m.notices = append(m.notices, IdNamespacedNotice{FileName: fileName, FieldName: fieldName, Id: id, NewId: newId})
+
+	return newId
+}
+
+func remapId(id *string, ids map[string]string) *string {
+	if StringIsNilOrEmpty(id) {
+		return id
+	}
+	if newId, ok := ids[idKey(id)]; ok {
+		return strPtr(newId)
+	}
+	return id
+}
+
+func sameAgency(a *Agency, b *Agency) bool {
+	return strings.EqualFold(idKey(a.Name), idKey(b.Name)) && idKey(a.URL) == idKey(b.URL)
+}
+
+func routeSignature(r *Route) string {
+	return joinValues(r.AgencyId, r.ShortName, r.LongName, r.Desc, r.Type, r.URL, r.Color, r.TextColor, r.SortOrder)
+}
+
+func serviceSignature(item *CalendarItem, dates []*CalendarDate) string {
+	var parts []string
+	if item != nil {
+		parts = append(parts, joinValues(item.Monday, item.Tuesday, item.Wednesday, item.Thursday, item.Friday,
+			item.Saturday, item.Sunday, item.StartDate, item.EndDate))
+	}
+
+	var dateParts []string
+	for _, cd := range dates {
+		dateParts = append(dateParts, joinValues(cd.Date, cd.ExceptionType))
+	}
+	sort.Strings(dateParts)
+
+	return strings.Join(append(parts, dateParts...), "|")
+}
+
+func shapeSignature(points []*Shape) string {
+	var parts []string
+	for _, p := range points {
+		parts = append(parts, joinValues(p.PtLat, p.PtLon, p.PtSequence))
+	}
+	return strings.Join(parts, "|")
+}
+
+func tripSignature(t *Trip, stopTimes []*StopTime, frequencies []*Frequency) string {
+	parts := []string{joinValues(t.RouteId, t.ServiceId, t.ShapeId, t.HeadSign, t.DirectionId)}
+	for _, st := range stopTimes {
+		parts = append(parts, joinValues(st.StopId, st.StopSequence, st.ArrivalTime, st.DepartureTime))
+	}
+	for _, f := range frequencies {
+		parts = append(parts, joinValues(f.StartTime, f.EndTime, f.HeadwaySecs, f.ExactTimes))
+	}
+	return strings.Join(parts, "|")
+}
+
+func fareSignature(fa *FareAttributes, rules []*FareRule) string {
+	parts := []string{joinValues(fa.Price, fa.CurrencyType, fa.PaymentMethod, fa.Transfers, fa.AgencyId, fa.TransferDuration)}
+
+	var ruleParts []string
+	for _, r := range rules {
+		ruleParts = append(ruleParts, joinValues(r.RouteId, r.OriginId, r.DestinationId, r.ContainsId))
+	}
+	sort.Strings(ruleParts)
+
+	return strings.Join(append(parts, ruleParts...), "|")
+}
+
+func joinValues(values ...*string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = idKey(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,178 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func trainFeedFiles() map[string]string {
+	return map[string]string{
+		FileNameAgency: "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"A1,VR,http://vr.fi,Europe/Helsinki\n",
+		FileNameRoutes: "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"R1,A1,R,Tampere - Helsinki,2\n",
+		FileNameStops: "stop_id,stop_code,stop_name,stop_lat,stop_lon\n" +
+			"KT,0001,Keskustori,61.49760,23.76150\n" +
+			"TPE,TPE,Tampere asema,61.49859,23.77324\n",
+		FileNameTrips: "route_id,service_id,trip_id\n" +
+			"R1,WD,T1\n",
+		FileNameStopTimes: "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,10:00:00,10:00:00,TPE,1\n" +
+			"T1,10:10:00,10:10:00,KT,2\n",
+		FileNameCalendar: "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WD,1,1,1,1,1,1,1,20250101,20251231\n",
+	}
+}
+
+func TestMergeFeeds(t *testing.T) {
+	buses, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	trains, errs := LoadFeed(writeFeedFiles(t, trainFeedFiles()), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	merged, notices := MergeFeeds([]*Feed{buses, trains}, MergeOptions{Namespaces: []string{"bus", "train"}})
+
+	if len(merged.Agencies) != 2 || len(merged.Stops) != 3 || len(merged.Routes) != 3 || len(merged.Trips) != 4 {
+		t.Fatalf("unexpected entity counts: agencies %v, stops %v, routes %v, trips %v",
+			len(merged.Agencies), len(merged.Stops), len(merged.Routes), len(merged.Trips))
+	}
+
+	if a, ok := merged.AgencyById("train:A1"); !ok || *a.Name != "VR" {
+		t.Errorf("expected namespaced agency train:A1, got %v", a)
+	}
+
+	if r, ok := merged.RouteById("train:R1"); !ok || *r.AgencyId != "train:A1" {
+		t.Errorf("expected route train:R1 to reference train:A1, got %v", r)
+	}
+
+	stopTimes := merged.StopTimesByTrip("train:T1")
+	if len(stopTimes) != 2 || *stopTimes[0].StopId != "TPE" || *stopTimes[1].StopId != "S1" {
+		t.Errorf("expected train stop times to use the unified stop S1, got %v", stopTimes)
+	}
+
+	if ci, ok := merged.CalendarItemByService("train:WD"); !ok || *ci.Saturday != "1" {
+		t.Errorf("expected namespaced service train:WD, got %v", ci)
+	}
+
+	handleValidationResults(t, notices, []ValidationNotice{
+		IdNamespacedNotice{FileName: FileNameAgency, FieldName: "agency_id", Id: "A1", NewId: "train:A1"},
+		EntityMergedNotice{FileName: FileNameStops, FieldName: "stop_id", Id: "KT", MergedInto: "S1"},
+		IdNamespacedNotice{FileName: FileNameRoutes, FieldName: "route_id", Id: "R1", NewId: "train:R1"},
+		IdNamespacedNotice{FileName: FileNameCalendar, FieldName: "service_id", Id: "WD", NewId: "train:WD"},
+		IdNamespacedNotice{FileName: FileNameTrips, FieldName: "trip_id", Id: "T1", NewId: "train:T1"},
+	})
+}
+
+func TestMergeFeedsDeduplication(t *testing.T) {
+	tests := map[string]struct {
+		opts          MergeOptions
+		secondStops   string
+		expectedStops int
+	}{
+		"identical-feeds": {
+			secondStops:   validFeedFiles()[FileNameStops],
+			expectedStops: 2,
+		},
+		"stop-moved-within-radius": {
+			secondStops:   "stop_id,stop_code,stop_name,stop_lat,stop_lon\nX1,0001,Keskustori,61.49760,23.76152\nS2,0002,Hämeenkatu,61.49800,23.76000\n",
+			expectedStops: 2,
+		},
+		"stop-moved-outside-radius": {
+			secondStops:   "stop_id,stop_code,stop_name,stop_lat,stop_lon\nX1,0001,Keskustori,61.50754,23.76152\nS2,0002,Hämeenkatu,61.49800,23.76000\n",
+			expectedStops: 3,
+		},
+		"matching-disabled": {
+			opts:          MergeOptions{StopMatchRadius: -1},
+			secondStops:   "stop_id,stop_code,stop_name,stop_lat,stop_lon\nX1,0001,Keskustori,61.49760,23.76152\nS2,0002,Hämeenkatu,61.49800,23.76000\n",
+			expectedStops: 3,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			first, errs := LoadFeed(writeFeedFiles(t, validFeedFiles()), CsvDialect{})
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			files := validFeedFiles()
+			files[FileNameStops] = tt.secondStops
+			second, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			merged, _ := MergeFeeds([]*Feed{first, second}, tt.opts)
+
+			if len(merged.Stops) != tt.expectedStops {
+				t.Errorf("expected %v stops, got %v", tt.expectedStops, len(merged.Stops))
+			}
+
+			if len(merged.Agencies) != 1 {
+				t.Errorf("expected agencies to be merged, got %v", len(merged.Agencies))
+			}
+		})
+	}
+}
+
+func TestMergeFeedsOptionalFiles(t *testing.T) {
+	busFiles := validFeedFiles()
+	for name, content := range optionalFeedFiles() {
+		busFiles[name] = content
+	}
+	buses, errs := LoadFeed(writeFeedFiles(t, busFiles), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	trainFiles := trainFeedFiles()
+	trainFiles[FileNameFrequencies] = "trip_id,start_time,end_time,headway_secs\nT1,06:00:00,22:00:00,3600\n"
+	trainFiles[FileNameTransfers] = "from_stop_id,to_stop_id,transfer_type,min_transfer_time\nTPE,KT,2,300\n"
+	trainFiles[FileNameFareAttributes] = "fare_id,price,currency_type,payment_method,transfers\nF1,4.90,EUR,1,0\n"
+	trainFiles[FileNameFareRules] = "fare_id,route_id\nF1,R1\n"
+	trainFiles[FileNameFeedInfo] = "feed_publisher_name,feed_publisher_url,feed_lang,feed_start_date,feed_end_date\n" +
+		"VR,http://vr.fi,fi,20241201,20260131\n"
+	trains, errs := LoadFeed(writeFeedFiles(t, trainFiles), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	merged, _ := MergeFeeds([]*Feed{buses, trains}, MergeOptions{Namespaces: []string{"bus", "train"}})
+
+	if frequencies := merged.FrequenciesByTrip("train:T1"); len(frequencies) != 1 || *frequencies[0].HeadwaySecs != "3600" {
+		t.Errorf("expected the train frequency to follow trip train:T1, got %v", frequencies)
+	}
+
+	if len(merged.Transfers) != 2 || *merged.Transfers[1].FromStopId != "TPE" || *merged.Transfers[1].ToStopId != "S1" {
+		t.Errorf("expected the train transfer to use the unified stop S1, got %v", merged.Transfers)
+	}
+
+	if fa, ok := merged.FareAttributesById("train:F1"); !ok || *fa.Price != "4.90" {
+		t.Errorf("expected namespaced fare train:F1, got %v", fa)
+	}
+	if len(merged.FareRules) != 2 || *merged.FareRules[1].FareId != "train:F1" || *merged.FareRules[1].RouteId != "train:R1" {
+		t.Errorf("expected the train fare rule to reference train:F1 and train:R1, got %v", merged.FareRules)
+	}
+
+	if len(merged.FeedInfo) != 1 || *merged.FeedInfo[0].PublisherName != "Nysse" ||
+		*merged.FeedInfo[0].StartDate != "20241201" || *merged.FeedInfo[0].EndDate != "20260131" {
+		t.Errorf("expected a single feed info row covering both feeds, got %v", merged.FeedInfo)
+	}
+
+	if notices := merged.Validate(ValidationOptions{MinimumSeverity: SeverityViolation}); len(notices) > 0 {
+		t.Errorf("expected the merged feed to be valid, got %v", notices)
+	}
+
+	duplicated, _ := MergeFeeds([]*Feed{buses, buses}, MergeOptions{})
+	if len(duplicated.Frequencies) != 1 || len(duplicated.Transfers) != 1 || len(duplicated.FareAttributes) != 1 ||
+		len(duplicated.FareRules) != 1 || len(duplicated.FeedInfo) != 1 {
+		t.Errorf("expected identical optional entities to be deduplicated, got %+v", duplicated)
+	}
+}
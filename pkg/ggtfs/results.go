@@ -437,6 +437,62 @@ func (n InvalidBikesAllowedNotice) AsText() string {
 	return convertSingleLineNotice(n.Code(), n.FileName, n.FieldName, n.Line)
 }
 
+type InvalidExactTimesNotice struct {
+	SingleLineNotice
+}
+
+func (n InvalidExactTimesNotice) Code() string {
+	return "invalid_exact_times"
+}
+func (n InvalidExactTimesNotice) Severity() ValidationNoticeSeverity {
+	return SeverityViolation
+}
+func (n InvalidExactTimesNotice) AsText() string {
+	return convertSingleLineNotice(n.Code(), n.FileName, n.FieldName, n.Line)
+}
+
+type InvalidTransferTypeNotice struct {
+	SingleLineNotice
+}
+
+func (n InvalidTransferTypeNotice) Code() string {
+	return "invalid_transfer_type"
+}
+func (n InvalidTransferTypeNotice) Severity() ValidationNoticeSeverity {
+	return SeverityViolation
+}
+func (n InvalidTransferTypeNotice) AsText() string {
+	return convertSingleLineNotice(n.Code(), n.FileName, n.FieldName, n.Line)
+}
+
+type InvalidPaymentMethodNotice struct {
+	SingleLineNotice
+}
+
+func (n InvalidPaymentMethodNotice) Code() string {
+	return "invalid_payment_method"
+}
+func (n InvalidPaymentMethodNotice) Severity() ValidationNoticeSeverity {
+	return SeverityViolation
+}
+func (n InvalidPaymentMethodNotice) AsText() string {
+	return convertSingleLineNotice(n.Code(), n.FileName, n.FieldName, n.Line)
+}
+
+type InvalidTransfersNotice struct {
+	SingleLineNotice
+}
+
+func (n InvalidTransfersNotice) Code() string {
+	return "invalid_transfers"
+}
+func (n InvalidTransfersNotice) Severity() ValidationNoticeSeverity {
+	return SeverityViolation
+}
+func (n InvalidTransfersNotice) AsText() string {
+	return convertSingleLineNotice(n.Code(), n.FileName, n.FieldName, n.Line)
+}
+
 type MissingRouteShortNameWhenLongNameIsNotPresentNotice struct {
 	SingleLineNotice
 }
@@ -601,6 +657,69 @@ func (n ForeignKeyViolationNotice) AsText() string {
 	return fmt.Sprintf("%s from %v:%v->%v(value: %v) to %v->%v", n.Code(), n.ReferencingFileName, n.ReferencedAtRow, n.ReferencingFieldName, n.OffendingValue, n.ReferencedFileName, n.ReferencedFieldName)
 }
 
+type CharacterEncodingConvertedNotice struct {
+	FileName string
+	Encoding CharacterEncoding
+}
+
+func (n CharacterEncodingConvertedNotice) Code() string {
+	return "character_encoding_converted"
+}
+func (n CharacterEncodingConvertedNotice) Severity() ValidationNoticeSeverity {
+	return SeverityInfo
+}
+func (n CharacterEncodingConvertedNotice) AsText() string {
+	return fmt.Sprintf("%s in %v (%v -> %v)", n.Code(), n.FileName, n.Encoding, EncodingUTF8)
+}
+
+type EntityMergedNotice struct {
+	FileName   string
+	FieldName  string
+	Id         string
+	MergedInto string
+}
+
+func (n EntityMergedNotice) Code() string {
+	return "entity_merged"
+}
+func (n EntityMergedNotice) Severity() ValidationNoticeSeverity {
+	return SeverityInfo
+}
+func (n EntityMergedNotice) AsText() string {
+	return fmt.Sprintf("%s in %v->%v (%v -> %v)", n.Code(), n.FileName, n.FieldName, n.Id, n.MergedInto)
+}
+
+type IdNamespacedNotice struct {
+	FileName  string
+	FieldName string
+	Id        string
+	NewId     string
+}
+
+func (n IdNamespacedNotice) Code() string {
+	return "id_namespaced"
+}
+func (n IdNamespacedNotice) Severity() ValidationNoticeSeverity {
+	return SeverityInfo
+}
+func (n IdNamespacedNotice) AsText() string {
+	return fmt.Sprintf("%s in %v->%v (%v -> %v)", n.Code(), n.FileName, n.FieldName, n.Id, n.NewId)
+}
+
+type FileNotLoadedNotice struct {
+	FileName string
+}
+
+func (n FileNotLoadedNotice) Code() string {
+	return "file_not_loaded"
+}
+func (n FileNotLoadedNotice) Severity() ValidationNoticeSeverity {
+	return SeverityRecommendation
+}
+func (n FileNotLoadedNotice) AsText() string {
+	return fmt.Sprintf("%s %v, the file is not supported and is left out of the feed", n.Code(), n.FileName)
+}
+
 func convertSingleLineNotice(code string, fileName string, fieldName string, line int) string {
 	return fmt.Sprintf("%s in %v->%v (line %v)", code, fileName, fieldName, line)
 }
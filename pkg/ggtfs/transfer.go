@@ -1,9 +1,134 @@
 package ggtfs
 
 type Transfer struct {
-	FromStopId      string
-	ToStopId        string
-	TransferType    uint
-	MinTransferTime uint
+	FromStopId      *string // from_stop_id 		(conditionally required)
+	ToStopId        *string // to_stop_id 			(conditionally required)
+	FromRouteId     *string // from_route_id 		(optional)
+	ToRouteId       *string // to_route_id 			(optional)
+	FromTripId      *string // from_trip_id 		(conditionally required)
+	ToTripId        *string // to_trip_id 			(conditionally required)
+	TransferType    *string // transfer_type 		(required)
+	MinTransferTime *string // min_transfer_time 	(optional)
 	LineNumber      int
 }
+
+func CreateTransfer(row []string, headers map[string]int, lineNumber int) *Transfer {
+	transfer := &Transfer{
+		LineNumber: lineNumber,
+	}
+
+	for hName := range headers {
+		v := getRowValueForHeaderName(row, headers, hName)
+		switch hName {
+		case "from_stop_id":
+			transfer.FromStopId = v
+		case "to_stop_id":
+			transfer.ToStopId = v
+		case "from_route_id":
+			transfer.FromRouteId = v
+		case "to_route_id":
+			transfer.ToRouteId = v
+		case "from_trip_id":
+			transfer.FromTripId = v
+		case "to_trip_id":
+			transfer.ToTripId = v
+		case "transfer_type":
+			transfer.TransferType = v
+		case "min_transfer_time":
+			transfer.MinTransferTime = v
+		}
+	}
+
+	return transfer
+}
+
+func ValidateTransfer(t Transfer) []ValidationNotice {
+	var validationResults []ValidationNotice
+
+	fields := []struct {
+		fieldType FieldType
+		name      string
+		value     *string
+		required  bool
+	}{
+		{FieldTypeID, "from_stop_id", t.FromStopId, false},
+		{FieldTypeID, "to_stop_id", t.ToStopId, false},
+		{FieldTypeID, "from_route_id", t.FromRouteId, false},
+		{FieldTypeID, "to_route_id", t.ToRouteId, false},
+		{FieldTypeID, "from_trip_id", t.FromTripId, false},
+		{FieldTypeID, "to_trip_id", t.ToTripId, false},
+		{FieldTypeTransferType, "transfer_type", t.TransferType, true},
+		{FieldTypeInteger, "min_transfer_time", t.MinTransferTime, false},
+	}
+
+	for _, field := range fields {
+		validationResults = append(validationResults, validateField(field.fieldType, field.name, field.value, field.required, FileNameTransfers, t.LineNumber)...)
+	}
+
+	return validationResults
+}
+
+func ValidateTransfers(transfers []*Transfer, stops []*Stop, routes []*Route, trips []*Trip) []ValidationNotice {
+	var results []ValidationNotice
+
+	stopIds := make(map[string]struct{})
+	for _, stop := range stops {
+		if stop != nil && !StringIsNilOrEmpty(stop.Id) {
+			stopIds[*stop.Id] = struct{}{}
+		}
+	}
+	routeIds := make(map[string]struct{})
+	for _, route := range routes {
+		if route != nil && !StringIsNilOrEmpty(route.Id) {
+			routeIds[*route.Id] = struct{}{}
+		}
+	}
+	tripIds := make(map[string]struct{})
+	for _, trip := range trips {
+		if trip != nil && !StringIsNilOrEmpty(trip.Id) {
+			tripIds[*trip.Id] = struct{}{}
+		}
+	}
+
+	for _, transfer := range transfers {
+		if transfer == nil {
+			continue
+		}
+
+		results = append(results, ValidateTransfer(*transfer)...)
+
+		references := []struct {
+			fieldName      string
+			value          *string
+			referencedFile string
+			referencedName string
+			referencedIds  map[string]struct{}
+			skip           bool
+		}{
+			{"from_stop_id", transfer.FromStopId, FileNameStops, "stop_id", stopIds, stops == nil},
+			{"to_stop_id", transfer.ToStopId, FileNameStops, "stop_id", stopIds, stops == nil},
+			{"from_route_id", transfer.FromRouteId, FileNameRoutes, "route_id", routeIds, routes == nil},
+			{"to_route_id", transfer.ToRouteId, FileNameRoutes, "route_id", routeIds, routes == nil},
+			{"from_trip_id", transfer.FromTripId, FileNameTrips, "trip_id", tripIds, trips == nil},
+			{"to_trip_id", transfer.ToTripId, FileNameTrips, "trip_id", tripIds, trips == nil},
+		}
+
+		for _, ref := range references {
+			if ref.skip || StringIsNilOrEmpty(ref.value) {
+				continue
+			}
+			if _, found := ref.referencedIds[*ref.value]; !found {
+				results = append(results, ForeignKeyViolationNotice{
+					ReferencingFileName:  FileNameTransfers,
+					ReferencingFieldName: ref.fieldName,
+					ReferencedFileName:   ref.referencedFile,
+					ReferencedFieldName:  ref.referencedName,
+					OffendingValue:       *ref.value,
+					ReferencedAtRow:      transfer.LineNumber,
+				})
+			}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,109 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	headerMap := map[string]int{"from_stop_id": 0, "to_stop_id": 1, "from_route_id": 2, "to_route_id": 3,
+		"from_trip_id": 4, "to_trip_id": 5, "transfer_type": 6, "min_transfer_time": 7}
+
+	tests := map[string]struct {
+		headers  map[string]int
+		rows     [][]string
+		expected []*Transfer
+	}{
+		"nil-values": {
+			headers:  headerMap,
+			rows:     [][]string{nil},
+			expected: []*Transfer{{}},
+		},
+		"OK": {
+			headers: headerMap,
+			rows:    [][]string{{"S1", "S2", "R1", "R2", "T1", "T2", "2", "180"}},
+			expected: []*Transfer{{
+				FromStopId:      stringPtr("S1"),
+				ToStopId:        stringPtr("S2"),
+				FromRouteId:     stringPtr("R1"),
+				ToRouteId:       stringPtr("R2"),
+				FromTripId:      stringPtr("T1"),
+				ToTripId:        stringPtr("T2"),
+				TransferType:    stringPtr("2"),
+				MinTransferTime: stringPtr("180"),
+			}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			var actual []*Transfer
+			for i, row := range tt.rows {
+				actual = append(actual, CreateTransfer(row, tt.headers, i))
+			}
+			handleEntityCreateResults(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestValidateTransfers(t *testing.T) {
+	tests := map[string]struct {
+		actualEntities  []*Transfer
+		stops           []*Stop
+		routes          []*Route
+		trips           []*Trip
+		expectedResults []ValidationNotice
+	}{
+		"nil-slice-items": {
+			actualEntities:  []*Transfer{nil},
+			expectedResults: []ValidationNotice{},
+		},
+		"invalid-fields": {
+			actualEntities: []*Transfer{{
+				FromStopId:      stringPtr("S1"),
+				ToStopId:        stringPtr("S2"),
+				TransferType:    stringPtr("6"),
+				MinTransferTime: stringPtr("three minutes"),
+			}},
+			expectedResults: []ValidationNotice{
+				InvalidTransferTypeNotice{SingleLineNotice{FileName: "transfers.txt", FieldName: "transfer_type"}},
+				InvalidIntegerNotice{SingleLineNotice{FileName: "transfers.txt", FieldName: "min_transfer_time"}},
+			},
+		},
+		"missing-references": {
+			actualEntities: []*Transfer{{
+				FromStopId:   stringPtr("S1"),
+				ToStopId:     stringPtr("S3"),
+				FromTripId:   stringPtr("T1"),
+				TransferType: stringPtr("3"),
+			}},
+			stops:  []*Stop{{Id: stringPtr("S1")}, {Id: stringPtr("S2")}},
+			routes: []*Route{},
+			trips:  []*Trip{{Id: stringPtr("T2")}},
+			expectedResults: []ValidationNotice{
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "transfers.txt",
+					ReferencingFieldName: "to_stop_id",
+					ReferencedFileName:   "stops.txt",
+					ReferencedFieldName:  "stop_id",
+					OffendingValue:       "S3",
+				},
+				ForeignKeyViolationNotice{
+					ReferencingFileName:  "transfers.txt",
+					ReferencingFieldName: "from_trip_id",
+					ReferencedFileName:   "trips.txt",
+					ReferencedFieldName:  "trip_id",
+					OffendingValue:       "T1",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
+			handleValidationResults(t, ValidateTransfers(tt.actualEntities, tt.stops, tt.routes, tt.trips), tt.expectedResults)
+		})
+	}
+}
@@ -82,7 +82,9 @@ func ValidateTrip(t Trip) []ValidationNotice {
 	return validationResults
 }
 
-func ValidateTrips(trips []*Trip, routes []*Route, calendarItems []*CalendarItem, shapes []*Shape) []ValidationNotice {
+// ValidateTrips checks the trips and their references. A service_id may be defined in either calendar.txt or
+// calendar_dates.txt.
+func ValidateTrips(trips []*Trip, routes []*Route, calendarItems []*CalendarItem, calendarDates []*CalendarDate, shapes []*Shape) []ValidationNotice {
 	var validationResults []ValidationNotice
 
 	if trips == nil {
@@ -119,7 +121,7 @@ func ValidateTrips(trips []*Trip, routes []*Route, calendarItems []*CalendarItem
 			}
 		}
 
-		if calendarItems != nil {
+		if calendarItems != nil || calendarDates != nil {
 			serviceFound := false
 			for _, calendarItem := range calendarItems {
 				if calendarItem == nil {
@@ -130,6 +132,17 @@ func ValidateTrips(trips []*Trip, routes []*Route, calendarItems []*CalendarItem
 					break
 				}
 			}
+			if !serviceFound {
+				for _, calendarDate := range calendarDates {
+					if calendarDate == nil || calendarDate.ServiceId == nil {
+						continue
+					}
+					if *trip.ServiceId == *calendarDate.ServiceId {
+						serviceFound = true
+						break
+					}
+				}
+			}
 			if !serviceFound {
 				validationResults = append(validationResults, ForeignKeyViolationNotice{
 					ReferencingFileName:  FileNameTrips,
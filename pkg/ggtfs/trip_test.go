@@ -89,6 +89,7 @@ func TestValidateTrips(t *testing.T) {
 		expectedResults []ValidationNotice
 		routes          []*Route
 		calendarItems   []*CalendarItem
+		calendarDates   []*CalendarDate
 		shapes          []*Shape
 	}{
 		"nil-slice": {
@@ -155,11 +156,24 @@ func TestValidateTrips(t *testing.T) {
 				},
 			},
 		},
+		"service-in-calendar-dates": {
+			actualEntities: []*Trip{
+				{
+					RouteId:   stringPtr("ROUTE_1"),
+					ServiceId: stringPtr("SERVICE_1"),
+					Id:        stringPtr("trip id"),
+				},
+			},
+			routes:          []*Route{{Id: stringPtr("ROUTE_1")}},
+			calendarItems:   []*CalendarItem{{ServiceId: stringPtr("SERVICE_2")}},
+			calendarDates:   []*CalendarDate{{ServiceId: stringPtr("SERVICE_1"), Date: stringPtr("20201201"), ExceptionType: stringPtr("1")}},
+			expectedResults: []ValidationNotice{},
+		},
 	}
 
 	for name, tt := range tests {
 		t.Run(fmt.Sprintf("%s", name), func(t *testing.T) {
-			handleValidationResults(t, ValidateTrips(tt.actualEntities, tt.routes, tt.calendarItems, tt.shapes), tt.expectedResults)
+			handleValidationResults(t, ValidateTrips(tt.actualEntities, tt.routes, tt.calendarItems, tt.calendarDates, tt.shapes), tt.expectedResults)
 		})
 	}
 }
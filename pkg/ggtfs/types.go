@@ -1,5 +1,6 @@
 package ggtfs
 
 type GtfsEntity interface {
-	*Shape | *Stop | *Agency | *CalendarItem | *CalendarDate | *Route | *StopTime | *Trip | any
+	*Shape | *Stop | *Agency | *CalendarItem | *CalendarDate | *Route | *StopTime | *Trip | *Frequency | *Transfer |
+		*FareAttributes | *FareRule | *FeedInfo | any
 }
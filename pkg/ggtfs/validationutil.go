@@ -364,6 +364,58 @@ func validateTypeBikesAllowed(fieldName string, fieldValue string, fileName stri
 	return []ValidationNotice{}
 }
 
+func validateExactTimes(fieldName string, fieldValue string, fileName string, line int) []ValidationNotice {
+	i, err := strconv.Atoi(fieldValue)
+	if err != nil || i < 0 || i > 1 {
+		return []ValidationNotice{InvalidExactTimesNotice{SingleLineNotice{
+			FileName:  fileName,
+			FieldName: fieldName,
+			Line:      line,
+		}}}
+	}
+
+	return []ValidationNotice{}
+}
+
+func validateTransferType(fieldName string, fieldValue string, fileName string, line int) []ValidationNotice {
+	i, err := strconv.Atoi(fieldValue)
+	if err != nil || i < 0 || i > 5 {
+		return []ValidationNotice{InvalidTransferTypeNotice{SingleLineNotice{
+			FileName:  fileName,
+			FieldName: fieldName,
+			Line:      line,
+		}}}
+	}
+
+	return []ValidationNotice{}
+}
+
+func validatePaymentMethod(fieldName string, fieldValue string, fileName string, line int) []ValidationNotice {
+	i, err := strconv.Atoi(fieldValue)
+	if err != nil || i < 0 || i > 1 {
+		return []ValidationNotice{InvalidPaymentMethodNotice{SingleLineNotice{
+			FileName:  fileName,
+			FieldName: fieldName,
+			Line:      line,
+		}}}
+	}
+
+	return []ValidationNotice{}
+}
+
+func validateTransfers(fieldName string, fieldValue string, fileName string, line int) []ValidationNotice {
+	i, err := strconv.Atoi(fieldValue)
+	if err != nil || i < 0 || i > 2 {
+		return []ValidationNotice{InvalidTransfersNotice{SingleLineNotice{
+			FileName:  fileName,
+			FieldName: fieldName,
+			Line:      line,
+		}}}
+	}
+
+	return []ValidationNotice{}
+}
+
 func validateField(fieldType FieldType, fieldName string, fieldValue *string, isRequired bool, fileName string, line int) []ValidationNotice {
 	hasValue := fieldValue != nil && *fieldValue != ""
 
@@ -438,6 +490,14 @@ func validateField(fieldType FieldType, fieldName string, fieldValue *string, is
 		results = append(results, validateWheelchairAccessible(fieldName, *fieldValue, fileName, line)...)
 	case FieldTypeBikesAllowed:
 		results = append(results, validateTypeBikesAllowed(fieldName, *fieldValue, fileName, line)...)
+	case FieldTypeExactTimes:
+		results = append(results, validateExactTimes(fieldName, *fieldValue, fileName, line)...)
+	case FieldTypeTransferType:
+		results = append(results, validateTransferType(fieldName, *fieldValue, fileName, line)...)
+	case FieldTypePaymentMethod:
+		results = append(results, validatePaymentMethod(fieldName, *fieldValue, fileName, line)...)
+	case FieldTypeTransfers:
+		results = append(results, validateTransfers(fieldName, *fieldValue, fileName, line)...)
 	}
 
 	return results
@@ -0,0 +1,450 @@
+package ggtfs
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path"
+)
+
+// WriteFeed writes the feed as GTFS files into dir, which is created if it does not exist. Files without entities
+// are not written, and only the columns which have a value in at least one row are included.
+func WriteFeed(dir string, feed *Feed) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := []string{FileNameAgency, FileNameRoutes, FileNameStops, FileNameTrips, FileNameStopTimes,
+		FileNameCalendar, FileNameCalendarDate, FileNameShapes, FileNameFrequencies, FileNameTransfers,
+		FileNameFareAttributes, FileNameFareRules, FileNameFeedInfo}
+
+	for _, file := range files {
+		filePath := path.Join(dir, file)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if !fileHasEntities(feed, file) {
+			continue
+		}
+
+		f, err := os.Create(filePath)
+		if err != nil {
+			return err
+		}
+
+		err = writeFeedFile(f, feed, file)
+		closeErr := f.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+
+	return nil
+}
+
+func fileHasEntities(feed *Feed, fileName string) bool {
+	switch fileName {
+	case FileNameAgency:
+		return len(feed.Agencies) > 0
+	case FileNameRoutes:
+		return len(feed.Routes) > 0
+	case FileNameStops:
+		return len(feed.Stops) > 0
+	case FileNameTrips:
+		return len(feed.Trips) > 0
+	case FileNameStopTimes:
+		return len(feed.StopTimes) > 0
+	case FileNameCalendar:
+		return len(feed.CalendarItems) > 0
+	case FileNameCalendarDate:
+		return len(feed.CalendarDates) > 0
+	case FileNameShapes:
+		return len(feed.Shapes) > 0
+	case FileNameFrequencies:
+		return len(feed.Frequencies) > 0
+	case FileNameTransfers:
+		return len(feed.Transfers) > 0
+	case FileNameFareAttributes:
+		return len(feed.FareAttributes) > 0
+	case FileNameFareRules:
+		return len(feed.FareRules) > 0
+	case FileNameFeedInfo:
+		return len(feed.FeedInfo) > 0
+	}
+	return false
+}
+
+func writeFeedFile(w io.Writer, feed *Feed, fileName string) error {
+	switch fileName {
+	case FileNameAgency:
+		return writeCsvEntities(w, defaultAgencyHeaders, feed.Agencies, agencyFieldValue)
+	case FileNameRoutes:
+		return writeCsvEntities(w, defaultRouteHeaders, feed.Routes, routeFieldValue)
+	case FileNameStops:
+		return writeCsvEntities(w, defaultStopHeaders, feed.Stops, stopFieldValue)
+	case FileNameTrips:
+		return writeCsvEntities(w, defaultTripHeaders, feed.Trips, tripFieldValue)
+	case FileNameStopTimes:
+		return writeCsvEntities(w, defaultStopTimeHeaders, feed.StopTimes, stopTimeFieldValue)
+	case FileNameCalendar:
+		return writeCsvEntities(w, defaultCalendarHeaders, feed.CalendarItems, calendarItemFieldValue)
+	case FileNameCalendarDate:
+		return writeCsvEntities(w, defaultCalendarDateHeaders, feed.CalendarDates, calendarDateFieldValue)
+	case FileNameShapes:
+		return writeCsvEntities(w, defaultShapeHeaders, feed.Shapes, shapeFieldValue)
+	case FileNameFrequencies:
+		return writeCsvEntities(w, defaultFrequencyHeaders, feed.Frequencies, frequencyFieldValue)
+	case FileNameTransfers:
+		return writeCsvEntities(w, defaultTransferHeaders, feed.Transfers, transferFieldValue)
+	case FileNameFareAttributes:
+		return writeCsvEntities(w, defaultFareAttributesHeaders, feed.FareAttributes, fareAttributesFieldValue)
+	case FileNameFareRules:
+		return writeCsvEntities(w, defaultFareRuleHeaders, feed.FareRules, fareRuleFieldValue)
+	case FileNameFeedInfo:
+		return writeCsvEntities(w, defaultFeedInfoHeaders, feed.FeedInfo, feedInfoFieldValue)
+	}
+	return nil
+}
+
+func writeCsvEntities[T comparable](w io.Writer, headerNames []string, entities []T, fieldValue func(T, string) *string) error {
+	var zero T
+
+	var headers []string
+	for _, h := range headerNames {
+		for _, e := range entities {
+			if e != zero && fieldValue(e, h) != nil {
+				headers = append(headers, h)
+				break
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	for _, e := range entities {
+		if e == zero {
+			continue
+		}
+
+		row := make([]string, len(headers))
+		for i, h := range headers {
+			if v := fieldValue(e, h); v != nil {
+				row[i] = *v
+			}
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func agencyFieldValue(a *Agency, header string) *string {
+	switch header {
+	case "agency_id":
+		return a.Id
+	case "agency_name":
+		return a.Name
+	case "agency_url":
+		return a.URL
+	case "agency_timezone":
+		return a.Timezone
+	case "agency_lang":
+		return a.Lang
+	case "agency_phone":
+		return a.Phone
+	case "agency_fare_url":
+		return a.FareURL
+	case "agency_email":
+		return a.Email
+	}
+	return nil
+}
+
+func routeFieldValue(r *Route, header string) *string {
+	switch header {
+	case "route_id":
+		return r.Id
+	case "agency_id":
+		return r.AgencyId
+	case "route_short_name":
+		return r.ShortName
+	case "route_long_name":
+		return r.LongName
+	case "route_desc":
+		return r.Desc
+	case "route_type":
+		return r.Type
+	case "route_url":
+		return r.URL
+	case "route_color":
+		return r.Color
+	case "route_text_color":
+		return r.TextColor
+	case "route_sort_order":
+		return r.SortOrder
+	case "continuous_pickup":
+		return r.ContinuousPickup
+	case "continuous_drop_off":
+		return r.ContinuousDropOff
+	case "network_id":
+		return r.NetworkId
+	}
+	return nil
+}
+
+func stopFieldValue(s *Stop, header string) *string {
+	switch header {
+	case "stop_id":
+		return s.Id
+	case "stop_code":
+		return s.Code
+	case "stop_name":
+		return s.Name
+	case "stop_desc":
+		return s.Desc
+	case "stop_lat":
+		return s.Lat
+	case "stop_lon":
+		return s.Lon
+	case "zone_id":
+		return s.ZoneId
+	case "stop_url":
+		return s.URL
+	case "location_type":
+		return s.LocationType
+	case "parent_station":
+		return s.ParentStation
+	case "stop_timezone":
+		return s.Timezone
+	case "wheelchair_boarding":
+		return s.WheelchairBoarding
+	case "level_id":
+		return s.LevelId
+	case "platform_code":
+		return s.PlatformCode
+	case "municipality_id":
+		if s.Extensions != nil {
+			return s.Extensions.MunicipalityId
+		}
+	}
+	return nil
+}
+
+func tripFieldValue(t *Trip, header string) *string {
+	switch header {
+	case "route_id":
+		return t.RouteId
+	case "service_id":
+		return t.ServiceId
+	case "trip_id":
+		return t.Id
+	case "trip_headsign":
+		return t.HeadSign
+	case "trip_short_name":
+		return t.ShortName
+	case "direction_id":
+		return t.DirectionId
+	case "block_id":
+		return t.BlockId
+	case "shape_id":
+		return t.ShapeId
+	case "wheelchair_accessible":
+		return t.WheelchairAccessible
+	case "bikes_allowed":
+		return t.BikesAllowed
+	}
+	return nil
+}
+
+func stopTimeFieldValue(st *StopTime, header string) *string {
+	switch header {
+	case "trip_id":
+		return st.TripId
+	case "arrival_time":
+		return st.ArrivalTime
+	case "departure_time":
+		return st.DepartureTime
+	case "stop_id":
+		return st.StopId
+	case "stop_sequence":
+		return st.StopSequence
+	case "stop_headsign":
+		return st.StopHeadSign
+	case "pickup_type":
+		return st.PickupType
+	case "drop_off_type":
+		return st.DropOffType
+	case "continuous_pickup":
+		return st.ContinuousPickup
+	case "continuous_drop_off":
+		return st.ContinuousDropOff
+	case "shape_dist_traveled":
+		return st.ShapeDistTraveled
+	case "timepoint":
+		return st.Timepoint
+	}
+	return nil
+}
+
+func calendarItemFieldValue(c *CalendarItem, header string) *string {
+	switch header {
+	case "service_id":
+		return c.ServiceId
+	case "monday":
+		return c.Monday
+	case "tuesday":
+		return c.Tuesday
+	case "wednesday":
+		return c.Wednesday
+	case "thursday":
+		return c.Thursday
+	case "friday":
+		return c.Friday
+	case "saturday":
+		return c.Saturday
+	case "sunday":
+		return c.Sunday
+	case "start_date":
+		return c.StartDate
+	case "end_date":
+		return c.EndDate
+	}
+	return nil
+}
+
+func calendarDateFieldValue(cd *CalendarDate, header string) *string {
+	switch header {
+	case "service_id":
+		return cd.ServiceId
+	case "date":
+		return cd.Date
+	case "exception_type":
+		return cd.ExceptionType
+	}
+	return nil
+}
+
+func shapeFieldValue(s *Shape, header string) *string {
+	switch header {
+	case "shape_id":
+		return s.Id
+	case "shape_pt_lat":
+		return s.PtLat
+	case "shape_pt_lon":
+		return s.PtLon
+	case "shape_pt_sequence":
+		return s.PtSequence
+	case "shape_dist_traveled":
+		return s.DistTraveled
+	}
+	return nil
+}
+
+func frequencyFieldValue(f *Frequency, header string) *string {
+	switch header {
+	case "trip_id":
+		return f.TripId
+	case "start_time":
+		return f.StartTime
+	case "end_time":
+		return f.EndTime
+	case "headway_secs":
+		return f.HeadwaySecs
+	case "exact_times":
+		return f.ExactTimes
+	}
+	return nil
+}
+
+func transferFieldValue(t *Transfer, header string) *string {
+	switch header {
+	case "from_stop_id":
+		return t.FromStopId
+	case "to_stop_id":
+		return t.ToStopId
+	case "from_route_id":
+		return t.FromRouteId
+	case "to_route_id":
+		return t.ToRouteId
+	case "from_trip_id":
+		return t.FromTripId
+	case "to_trip_id":
+		return t.ToTripId
+	case "transfer_type":
+		return t.TransferType
+	case "min_transfer_time":
+		return t.MinTransferTime
+	}
+	return nil
+}
+
+func fareAttributesFieldValue(fa *FareAttributes, header string) *string {
+	switch header {
+	case "fare_id":
+		return fa.Id
+	case "price":
+		return fa.Price
+	case "currency_type":
+		return fa.CurrencyType
+	case "payment_method":
+		return fa.PaymentMethod
+	case "transfers":
+		return fa.Transfers
+	case "agency_id":
+		return fa.AgencyId
+	case "transfer_duration":
+		return fa.TransferDuration
+	}
+	return nil
+}
+
+func fareRuleFieldValue(fr *FareRule, header string) *string {
+	switch header {
+	case "fare_id":
+		return fr.FareId
+	case "route_id":
+		return fr.RouteId
+	case "origin_id":
+		return fr.OriginId
+	case "destination_id":
+		return fr.DestinationId
+	case "contains_id":
+		return fr.ContainsId
+	}
+	return nil
+}
+
+func feedInfoFieldValue(fi *FeedInfo, header string) *string {
+	switch header {
+	case "feed_publisher_name":
+		return fi.PublisherName
+	case "feed_publisher_url":
+		return fi.PublisherURL
+	case "feed_lang":
+		return fi.Lang
+	case "default_lang":
+		return fi.DefaultLang
+	case "feed_start_date":
+		return fi.StartDate
+	case "feed_end_date":
+		return fi.EndDate
+	case "feed_version":
+		return fi.Version
+	case "feed_contact_email":
+		return fi.ContactEmail
+	case "feed_contact_url":
+		return fi.ContactURL
+	}
+	return nil
+}
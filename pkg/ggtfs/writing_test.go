@@ -0,0 +1,58 @@
+//go:build ggtfs_tests || all_tests
+
+package ggtfs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestWriteFeed(t *testing.T) {
+	files := validFeedFiles()
+	for name, content := range optionalFeedFiles() {
+		files[name] = content
+	}
+
+	feed, errs := LoadFeed(writeFeedFiles(t, files), CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	dir := path.Join(t.TempDir(), "out")
+	if err := WriteFeed(dir, feed); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path.Join(dir, FileNameStops))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := strings.SplitN(string(content), "\n", 2)[0]; header != "stop_id,stop_code,stop_name,stop_lat,stop_lon" {
+		t.Errorf("expected only the used columns to be written, got %v", header)
+	}
+
+	written, errs := LoadFeed(dir, CsvDialect{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if len(written.Agencies) != len(feed.Agencies) || len(written.Routes) != len(feed.Routes) ||
+		len(written.Stops) != len(feed.Stops) || len(written.Trips) != len(feed.Trips) ||
+		len(written.StopTimes) != len(feed.StopTimes) || len(written.CalendarItems) != len(feed.CalendarItems) ||
+		len(written.CalendarDates) != len(feed.CalendarDates) || len(written.Shapes) != len(feed.Shapes) ||
+		len(written.Frequencies) != len(feed.Frequencies) || len(written.Transfers) != len(feed.Transfers) ||
+		len(written.FareAttributes) != len(feed.FareAttributes) || len(written.FareRules) != len(feed.FareRules) ||
+		len(written.FeedInfo) != len(feed.FeedInfo) {
+		t.Errorf("expected the written feed to have the same entities as the original")
+	}
+
+	if s, ok := written.StopById("S2"); !ok || *s.Name != "Hämeenkatu" || *s.Lat != "61.49800" {
+		t.Errorf("expected stop S2 to survive the round trip, got %v", s)
+	}
+
+	if fa, ok := written.FareAttributesById("F1"); !ok || fa.Transfers == nil || *fa.Transfers != "" {
+		t.Errorf("expected the empty transfers column of fare F1 to survive the round trip, got %v", fa)
+	}
+}